@@ -0,0 +1,114 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"slices"
+)
+
+// App is a GitHub app which can create [Transport]s for multiple installations.
+//
+// All installation transports created by the App share the same JWT, and
+// the app is verified only once. This avoids minting JWTs and verifying the app
+// for each installation, when serving a large number of installations.
+type App struct {
+	transport *Transport
+	opts      []Option
+}
+
+// NewApp creates a new [App]. Options specified apply to all installation
+// transports created by the App. Installation options like [WithInstallationID],
+// [WithOwner] and [WithRepositories] are not supported, and must be specified
+// when creating installation transports via [App.InstallationTransport].
+func NewApp(ctx context.Context, appid uint64, signer crypto.Signer, opts ...Option) (*App, error) {
+	// Apply options to check if any installation options are specified,
+	// before making any API calls.
+	probe, err := newTransport(appid, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if probe.installID != 0 || probe.owner != "" {
+		return nil, errors.New("githubapp: invalid options: installation options are not supported by NewApp")
+	}
+
+	t, err := NewTransport(ctx, appid, signer, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &App{
+		transport: t,
+		opts:      slices.Clone(opts),
+	}, nil
+}
+
+// Transport returns [Transport] which authenticates as the app (using JWT).
+func (a *App) Transport() *Transport {
+	return a.transport
+}
+
+// AppMetadata is metadata of a GitHub app, as returned by the API.
+type AppMetadata struct {
+	// GitHub app ID.
+	ID uint64 `json:"id,omitempty" yaml:"id,omitempty"`
+
+	// GitHub app slug.
+	Slug string `json:"slug,omitempty" yaml:"slug,omitempty"`
+
+	// GitHub app display name.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+
+	// Owner of the GitHub app.
+	Owner string `json:"owner,omitempty" yaml:"owner,omitempty"`
+
+	// Permissions requested by the app. Installations may not have
+	// accepted all of them.
+	Permissions map[string]string `json:"permissions,omitempty" yaml:"permissions,omitempty"`
+
+	// Events the app is subscribed to.
+	Events []string `json:"events,omitempty" yaml:"events,omitempty"`
+}
+
+// InstallationTransport creates a new [Transport] for the installation. Options
+// specified here are applied after the options specified in [NewApp]. Returned
+// [Transport] shares JWT with the app, but has its own installation access tokens.
+func (a *App) InstallationTransport(ctx context.Context, installID uint64, opts ...Option) (*Transport, error) {
+	if installID == 0 {
+		return nil, errors.New("githubapp: invalid options: installation id cannot be zero")
+	}
+
+	options := make([]Option, 0, len(a.opts)+len(opts)+1)
+	options = append(options, a.opts...)
+	options = append(options, opts...)
+	options = append(options, WithInstallationID(installID))
+	t, err := newTransport(a.transport.appID, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	// If context is nil, assign a default context.
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	// Re-use app's signer, JWT and app metadata.
+	t.app = a.transport
+	t.appSlug = a.transport.appSlug
+	t.signer = a.transport.signer
+	t.minter = a.transport.minter
+
+	// Bound bootstrap phase, as default http transport has no overall deadline.
+	ctx, cancel := t.bootstrapContext(ctx)
+	defer cancel()
+
+	err = t.bootstrapInstallation(ctx, t.internalClient(), newRetryBudget(t.bootstrapBudget, t.requestTimeout))
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
@@ -0,0 +1,117 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/tprasadtp/go-githubapp/githubapptest"
+	"github.com/tprasadtp/go-githubapp/internal/api"
+	"github.com/tprasadtp/go-githubapp/internal/testdata/apitestdata"
+	"github.com/tprasadtp/go-githubapp/internal/testkeys"
+)
+
+func TestNewApp_InstallationOptions(t *testing.T) {
+	tt := []struct {
+		name string
+		opt  Option
+	}{
+		{name: "installation-id", opt: WithInstallationID(apitestdata.InstallationID)},
+		{name: "owner", opt: WithOwner("gh-integration-tests")},
+		{name: "repositories", opt: WithRepositories("gh-integration-tests/foo")},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			app, err := NewApp(context.Background(), apitestdata.AppID, testkeys.RSA2048(),
+				WithEndpoint("https://api.go-githubapp.test/"),
+				WithRoundTripper(api.RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+					t.Errorf("no API calls must be made => %s", r.URL)
+					return nil, errors.New("no API calls must be made")
+				})),
+				tc.opt,
+			)
+			if err == nil {
+				t.Errorf("expected an error, got nil")
+			}
+			if app != nil {
+				t.Errorf("expected app to be nil")
+			}
+		})
+	}
+}
+
+func TestApp_InstallationTransport(t *testing.T) {
+	server := githubapptest.NewServer()
+	t.Cleanup(server.Close)
+
+	recorder := &testRecorder{}
+	requests := &requestRecorder{}
+	app, err := NewApp(context.Background(), apitestdata.AppID, testkeys.RSA2048(),
+		WithEndpoint(server.URL),
+		WithMetrics(recorder),
+		WithRoundTripper(requests),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	if app.Transport().InstallationID() != 0 {
+		t.Errorf("app transport must not have installation id")
+	}
+
+	for i := 0; i < 3; i++ {
+		transport, err := app.InstallationTransport(context.Background(), apitestdata.InstallationID)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		if transport.InstallationID() != apitestdata.InstallationID {
+			t.Errorf("expected installation id=%d, got=%d", apitestdata.InstallationID, transport.InstallationID())
+		}
+
+		if transport.AppName() != apitestdata.AppSlug {
+			t.Errorf("expected app name=%s, got=%s", apitestdata.AppSlug, transport.AppName())
+		}
+
+		if transport.BotUsername() == "" {
+			t.Errorf("expected bot username to be populated")
+		}
+
+		if transport.BotUserID() != apitestdata.BotUserID {
+			t.Errorf("expected bot user id=%d, got=%d", apitestdata.BotUserID, transport.BotUserID())
+		}
+	}
+
+	if v := requests.count("/app"); v != 1 {
+		t.Errorf("expected app to be verified once, got %d", v)
+	}
+
+	if v := recorder.count(TokenKindJWT, false); v != 1 {
+		t.Errorf("expected 1 JWT mint, got %d", v)
+	}
+
+	tokenPath := fmt.Sprintf("/app/installations/%d/access_tokens", apitestdata.InstallationID)
+	authz := requests.headers(api.AuthzHeader)
+	jwts := map[string]struct{}{}
+	for i, v := range requests.paths() {
+		if v == tokenPath {
+			jwts[authz[i]] = struct{}{}
+		}
+	}
+
+	if len(jwts) != 1 {
+		t.Errorf("expected all installation tokens to be minted with the same JWT, got %d", len(jwts))
+	}
+
+	t.Run("zero-installation-id", func(t *testing.T) {
+		_, err := app.InstallationTransport(context.Background(), 0)
+		if err == nil {
+			t.Errorf("expected an error, got nil")
+		}
+	})
+}
@@ -0,0 +1,77 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// defaultBootstrapTimeout is used when neither [WithBootstrapTimeout] is specified
+// nor context passed to [NewTransport] has a deadline.
+const defaultBootstrapTimeout = 30 * time.Second
+
+// WithBootstrapTimeout configures timeout for bootstrap phase of [NewTransport],
+// i.e. verifying the signer, app, installation and fetching bot user metadata.
+// This is independent of context passed to [NewTransport], whichever expires
+// first applies.
+//
+// When not specified or zero, and context passed to [NewTransport] does not
+// have a deadline, a default timeout of 30s is used.
+func WithBootstrapTimeout(timeout time.Duration) Option {
+	return &funcOption{
+		name: "WithBootstrapTimeout",
+		f: func(t *Transport) error {
+			if timeout < 0 {
+				return fmt.Errorf("bootstrap timeout cannot be negative: %s", timeout)
+			}
+			t.bootstrapTimeout = timeout
+			return nil
+		},
+	}
+}
+
+// WithTimeout configures timeout for each API request made by [NewTransport]
+// when building the [Transport]. Unlike [WithBootstrapTimeout], which bounds
+// all bootstrap requests combined, this bounds individual requests. Context
+// passed to [NewTransport] still applies, whichever expires first applies.
+//
+// Requests which time out are retried if [WithBootstrapRetryBudget] is specified.
+// When not specified or zero, individual requests are not bounded.
+func WithTimeout(timeout time.Duration) Option {
+	return &funcOption{
+		name: "WithTimeout",
+		f: func(t *Transport) error {
+			if timeout < 0 {
+				return fmt.Errorf("timeout cannot be negative: %s", timeout)
+			}
+			t.requestTimeout = timeout
+			return nil
+		},
+	}
+}
+
+// bootstrapContext returns context to use for bootstrap phase of [NewTransport].
+func (t *Transport) bootstrapContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if t.bootstrapTimeout > 0 {
+		return context.WithTimeout(ctx, t.bootstrapTimeout)
+	}
+
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, defaultBootstrapTimeout)
+}
+
+// bootstrapError wraps error returned by a bootstrap phase. If bootstrap
+// context has expired or request timed out, returned error indicates
+// the phase which timed out.
+func bootstrapError(ctx context.Context, phase string, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) || errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("githubapp: timed out while trying to %s: %w", phase, err)
+	}
+	return fmt.Errorf("githubapp: failed to %s: %w", phase, err)
+}
@@ -0,0 +1,243 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tprasadtp/go-githubapp/internal/testdata/apitestdata"
+	"github.com/tprasadtp/go-githubapp/internal/testkeys"
+)
+
+func TestWithBootstrapTimeout(t *testing.T) {
+	t.Run("negative", func(t *testing.T) {
+		transport := Transport{}
+		err := Options(WithBootstrapTimeout(-time.Second)).apply(&transport)
+		if err == nil {
+			t.Errorf("expected an error, got nil")
+		}
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		transport := Transport{}
+		err := Options(WithBootstrapTimeout(time.Second)).apply(&transport)
+		if err != nil {
+			t.Errorf("expected no error, got %s", err)
+		}
+		if transport.bootstrapTimeout != time.Second {
+			t.Errorf("expected timeout=%s, got=%s", time.Second, transport.bootstrapTimeout)
+		}
+	})
+}
+
+func TestWithTimeout(t *testing.T) {
+	t.Run("negative", func(t *testing.T) {
+		transport := Transport{}
+		err := Options(WithTimeout(-time.Second)).apply(&transport)
+		if err == nil {
+			t.Errorf("expected an error, got nil")
+		}
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		transport := Transport{}
+		err := Options(WithTimeout(time.Second)).apply(&transport)
+		if err != nil {
+			t.Errorf("expected no error, got %s", err)
+		}
+		if transport.requestTimeout != time.Second {
+			t.Errorf("expected timeout=%s, got=%s", time.Second, transport.requestTimeout)
+		}
+	})
+}
+
+func TestTransport_bootstrapContext(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		transport := Transport{}
+		ctx, cancel := transport.bootstrapContext(context.Background())
+		defer cancel()
+
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			t.Fatalf("expected bootstrap context to have a deadline")
+		}
+		if v := time.Until(deadline); v > defaultBootstrapTimeout {
+			t.Errorf("expected deadline within %s, got %s", defaultBootstrapTimeout, v)
+		}
+	})
+
+	t.Run("parent-deadline", func(t *testing.T) {
+		parent, parentCancel := context.WithTimeout(context.Background(), time.Hour)
+		defer parentCancel()
+		expect, _ := parent.Deadline()
+
+		transport := Transport{}
+		ctx, cancel := transport.bootstrapContext(parent)
+		defer cancel()
+
+		deadline, _ := ctx.Deadline()
+		if !deadline.Equal(expect) {
+			t.Errorf("expected deadline=%s, got=%s", expect, deadline)
+		}
+	})
+
+	t.Run("configured", func(t *testing.T) {
+		parent, parentCancel := context.WithTimeout(context.Background(), time.Hour)
+		defer parentCancel()
+
+		transport := Transport{bootstrapTimeout: time.Second}
+		ctx, cancel := transport.bootstrapContext(parent)
+		defer cancel()
+
+		deadline, _ := ctx.Deadline()
+		if v := time.Until(deadline); v > time.Second {
+			t.Errorf("expected deadline within %s, got %s", time.Second, v)
+		}
+	})
+}
+
+func TestNewTransport_BootstrapTimeout(t *testing.T) {
+	m := apitestdata.Get(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app":
+			_, _ = w.Write(m["get-app"])
+		case fmt.Sprintf("/app/installations/%d", apitestdata.InstallationID):
+			// Sleep longer than bootstrap timeout.
+			select {
+			case <-r.Context().Done():
+			case <-time.After(10 * time.Second):
+			}
+		default:
+			t.Errorf("Unknown/Invalid Request => %s", r.URL)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	const timeout = 500 * time.Millisecond
+	start := time.Now()
+	transport, err := NewTransport(context.Background(), apitestdata.AppID, testkeys.RSA2048(),
+		WithEndpoint(server.URL),
+		WithInstallationID(apitestdata.InstallationID),
+		WithBootstrapTimeout(timeout),
+	)
+	elapsed := time.Since(start)
+
+	if transport != nil {
+		t.Errorf("expected transport to be nil")
+	}
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "timed out while trying to verify installation") {
+		t.Errorf("expected error to indicate the phase which timed out, got %s", err)
+	}
+
+	// Allow some slack.
+	if elapsed > timeout+time.Second {
+		t.Errorf("bootstrap took %s, which exceeds timeout %s", elapsed, timeout)
+	}
+}
+
+func TestNewTransport_Timeout(t *testing.T) {
+	m := apitestdata.Get(t)
+
+	// newServer returns a server which hangs on the first request to /app.
+	newServer := func(t *testing.T) *httptest.Server {
+		var calls atomic.Int64
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var key string
+			switch r.URL.Path {
+			case "/app":
+				if calls.Add(1) == 1 {
+					select {
+					case <-r.Context().Done():
+					case <-time.After(10 * time.Second):
+					}
+					return
+				}
+				key = "get-app"
+			default:
+				t.Errorf("Unknown/Invalid Request => %s", r.URL)
+			}
+			_, _ = w.Write(m[key])
+		}))
+		t.Cleanup(server.Close)
+		return server
+	}
+
+	const timeout = 250 * time.Millisecond
+
+	t.Run("no-retries", func(t *testing.T) {
+		server := newServer(t)
+		start := time.Now()
+		_, err := NewTransport(context.Background(), apitestdata.AppID, testkeys.RSA2048(),
+			WithEndpoint(server.URL),
+			WithTimeout(timeout),
+		)
+		elapsed := time.Since(start)
+
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+		}
+
+		if !strings.Contains(err.Error(), "timed out while trying to verify app") {
+			t.Errorf("expected error to indicate the phase which timed out, got %s", err)
+		}
+
+		// Allow some slack.
+		if elapsed > timeout+time.Second {
+			t.Errorf("bootstrap took %s, which exceeds timeout %s", elapsed, timeout)
+		}
+	})
+
+	t.Run("with-retries", func(t *testing.T) {
+		server := newServer(t)
+		transport, err := NewTransport(context.Background(), apitestdata.AppID, testkeys.RSA2048(),
+			WithEndpoint(server.URL),
+			WithTimeout(timeout),
+			WithBootstrapRetryBudget(5*time.Second),
+		)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		if transport.AppName() != apitestdata.AppSlug {
+			t.Errorf("expected app name=%s, got=%s", apitestdata.AppSlug, transport.AppName())
+		}
+	})
+
+	t.Run("context-shorter-than-timeout", func(t *testing.T) {
+		server := newServer(t)
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		start := time.Now()
+		_, err := NewTransport(ctx, apitestdata.AppID, testkeys.RSA2048(),
+			WithEndpoint(server.URL),
+			WithTimeout(time.Minute),
+			WithBootstrapRetryBudget(5*time.Second),
+		)
+		elapsed := time.Since(start)
+
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+		}
+
+		// Allow some slack.
+		if elapsed > timeout+time.Second {
+			t.Errorf("bootstrap took %s, which exceeds context deadline %s", elapsed, timeout)
+		}
+	})
+}
@@ -0,0 +1,196 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/tprasadtp/go-githubapp/internal/api"
+)
+
+// BotUser is GitHub app's bot user metadata, which is useful to attribute
+// git commits to the app.
+type BotUser struct {
+	// Username is app's github username.
+	Username string `json:"username,omitempty" yaml:"username,omitempty"`
+
+	// ID is app's github user id.
+	ID uint64 `json:"id,omitempty" yaml:"id,omitempty"`
+
+	// CommitterName is committer name to use to attribute commits to the bot.
+	CommitterName string `json:"committer_name,omitempty" yaml:"committer_name,omitempty"`
+
+	// CommitterEmail is committer email to use to attribute commits to the bot.
+	CommitterEmail string `json:"committer_email,omitempty" yaml:"committer_email,omitempty"`
+}
+
+// botUser is app's bot user metadata.
+type botUser struct {
+	username string
+	email    string
+	id       uint64
+}
+
+// botUserRetryInterval is duration for which failures to fetch bot user metadata
+// are cached, so that a misbehaving API is not hit on every use.
+const botUserRetryInterval = time.Minute
+
+// botUserLoader fetches app's bot user metadata on first use and is shared by
+// transports for the same installation. Successfully fetched metadata is cached
+// forever, while failures are cached for [botUserRetryInterval] and are retried
+// on next use afterwards. Concurrent fetches are shared.
+type botUserLoader struct {
+	flight flight[*botUser]
+	loaded atomic.Pointer[botUser]
+	failed atomic.Pointer[botUserFailure]
+}
+
+// botUserFailure is a cached failure to fetch bot user metadata.
+type botUserFailure struct {
+	err   error
+	until time.Time
+}
+
+// failure returns cached failure to fetch bot user metadata, if it has not expired.
+func (b *botUserLoader) failure() error {
+	if v := b.failed.Load(); v != nil && time.Now().Before(v.until) {
+		return v.err
+	}
+	return nil
+}
+
+// newLoadedBotUser returns [botUserLoader] which is already populated
+// and never fetched, for example, from a static installation token.
+func newLoadedBotUser(username, email string, id uint64) *botUserLoader {
+	bot := &botUserLoader{}
+	bot.loaded.Store(&botUser{username: username, email: email, id: id})
+	return bot
+}
+
+// loadedBotUser returns bot user metadata if it was already fetched successfully.
+// Unlike [Transport.botUserMetadata], this never blocks or fetches metadata,
+// thus it is safe to use while minting installation tokens.
+func (t *Transport) loadedBotUser() *botUser {
+	if t.bot == nil {
+		return nil
+	}
+	return t.bot.loaded.Load()
+}
+
+// botUserMetadata fetches bot user metadata if it was not already fetched and
+// returns it. This returns nil if bot user metadata is disabled via [WithoutBotMetadata].
+func (t *Transport) botUserMetadata(ctx context.Context) (*botUser, error) {
+	if t.bot == nil {
+		return nil, nil
+	}
+
+	if v := t.bot.loaded.Load(); v != nil {
+		return v, nil
+	}
+
+	if err := t.bot.failure(); err != nil {
+		return nil, err
+	}
+
+	return t.bot.flight.do(ctx, func(ctx context.Context) (*botUser, error) {
+		// Another caller might have fetched it while waiting for the lock.
+		if v := t.bot.loaded.Load(); v != nil {
+			return v, nil
+		}
+
+		if err := t.bot.failure(); err != nil {
+			return nil, err
+		}
+
+		bctx, cancel := t.bootstrapContext(ctx)
+		defer cancel()
+
+		v := &botUser{}
+		err := t.fetchBotUserID(bctx, t.internalClient(), v)
+		if err != nil {
+			err = fmt.Errorf("githubapp: failed to fetch bot user metadata: %w", err)
+			// Failures caused by the caller's context are not cached,
+			// as they say nothing about the API.
+			if ctx.Err() == nil {
+				t.bot.failed.Store(&botUserFailure{err: err, until: time.Now().Add(botUserRetryInterval)})
+			}
+			return nil, err
+		}
+		t.bot.loaded.Store(v)
+		t.bot.failed.Store(nil)
+		return v, nil
+	})
+}
+
+// BotUserMetadata returns the GitHub app's bot user metadata. Bot user metadata
+// is fetched if it was not already fetched. Failures are cached for a minute, during
+// which the cached error is returned without making any API requests.
+// Unlike [Transport.BotUsername], [Transport.BotCommitterEmail] and [Transport.BotUserID],
+// this respects cancellation of ctx and returns errors fetching the metadata.
+// This returns an empty [BotUser] if [WithoutBotMetadata] is specified.
+func (t *Transport) BotUserMetadata(ctx context.Context) (BotUser, error) {
+	bot, err := t.botUserMetadata(ctx)
+	if err != nil || bot == nil {
+		return BotUser{}, err
+	}
+
+	return BotUser{
+		Username:       bot.username,
+		ID:             bot.id,
+		CommitterName:  t.BotCommitterName(),
+		CommitterEmail: bot.email,
+	}, nil
+}
+
+// fetchBotUserID fetches bot's GitHub user metadata and populates bot.
+func (t *Transport) fetchBotUserID(ctx context.Context, client *http.Client, bot *botUser) error {
+	u := t.baseURL.JoinPath("users", fmt.Sprintf("%s[bot]", t.appSlug))
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+
+	// Installation token requests do not set these headers.
+	r.Header.Set(api.AcceptHeader, api.AcceptHeaderValue)
+	r.Header.Set(api.VersionHeader, t.versionHeaderValue())
+	r.Header.Set(api.UAHeader, t.userAgent())
+
+	resp, err := client.Do(r)
+	if err != nil {
+		return fmt.Errorf("request failed - %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	// If the API responds with non 200 status, try to read the error message in the response.
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError(resp, data)
+	}
+
+	user := api.User{}
+	err = json.Unmarshal(data, &user)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	if user.ID == nil || *user.ID <= 0 || user.Login == nil {
+		return errors.New("missing or invalid user id or login in API response")
+	}
+
+	bot.username = *user.Login
+	bot.id = uint64(*user.ID)
+	bot.email = fmt.Sprintf("%d+%s@users.noreply.github.com", *user.ID, *user.Login)
+	return nil
+}
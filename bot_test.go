@@ -0,0 +1,258 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tprasadtp/go-githubapp/internal/testdata/apitestdata"
+	"github.com/tprasadtp/go-githubapp/internal/testkeys"
+)
+
+func TestTransport_BotUserMetadata(t *testing.T) {
+	// newServer returns mock server which responds to bot user requests
+	// with the given status, and counter for the number of bot user requests.
+	newServer := func(t *testing.T, status int) (*httptest.Server, *atomic.Int64) {
+		m := apitestdata.Get(t)
+		bot := &atomic.Int64{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var key string
+			switch r.URL.Path {
+			case "/app":
+				key = "get-app"
+			case fmt.Sprintf("/app/installations/%d", apitestdata.InstallationID):
+				key = "get-installation-by-id"
+			case fmt.Sprintf("/app/installations/%d/access_tokens", apitestdata.InstallationID):
+				w.WriteHeader(http.StatusCreated)
+				_, _ = w.Write([]byte(`{"token":"ghs_token","expires_at":"2099-01-01T00:00:00Z"}`))
+				return
+			case fmt.Sprintf("/users/%s[bot]", apitestdata.AppSlug):
+				bot.Add(1)
+				if status != http.StatusOK {
+					w.WriteHeader(status)
+					_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+					return
+				}
+				key = "get-user-bot"
+			default:
+				t.Errorf("Unknown/Invalid Request => %s", r.URL)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			_, _ = w.Write(m[key])
+		}))
+		t.Cleanup(server.Close)
+		return server, bot
+	}
+
+	assertBot := func(t *testing.T, transport *Transport) {
+		t.Helper()
+		if v := transport.BotUsername(); v != apitestdata.AppSlug+"[bot]" {
+			t.Errorf("expected bot username=%s[bot], got=%q", apitestdata.AppSlug, v)
+		}
+
+		if v := transport.BotUserID(); v != apitestdata.BotUserID {
+			t.Errorf("expected bot user id=%d, got=%d", apitestdata.BotUserID, v)
+		}
+
+		email := fmt.Sprintf("%d+%s[bot]@users.noreply.github.com", apitestdata.BotUserID, apitestdata.AppSlug)
+		if v := transport.BotCommitterEmail(); v != email {
+			t.Errorf("expected bot email=%s, got=%q", email, v)
+		}
+	}
+
+	t.Run("lazy", func(t *testing.T) {
+		server, bot := newServer(t, http.StatusOK)
+		transport, err := NewTransport(context.Background(), apitestdata.AppID, testkeys.RSA2048(),
+			WithEndpoint(server.URL),
+			WithInstallationID(apitestdata.InstallationID),
+		)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		if v := bot.Load(); v != 0 {
+			t.Fatalf("expected bot user not to be fetched by NewTransport, got %d requests", v)
+		}
+
+		assertBot(t, transport)
+		assertBot(t, transport)
+
+		token, err := transport.InstallationToken(context.Background())
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		if token.BotUserID != apitestdata.BotUserID {
+			t.Errorf("expected token bot user id=%d, got=%d", apitestdata.BotUserID, token.BotUserID)
+		}
+
+		// Derived transports share bot user metadata.
+		ct, err := transport.CloneWith(WithPermissions("issues:read"))
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+		assertBot(t, ct)
+
+		if v := bot.Load(); v != 1 {
+			t.Errorf("expected bot user to be fetched once, got %d requests", v)
+		}
+	})
+
+	t.Run("eager", func(t *testing.T) {
+		server, bot := newServer(t, http.StatusOK)
+		transport, err := NewTransport(context.Background(), apitestdata.AppID, testkeys.RSA2048(),
+			WithEndpoint(server.URL),
+			WithInstallationID(apitestdata.InstallationID),
+			WithEagerBotMetadata(),
+		)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		if v := bot.Load(); v != 1 {
+			t.Fatalf("expected bot user to be fetched by NewTransport, got %d requests", v)
+		}
+
+		assertBot(t, transport)
+
+		if v := bot.Load(); v != 1 {
+			t.Errorf("expected bot user to be fetched once, got %d requests", v)
+		}
+	})
+
+	t.Run("lazy-error-cached", func(t *testing.T) {
+		server, bot := newServer(t, http.StatusNotFound)
+		transport, err := NewTransport(context.Background(), apitestdata.AppID, testkeys.RSA2048(),
+			WithEndpoint(server.URL),
+			WithInstallationID(apitestdata.InstallationID),
+		)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		for i := 0; i < 3; i++ {
+			if v := transport.BotUsername(); v != "" {
+				t.Errorf("expected bot username to be empty, got=%q", v)
+			}
+
+			if _, err := transport.BotUserMetadata(context.Background()); err == nil {
+				t.Errorf("expected BotUserMetadata to return an error")
+			}
+
+			// Minting tokens does not depend on bot user metadata.
+			token, err := transport.InstallationToken(context.Background())
+			if err != nil {
+				t.Errorf("expected no error, got %s", err)
+			}
+
+			if token.BotUsername != "" {
+				t.Errorf("expected token bot username to be empty, got=%q", token.BotUsername)
+			}
+		}
+
+		if v := bot.Load(); v != 1 {
+			t.Errorf("expected failure to be cached, got %d requests", v)
+		}
+
+		// Expire the cached failure.
+		transport.bot.failed.Store(&botUserFailure{err: errors.New("expired"), until: time.Now()})
+		if _, err := transport.BotUserMetadata(context.Background()); err == nil {
+			t.Errorf("expected BotUserMetadata to return an error")
+		}
+
+		if v := bot.Load(); v != 2 {
+			t.Errorf("expected bot user to be fetched after cached failure expires, got %d requests", v)
+		}
+	})
+
+	t.Run("context", func(t *testing.T) {
+		server, bot := newServer(t, http.StatusOK)
+		transport, err := NewTransport(context.Background(), apitestdata.AppID, testkeys.RSA2048(),
+			WithEndpoint(server.URL),
+			WithInstallationID(apitestdata.InstallationID),
+		)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := transport.BotUserMetadata(ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected %s, got %v", context.Canceled, err)
+		}
+
+		v, err := transport.BotUserMetadata(context.Background())
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		expect := BotUser{
+			Username:       apitestdata.AppSlug + "[bot]",
+			ID:             apitestdata.BotUserID,
+			CommitterName:  apitestdata.AppSlug + "[bot]",
+			CommitterEmail: fmt.Sprintf("%d+%s[bot]@users.noreply.github.com", apitestdata.BotUserID, apitestdata.AppSlug),
+		}
+		if v != expect {
+			t.Errorf("expected bot user=%#v, got=%#v", expect, v)
+		}
+
+		assertBot(t, transport)
+		if v := bot.Load(); v != 1 {
+			t.Errorf("expected bot user to be fetched once, got %d requests", v)
+		}
+	})
+
+	t.Run("eager-error", func(t *testing.T) {
+		server, bot := newServer(t, http.StatusNotFound)
+		_, err := NewTransport(context.Background(), apitestdata.AppID, testkeys.RSA2048(),
+			WithEndpoint(server.URL),
+			WithInstallationID(apitestdata.InstallationID),
+			WithEagerBotMetadata(),
+		)
+		if err == nil {
+			t.Fatalf("expected an error, got nil")
+		}
+
+		if v := bot.Load(); v != 1 {
+			t.Errorf("expected bot user to be fetched once, got %d requests", v)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		server, bot := newServer(t, http.StatusOK)
+		transport, err := NewTransport(context.Background(), apitestdata.AppID, testkeys.RSA2048(),
+			WithEndpoint(server.URL),
+			WithInstallationID(apitestdata.InstallationID),
+			WithoutBotMetadata(),
+			WithEagerBotMetadata(),
+		)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		if v := transport.BotUsername(); v != "" {
+			t.Errorf("expected bot username to be empty, got=%q", v)
+		}
+
+		if v, err := transport.BotUserMetadata(context.Background()); err != nil || v != (BotUser{}) {
+			t.Errorf("expected empty bot user and no error, got %#v, %v", v, err)
+		}
+
+		if _, err := transport.InstallationToken(context.Background()); err != nil {
+			t.Errorf("expected no error, got %s", err)
+		}
+
+		if v := bot.Load(); v != 0 {
+			t.Errorf("expected bot user not to be fetched, got %d requests", v)
+		}
+	})
+}
@@ -0,0 +1,354 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tprasadtp/go-githubapp/internal/api"
+)
+
+var (
+	_ Cache             = (*MemoryCache)(nil)
+	_ InstallationCache = (*InstallationMemoryCache)(nil)
+)
+
+// Cache caches installation access tokens. This allows multiple [Transport]s,
+// possibly in different processes, to share installation access tokens instead
+// of minting their own.
+//
+// Keys are opaque strings which encode app id, installation id, repositories
+// and permission scopes of the [Transport]. Thus, differently scoped tokens never
+// share the same key. Implementations MUST be safe for concurrent use.
+type Cache interface {
+	// Get returns cached installation token for the key. If the key is not
+	// found, this must return false.
+	Get(ctx context.Context, key string) (InstallationToken, bool)
+
+	// Set saves installation token for the key.
+	Set(ctx context.Context, key string, token InstallationToken) error
+
+	// Delete removes installation token for the key, if any.
+	Delete(ctx context.Context, key string) error
+}
+
+// MemoryCache is an in-memory implementation of [Cache].
+// Zero value is ready to use.
+type MemoryCache struct {
+	mu    sync.Mutex
+	items map[string]InstallationToken
+}
+
+// NewMemoryCache returns a new in-memory [Cache].
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{}
+}
+
+// Get returns cached installation token for the key. Expired tokens are
+// evicted and never returned.
+func (c *MemoryCache) Get(_ context.Context, key string) (InstallationToken, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	token, ok := c.items[key]
+	if !ok {
+		return InstallationToken{}, false
+	}
+
+	if !token.IsValid() {
+		delete(c.items, key)
+		return InstallationToken{}, false
+	}
+	return token, true
+}
+
+// Set saves installation token for the key.
+func (c *MemoryCache) Set(_ context.Context, key string, token InstallationToken) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.items == nil {
+		c.items = make(map[string]InstallationToken)
+	}
+	c.items[key] = token
+	return nil
+}
+
+// Delete removes installation token for the key, if any.
+func (c *MemoryCache) Delete(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.items, key)
+	return nil
+}
+
+// WithTokenCache configures [Transport] to use cache for installation access
+// tokens. Cache is consulted before minting a new installation access token,
+// and newly minted tokens are saved to the cache.
+func WithTokenCache(cache Cache) Option {
+	if cache == nil {
+		return nil
+	}
+	return &funcOption{
+		name: "WithTokenCache",
+		f: func(t *Transport) error {
+			t.cache = cache
+			return nil
+		},
+	}
+}
+
+// tokenCacheKey returns key to use for caching installation access tokens.
+// Key includes endpoint, app id, installation id, repositories, repository ids
+// and scopes.
+func (t *Transport) tokenCacheKey() string {
+	var sb strings.Builder
+	if t.baseURL != nil {
+		sb.WriteString(t.baseURL.String())
+	}
+	fmt.Fprintf(&sb, "|app:%d|installation:%d|repos:%s|permissions:%s",
+		t.appID, t.installID, strings.Join(t.repos, ","), permissionsKey(t.scopes))
+	if len(t.repoIDs) > 0 {
+		fmt.Fprintf(&sb, "|repo_ids:%v", t.repoIDs)
+	}
+	return sb.String()
+}
+
+// permissionsKey returns a stable string representation of scopes.
+func permissionsKey(scopes map[string]string) string {
+	// Sort scopes for a stable key.
+	items := make([]string, 0, len(scopes))
+	for scope, level := range scopes {
+		items = append(items, scope+":"+level)
+	}
+	slices.Sort(items)
+	return strings.Join(items, ",")
+}
+
+// InstallationCache caches installations and app metadata looked up by [NewTransport].
+// This allows multiple [Transport]s, for example one per webhook delivery, to share
+// installation lookups instead of fetching installation for the same owner every time.
+// Metadata is cached along with its validators (ETag and Last-Modified headers), and
+// is always re-validated with conditional requests, which do not count against the
+// rate limit if metadata has not changed. Thus, suspensions and permission changes
+// are never hidden by the cache.
+//
+// Keys and values are opaque, and values are valid for as long as the implementation
+// considers them fresh. Implementations MUST be safe for concurrent use.
+type InstallationCache interface {
+	// Get returns cached value for the key. If the key is not found or
+	// has expired, this must return false.
+	Get(ctx context.Context, key string) ([]byte, bool)
+
+	// Set saves value for the key.
+	Set(ctx context.Context, key string, value []byte) error
+}
+
+// InstallationMemoryCache is an in-memory, size bounded LRU implementation of
+// [InstallationCache], whose entries expire after a TTL.
+type InstallationMemoryCache struct {
+	mu    sync.Mutex
+	size  int
+	ttl   time.Duration
+	lru   *list.List
+	items map[string]*list.Element
+}
+
+// installationCacheEntry is an entry in [InstallationMemoryCache].
+type installationCacheEntry struct {
+	key   string
+	value []byte
+	exp   time.Time
+}
+
+// NewInstallationMemoryCache returns a new in-memory [InstallationCache], which
+// holds at-most size entries, each valid for ttl. If size is not positive, 128
+// is used. If ttl is not positive, 5 minutes is used.
+func NewInstallationMemoryCache(size int, ttl time.Duration) *InstallationMemoryCache {
+	if size <= 0 {
+		size = 128
+	}
+
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+
+	return &InstallationMemoryCache{
+		size:  size,
+		ttl:   ttl,
+		lru:   list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// Get returns cached value for the key. Expired entries are evicted
+// and never returned.
+func (c *InstallationMemoryCache) Get(_ context.Context, key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry, _ := elem.Value.(*installationCacheEntry)
+	if time.Now().After(entry.exp) {
+		c.lru.Remove(elem)
+		delete(c.items, key)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(elem)
+	return slices.Clone(entry.value), true
+}
+
+// Set saves value for the key, evicting least recently used entry
+// if cache is full.
+func (c *InstallationMemoryCache) Set(_ context.Context, key string, value []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &installationCacheEntry{
+		key:   key,
+		value: slices.Clone(value),
+		exp:   time.Now().Add(c.ttl),
+	}
+
+	if elem, ok := c.items[key]; ok {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return nil
+	}
+
+	c.items[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		if v, ok := oldest.Value.(*installationCacheEntry); ok {
+			delete(c.items, v.key)
+		}
+	}
+	return nil
+}
+
+// Len returns number of entries in the cache, including expired entries
+// which are yet to be evicted.
+func (c *InstallationMemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// WithInstallationCache configures [Transport] to use cache for installation
+// and app metadata lookups, i.e. this is also the metadata cache for conditional
+// requests. Requests for app and installation metadata are made conditional with
+// validators of the cached response, and if metadata has not changed, cached
+// response is used. Scoped permissions, repositories, single file and suspension
+// are always verified against the re-validated installation.
+//
+// Only responses with validators are cached. Owners which are organizations
+// are looked up via the organizations endpoint first, if it was cached.
+func WithInstallationCache(cache InstallationCache) Option {
+	if cache == nil {
+		return nil
+	}
+	return &funcOption{
+		name: "WithInstallationCache",
+		f: func(t *Transport) error {
+			t.installCache = cache
+			return nil
+		},
+	}
+}
+
+// metadataCacheKey returns key to use for caching metadata response for URL u.
+// Key includes app id and URL, which is lower cased as owner names are
+// case-insensitive.
+func (t *Transport) metadataCacheKey(u *url.URL) string {
+	return fmt.Sprintf("app:%d|%s", t.appID, strings.ToLower(u.String()))
+}
+
+// metadataCacheEntry is metadata response saved to [InstallationCache].
+type metadataCacheEntry struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	Body         []byte `json:"body,omitempty"`
+}
+
+// doMetadataRequest performs request r and returns the response along with its body.
+// Response body is always closed. If an [InstallationCache] is configured, request is made
+// conditional with validators of the cached response, and if server responds with
+// 304 (Not Modified), cached body is returned with status 200 (OK). Successful
+// responses with validators are saved to the cache.
+func (t *Transport) doMetadataRequest(client *http.Client, r *http.Request) (*http.Response, []byte, error) {
+	var entry metadataCacheEntry
+	var cached bool
+	var key string
+	if t.installCache != nil {
+		key = t.metadataCacheKey(r.URL)
+		if v, ok := t.installCache.Get(r.Context(), key); ok && json.Unmarshal(v, &entry) == nil {
+			cached = entry.ETag != "" || entry.LastModified != ""
+		}
+
+		if cached {
+			if entry.ETag != "" {
+				r.Header.Set(api.IfNoneMatchHeader, entry.ETag)
+			}
+
+			if entry.LastModified != "" {
+				r.Header.Set(api.IfModifiedSinceHeader, entry.LastModified)
+			}
+		}
+	}
+
+	resp, err := client.Do(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if t.installCache == nil {
+		return resp, data, nil
+	}
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		// Server must not respond with 304, if request was not conditional.
+		if !cached {
+			return resp, data, nil
+		}
+		resp.StatusCode = http.StatusOK
+		resp.Status = fmt.Sprintf("%d %s", http.StatusOK, http.StatusText(http.StatusOK))
+		return resp, entry.Body, nil
+	case http.StatusOK:
+		entry = metadataCacheEntry{
+			ETag:         resp.Header.Get(api.ETagHeader),
+			LastModified: resp.Header.Get(api.LastModifiedHeader),
+			Body:         data,
+		}
+		if entry.ETag != "" || entry.LastModified != "" {
+			if v, err := json.Marshal(entry); err == nil {
+				_ = t.installCache.Set(r.Context(), key, v)
+			}
+		}
+	}
+	return resp, data, nil
+}
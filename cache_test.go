@@ -0,0 +1,421 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tprasadtp/go-githubapp/internal/api"
+	"github.com/tprasadtp/go-githubapp/internal/testdata/apitestdata"
+	"github.com/tprasadtp/go-githubapp/internal/testkeys"
+)
+
+func TestMemoryCache(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCache()
+
+	t.Run("missing", func(t *testing.T) {
+		if _, ok := cache.Get(ctx, "missing"); ok {
+			t.Errorf("expected missing key to return false")
+		}
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		token := InstallationToken{Token: "ghs_token", Exp: time.Now().Add(time.Hour)}
+		err := cache.Set(ctx, "valid", token)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		got, ok := cache.Get(ctx, "valid")
+		if !ok {
+			t.Fatalf("expected valid token to be cached")
+		}
+
+		if got.Token != token.Token {
+			t.Errorf("expected token=%s, got=%s", token.Token, got.Token)
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		token := InstallationToken{Token: "ghs_token", Exp: time.Now().Add(-time.Minute)}
+		err := cache.Set(ctx, "expired", token)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if _, ok := cache.Get(ctx, "expired"); ok {
+			t.Errorf("expired tokens must not be returned")
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		token := InstallationToken{Token: "ghs_token", Exp: time.Now().Add(time.Hour)}
+		if err := cache.Set(ctx, "delete", token); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if err := cache.Delete(ctx, "delete"); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if _, ok := cache.Get(ctx, "delete"); ok {
+			t.Errorf("deleted tokens must not be returned")
+		}
+
+		if err := cache.Delete(ctx, "missing"); err != nil {
+			t.Errorf("deleting missing key must not fail, got %s", err)
+		}
+	})
+
+	t.Run("zero-value", func(t *testing.T) {
+		var c MemoryCache
+		if err := c.Delete(ctx, "key"); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		token := InstallationToken{Token: "ghs_token", Exp: time.Now().Add(time.Hour)}
+		if err := c.Set(ctx, "key", token); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if _, ok := c.Get(ctx, "key"); !ok {
+			t.Errorf("expected token to be cached")
+		}
+	})
+}
+
+func TestWithTokenCache(t *testing.T) {
+	t.Run("nil", func(t *testing.T) {
+		if WithTokenCache(nil) != nil {
+			t.Errorf("WithTokenCache with nil cache must return nil")
+		}
+	})
+
+	t.Run("non-nil", func(t *testing.T) {
+		transport := Transport{}
+		err := Options(WithTokenCache(NewMemoryCache())).apply(&transport)
+		if err != nil {
+			t.Errorf("expected no error, got %s", err)
+		}
+
+		if transport.cache == nil {
+			t.Errorf("transport.cache should be non nil")
+		}
+	})
+}
+
+func TestTransport_tokenCacheKey(t *testing.T) {
+	a := &Transport{appID: 99, installID: 99, repos: []string{"foo"}}
+	b := &Transport{appID: 99, installID: 99, repos: []string{"foo"}, scopes: map[string]string{"issues": "read"}}
+	c := &Transport{appID: 99, installID: 99, repos: []string{"bar"}}
+	d := &Transport{appID: 99, installID: 9, repos: []string{"foo"}}
+	e := &Transport{appID: 99, installID: 99, repoIDs: []int64{1}}
+	f := &Transport{appID: 99, installID: 99, repoIDs: []int64{1, 2}}
+
+	keys := map[string]struct{}{}
+	for _, item := range []*Transport{a, b, c, d, e, f} {
+		keys[item.tokenCacheKey()] = struct{}{}
+	}
+
+	if len(keys) != 6 {
+		t.Errorf("differently scoped transports must have different keys: %v", keys)
+	}
+
+	g := &Transport{
+		appID:     99,
+		installID: 99,
+		scopes:    map[string]string{"issues": "read", "contents": "read", "metadata": "read"},
+	}
+	for i := 0; i < 10; i++ {
+		if g.tokenCacheKey() != g.tokenCacheKey() {
+			t.Fatalf("key must be stable")
+		}
+	}
+}
+
+func TestTransport_SharedTokenCache(t *testing.T) {
+	var mints atomic.Int64
+	u, _ := url.Parse("https://api.go-githubapp.test/")
+	cache := NewMemoryCache()
+	next := api.RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+		resp := httptest.NewRecorder()
+		if strings.HasSuffix(r.URL.Path, "/access_tokens") {
+			mints.Add(1)
+			resp.WriteHeader(http.StatusCreated)
+			_, _ = resp.WriteString(`{"token":"ghs_token","expires_at":"2099-01-01T00:00:00Z"}`)
+			return resp.Result(), nil
+		}
+		resp.WriteHeader(http.StatusOK)
+		return resp.Result(), nil
+	})
+
+	newTransport := func() *Transport {
+		return &Transport{
+			appID:     99,
+			installID: 99,
+			baseURL:   u,
+			minter:    &jwtRS256{internal: testkeys.RSA2048()},
+			next:      next,
+			cache:     cache,
+		}
+	}
+
+	for _, transport := range []*Transport{newTransport(), newTransport()} {
+		r, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, u.JoinPath("repos").String(), nil)
+		resp, err := transport.RoundTrip(r)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		resp.Body.Close()
+	}
+
+	if v := mints.Load(); v != 1 {
+		t.Errorf("expected only one token to be minted, got %d", v)
+	}
+}
+
+func TestInstallationMemoryCache(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("missing", func(t *testing.T) {
+		cache := NewInstallationMemoryCache(2, time.Minute)
+		if _, ok := cache.Get(ctx, "missing"); ok {
+			t.Errorf("expected missing key to return false")
+		}
+	})
+
+	t.Run("lru", func(t *testing.T) {
+		cache := NewInstallationMemoryCache(2, time.Minute)
+		_ = cache.Set(ctx, "a", []byte("a"))
+		_ = cache.Set(ctx, "b", []byte("b"))
+
+		// Access a, so that b is least recently used.
+		if v, ok := cache.Get(ctx, "a"); !ok || string(v) != "a" {
+			t.Errorf("expected a to be cached, got %q", v)
+		}
+
+		_ = cache.Set(ctx, "c", []byte("c"))
+		if _, ok := cache.Get(ctx, "b"); ok {
+			t.Errorf("expected b to be evicted")
+		}
+
+		for _, key := range []string{"a", "c"} {
+			if v, ok := cache.Get(ctx, key); !ok || string(v) != key {
+				t.Errorf("expected %s to be cached, got %q", key, v)
+			}
+		}
+
+		if v := cache.Len(); v != 2 {
+			t.Errorf("expected cache len=2, got=%d", v)
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		cache := NewInstallationMemoryCache(2, time.Nanosecond)
+		_ = cache.Set(ctx, "a", []byte("a"))
+		time.Sleep(time.Millisecond)
+		if _, ok := cache.Get(ctx, "a"); ok {
+			t.Errorf("expired entries must not be returned")
+		}
+
+		if v := cache.Len(); v != 0 {
+			t.Errorf("expected expired entry to be evicted, got len=%d", v)
+		}
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		cache := NewInstallationMemoryCache(0, 0)
+		if cache.size != 128 || cache.ttl != 5*time.Minute {
+			t.Errorf("expected default size and ttl, got size=%d, ttl=%s", cache.size, cache.ttl)
+		}
+	})
+}
+
+func TestWithInstallationCache(t *testing.T) {
+	t.Run("nil", func(t *testing.T) {
+		if WithInstallationCache(nil) != nil {
+			t.Errorf("WithInstallationCache with nil cache must return nil")
+		}
+	})
+
+	t.Run("non-nil", func(t *testing.T) {
+		transport := Transport{}
+		err := Options(WithInstallationCache(NewInstallationMemoryCache(1, time.Minute))).apply(&transport)
+		if err != nil {
+			t.Errorf("expected no error, got %s", err)
+		}
+
+		if transport.installCache == nil {
+			t.Errorf("transport.installCache should be non nil")
+		}
+	})
+}
+
+func TestNewTransport_SharedInstallationCache(t *testing.T) {
+	m := apitestdata.Get(t)
+
+	// newServer returns a server which responds to conditional installation
+	// lookups by owner, via users or organizations endpoint, and counts them.
+	newServer := func(t *testing.T, org bool) (*httptest.Server, *atomic.Int64, *atomic.Int64) {
+		var lookups, notModified atomic.Int64
+		users := strings.ToLower(fmt.Sprintf("/users/%s/installation", apitestdata.InstallationOwner))
+		orgs := strings.ToLower(fmt.Sprintf("/orgs/%s/installation", apitestdata.InstallationOwner))
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path := strings.ToLower(r.URL.Path)
+			switch {
+			case path == "/app":
+				_, _ = w.Write(m["get-app"])
+			case path == users && org:
+				lookups.Add(1)
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write(m["error-not-found"])
+			case path == users || path == orgs:
+				lookups.Add(1)
+				if r.Header.Get(api.IfNoneMatchHeader) == `"installation"` {
+					notModified.Add(1)
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				w.Header().Set(api.ETagHeader, `"installation"`)
+				_, _ = w.Write(m["get-installation-by-user"])
+			case path == strings.ToLower(fmt.Sprintf("/app/installations/%d/access_tokens", apitestdata.InstallationID)):
+				w.WriteHeader(http.StatusCreated)
+				_, _ = w.Write([]byte(`{"token":"ghs_token","expires_at":"2099-01-01T00:00:00Z"}`))
+			default:
+				t.Errorf("Unknown/Invalid Request => %s", r.URL)
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		t.Cleanup(server.Close)
+		return server, &lookups, &notModified
+	}
+
+	tt := []struct {
+		name    string
+		org     bool
+		lookups int64
+	}{
+		// Cached installation is re-validated by the second transport.
+		{name: "user", lookups: 2},
+		// Users endpoint is not tried again, once owner is resolved
+		// via organizations endpoint.
+		{name: "organization", org: true, lookups: 3},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			server, lookups, notModified := newServer(t, tc.org)
+			cache := NewInstallationMemoryCache(8, time.Minute)
+			for _, owner := range []string{apitestdata.InstallationOwner, strings.ToUpper(apitestdata.InstallationOwner)} {
+				transport, err := NewTransport(context.Background(), apitestdata.AppID, testkeys.RSA2048(),
+					WithEndpoint(server.URL),
+					WithOwner(owner),
+					WithInstallationCache(cache),
+					WithoutBotMetadata(),
+				)
+				if err != nil {
+					t.Fatalf("expected no error, got %s", err)
+				}
+
+				if v := transport.InstallationID(); v != apitestdata.InstallationID {
+					t.Errorf("expected installation id=%d, got=%d", apitestdata.InstallationID, v)
+				}
+			}
+
+			if v := lookups.Load(); v != tc.lookups {
+				t.Errorf("expected %d installation lookups, got %d", tc.lookups, v)
+			}
+
+			if v := notModified.Load(); v != 1 {
+				t.Errorf("expected 1 not modified response, got %d", v)
+			}
+		})
+	}
+}
+
+func TestNewTransport_ConditionalMetadataRequests(t *testing.T) {
+	m := apitestdata.Get(t)
+	var notModified, lookups atomic.Int64
+	var suspended atomic.Bool
+	conditional := func(w http.ResponseWriter, r *http.Request, etag string, data []byte) {
+		if r.Header.Get(api.IfNoneMatchHeader) == etag {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set(api.ETagHeader, etag)
+		_, _ = w.Write(data)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app":
+			conditional(w, r, `"app"`, m["get-app"])
+		case fmt.Sprintf("/app/installations/%d", apitestdata.InstallationID):
+			lookups.Add(1)
+			if suspended.Load() {
+				conditional(w, r, `"suspended"`, m["get-installation-disabled"])
+				return
+			}
+			conditional(w, r, `"installation"`, m["get-installation-by-id"])
+		case fmt.Sprintf("/app/installations/%d/access_tokens", apitestdata.InstallationID):
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"token":"ghs_token","expires_at":"2099-01-01T00:00:00Z"}`))
+		default:
+			t.Errorf("Unknown/Invalid Request => %s", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	cache := NewInstallationMemoryCache(8, time.Hour)
+	for i := 0; i < 2; i++ {
+		transport, err := NewTransport(context.Background(), apitestdata.AppID, testkeys.RSA2048(),
+			WithEndpoint(server.URL),
+			WithInstallationID(apitestdata.InstallationID),
+			WithInstallationCache(cache),
+			WithoutBotMetadata(),
+		)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		if v := transport.AppName(); v != apitestdata.AppSlug {
+			t.Errorf("expected app name=%s, got=%s", apitestdata.AppSlug, v)
+		}
+
+		if v := transport.InstallationAccount().ID; v != apitestdata.InstallationOwnerID {
+			t.Errorf("expected installation account id=%d, got=%d", apitestdata.InstallationOwnerID, v)
+		}
+	}
+
+	// App and installation are re-validated by the second transport.
+	if v := notModified.Load(); v != 2 {
+		t.Errorf("expected 2 not modified responses, got %d", v)
+	}
+
+	if v := lookups.Load(); v != 2 {
+		t.Errorf("expected installation to be looked up twice, got %d", v)
+	}
+
+	// Installation is suspended after it was cached.
+	suspended.Store(true)
+	_, err := NewTransport(context.Background(), apitestdata.AppID, testkeys.RSA2048(),
+		WithEndpoint(server.URL),
+		WithInstallationID(apitestdata.InstallationID),
+		WithInstallationCache(cache),
+		WithoutBotMetadata(),
+	)
+	if !errors.Is(err, ErrInstallationSuspended) {
+		t.Errorf("expected %s, got %v", ErrInstallationSuspended, err)
+	}
+}
@@ -0,0 +1,143 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tprasadtp/go-githubapp/internal/api"
+)
+
+// defaultClientTimeout is default timeout of [http.Client] returned by [Transport.Client].
+const defaultClientTimeout = 30 * time.Second
+
+// ClientOption configures [http.Client] returned by [Transport.Client].
+type ClientOption interface {
+	apply(*clientConfig)
+}
+
+// clientConfig is configuration for [http.Client] returned by [Transport.Client].
+type clientConfig struct {
+	timeout   time.Duration // client timeout
+	crossHost bool          // follow cross-host redirects without credentials
+}
+
+var (
+	_ ClientOption      = (*clientFuncOption)(nil)
+	_ http.RoundTripper = (*redirectTransport)(nil)
+)
+
+// clientFuncOption implements [ClientOption].
+type clientFuncOption struct {
+	f func(*clientConfig)
+}
+
+func (opt *clientFuncOption) apply(c *clientConfig) {
+	opt.f(c)
+}
+
+// WithClientTimeout configures timeout of [http.Client] returned by [Transport.Client].
+// Timeout includes connection time, any redirects, and reading the response body,
+// and also applies to token renewals triggered by the request. If zero, client
+// has no timeout. Negative values are ignored.
+func WithClientTimeout(d time.Duration) ClientOption {
+	return &clientFuncOption{
+		f: func(c *clientConfig) {
+			if d >= 0 {
+				c.timeout = d
+			}
+		},
+	}
+}
+
+// WithClientCrossHostRedirects configures [http.Client] returned by [Transport.Client]
+// to follow redirects to a different host, like those issued for downloading
+// release assets and repository archives. Requests to a different host, are
+// sent without any credentials via the round tripper configured with
+// [WithRoundTripper], even if host is allowed via [WithAdditionalHosts].
+// Redirects from https to http are never followed.
+func WithClientCrossHostRedirects() ClientOption {
+	return &clientFuncOption{
+		f: func(c *clientConfig) {
+			c.crossHost = true
+		},
+	}
+}
+
+// Client returns a new [http.Client] which uses the [Transport]. Unlike using
+// the [Transport] with a zero value [http.Client], the returned client has
+// a default timeout of 30 seconds, and refuses to follow redirects to a different
+// host or from https to http, to avoid leaking credentials. Use [WithClientTimeout]
+// and [WithClientCrossHostRedirects] to override them.
+//
+//	client := transport.Client(githubapp.WithClientTimeout(time.Minute))
+func (t *Transport) Client(opts ...ClientOption) *http.Client {
+	cfg := clientConfig{
+		timeout: defaultClientTimeout,
+	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt.apply(&cfg)
+		}
+	}
+
+	if !cfg.crossHost {
+		client := newInternalClient(t)
+		client.Timeout = cfg.timeout
+		return client
+	}
+
+	return &http.Client{
+		Transport:     &redirectTransport{t: t},
+		CheckRedirect: checkCrossHostRedirect,
+		Timeout:       cfg.timeout,
+	}
+}
+
+// checkCrossHostRedirect refuses redirects which downgrade scheme from https to http.
+// Otherwise, it follows default policy of stopping after 10 redirects.
+func checkCrossHostRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+
+	prev := via[len(via)-1]
+	if strings.EqualFold(prev.URL.Scheme, "https") && !strings.EqualFold(req.URL.Scheme, "https") {
+		return fmt.Errorf("refusing to follow redirect from https to %s", req.URL.Scheme)
+	}
+	return nil
+}
+
+// redirectTransport sends requests created by following a redirect to a host other
+// than the host of the original request, without credentials, via the next round
+// tripper of [Transport]. All other requests are sent via the [Transport].
+type redirectTransport struct {
+	t *Transport
+}
+
+func (rt *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Response is only populated for requests created by following redirects.
+	// Walk the redirect chain to find the original request.
+	origin := req
+	for origin.Response != nil && origin.Response.Request != nil {
+		origin = origin.Response.Request
+	}
+
+	if strings.EqualFold(req.URL.Host, origin.URL.Host) {
+		return rt.t.RoundTrip(req)
+	}
+
+	// Authorization header is already removed by the client for cross-domain
+	// redirects, but it may be retained for subdomains.
+	clone := cloneRequest(req)
+	clone.Header.Del(api.AuthzHeader)
+
+	//nolint:wrapcheck // don't wrap errors returned by underlying round-tripper.
+	return rt.t.next.RoundTrip(clone)
+}
@@ -0,0 +1,194 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tprasadtp/go-githubapp/internal/api"
+	"github.com/tprasadtp/go-githubapp/internal/testkeys"
+)
+
+func TestTransport_Client(t *testing.T) {
+	// Server on a different host, which records Authorization header, if any.
+	var leaked atomic.Value
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		leaked.Store(r.Header.Get(api.AuthzHeader))
+		switch r.URL.Path {
+		case "/hop":
+			// Redirect again on the same external host.
+			http.Redirect(w, r, "/asset", http.StatusFound)
+		default:
+			_, _ = w.Write([]byte("asset"))
+		}
+	}))
+	t.Cleanup(external.Close)
+
+	// Use a different host name for the external server, as both servers
+	// listen on the loopback address.
+	externalURL, _ := url.Parse(external.URL)
+	externalURL.Host = strings.Replace(externalURL.Host, "127.0.0.1", "localhost", 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/download":
+			http.Redirect(w, r, externalURL.JoinPath("asset").String(), http.StatusFound)
+		case "/download-hop":
+			http.Redirect(w, r, externalURL.JoinPath("hop").String(), http.StatusFound)
+		case "/moved":
+			http.Redirect(w, r, "/repos", http.StatusMovedPermanently)
+		case "/repos":
+			_, _ = w.Write([]byte(r.Header.Get(api.AuthzHeader)))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	u, _ := url.Parse(server.URL)
+	transport := &Transport{
+		appID:   99,
+		ua:      api.UAHeaderValue,
+		baseURL: u,
+		minter:  &jwtRS256{internal: testkeys.RSA2048()},
+		next:    http.DefaultTransport,
+		// External host is allowed, to ensure credentials are not sent on
+		// redirects, even to allowed hosts.
+		hosts: []string{canonicalHost(externalURL.Scheme, externalURL.Host)},
+	}
+
+	get := func(client *http.Client, path string) (string, error) {
+		r, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL+path, nil)
+		resp, err := client.Do(r)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+
+		data, err := io.ReadAll(resp.Body)
+		return string(data), err
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		client := transport.Client()
+		if client.Timeout != defaultClientTimeout {
+			t.Errorf("expected timeout=%s, got=%s", defaultClientTimeout, client.Timeout)
+		}
+
+		// Same host redirects are followed with credentials.
+		v, err := get(client, "/moved")
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		if !strings.HasPrefix(v, "Bearer ") {
+			t.Errorf("expected request to be authenticated, got %q", v)
+		}
+
+		// Cross host redirects are refused.
+		leaked.Store("")
+		_, err = get(client, "/download")
+		if err == nil {
+			t.Errorf("expected an error for cross-host redirect, got nil")
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		client := transport.Client(WithClientTimeout(time.Minute))
+		if client.Timeout != time.Minute {
+			t.Errorf("expected timeout=%s, got=%s", time.Minute, client.Timeout)
+		}
+
+		client = transport.Client(WithClientTimeout(0))
+		if client.Timeout != 0 {
+			t.Errorf("expected no timeout, got=%s", client.Timeout)
+		}
+
+		client = transport.Client(WithClientTimeout(-time.Minute), nil)
+		if client.Timeout != defaultClientTimeout {
+			t.Errorf("expected timeout=%s, got=%s", defaultClientTimeout, client.Timeout)
+		}
+	})
+
+	t.Run("cross-host-redirects", func(t *testing.T) {
+		client := transport.Client(WithClientCrossHostRedirects())
+		for _, path := range []string{"/download", "/download-hop"} {
+			leaked.Store("")
+			v, err := get(client, path)
+			if err != nil {
+				t.Fatalf("expected no error, got %s", err)
+			}
+
+			if v != "asset" {
+				t.Errorf("expected response=asset, got=%q", v)
+			}
+
+			if v, _ := leaked.Load().(string); v != "" {
+				t.Errorf("credentials leaked on cross-host redirect(%s): %q", path, v)
+			}
+		}
+
+		// Same host redirects are still authenticated.
+		v, err := get(client, "/moved")
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		if !strings.HasPrefix(v, "Bearer ") {
+			t.Errorf("expected request to be authenticated, got %q", v)
+		}
+	})
+}
+
+func TestCheckCrossHostRedirect(t *testing.T) {
+	prev := httptest.NewRequest(http.MethodGet, "https://api.github.com/repos/foo/bar/zipball", nil)
+	tt := []struct {
+		name string
+		url  string
+		via  int
+		ok   bool
+	}{
+		{
+			name: "cross-host",
+			url:  "https://codeload.github.com/foo/bar",
+			via:  1,
+			ok:   true,
+		},
+		{
+			name: "downgrade",
+			url:  "http://codeload.github.com/foo/bar",
+			via:  1,
+		},
+		{
+			name: "too-many",
+			url:  "https://codeload.github.com/foo/bar",
+			via:  10,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			via := make([]*http.Request, 0, tc.via)
+			for i := 0; i < tc.via; i++ {
+				via = append(via, prev)
+			}
+
+			err := checkCrossHostRedirect(httptest.NewRequest(http.MethodGet, tc.url, nil), via)
+			if tc.ok && err != nil {
+				t.Errorf("expected no error, got %s", err)
+			}
+
+			if !tc.ok && err == nil {
+				t.Errorf("expected an error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"errors"
+)
+
+// Config is configuration for [Transport], which can be loaded from
+// JSON or YAML files. Use [Config.Options] to build options for [NewTransport].
+//
+//	var config githubapp.Config
+//	err := yaml.Unmarshal(data, &config)
+//	...
+//	opts, err := config.Options()
+//	...
+//	transport, err := githubapp.NewTransport(ctx, config.AppID, signer, opts...)
+type Config struct {
+	// GitHub app ID.
+	AppID uint64 `json:"app_id,omitempty" yaml:"appID,omitempty"`
+
+	// GitHub API endpoint. If omitted, default endpoint is used.
+	// See [WithEndpoint] for more info.
+	Endpoint string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+
+	// Installation ID. See [WithInstallationID] for more info.
+	InstallationID uint64 `json:"installation_id,omitempty" yaml:"installationID,omitempty"`
+
+	// Installation owner. See [WithOwner] for more info.
+	Owner string `json:"owner,omitempty" yaml:"owner,omitempty"`
+
+	// Repositories to scope the installation access tokens to.
+	// See [WithRepositories] for more info.
+	Repositories []string `json:"repositories,omitempty" yaml:"repositories,omitempty"`
+
+	// Permissions to scope the installation access tokens to, as a map
+	// of scope to access level. See [WithPermissionsMap] for more info.
+	Permissions map[string]string `json:"permissions,omitempty" yaml:"permissions,omitempty"`
+
+	// User agent used for API requests. See [WithUserAgent] for more info.
+	UserAgent string `json:"user_agent,omitempty" yaml:"user_agent,omitempty"`
+}
+
+// Options returns options for [NewTransport] from the config. Options are
+// validated, and if any of them are invalid or conflict with each other,
+// an error aggregating all of them is returned. Fields which are empty are
+// omitted. AppID is not an option, and must be passed to [NewTransport].
+func (c Config) Options() ([]Option, error) {
+	var opts []Option
+
+	if c.Endpoint != "" {
+		opts = append(opts, WithEndpoint(c.Endpoint))
+	}
+
+	if c.InstallationID != 0 {
+		opts = append(opts, WithInstallationID(c.InstallationID))
+	}
+
+	if c.Owner != "" {
+		opts = append(opts, WithOwner(c.Owner))
+	}
+
+	if len(c.Repositories) > 0 {
+		opts = append(opts, WithRepositories(c.Repositories...))
+	}
+
+	if len(c.Permissions) > 0 {
+		opts = append(opts, WithPermissionsMap(c.Permissions))
+	}
+
+	if c.UserAgent != "" {
+		opts = append(opts, WithUserAgent(c.UserAgent))
+	}
+
+	// Validate options, same as NewTransport.
+	var err error
+	if c.AppID == 0 {
+		err = errors.New("githubapp: app id cannot be zero")
+	}
+
+	if _, terr := newTransport(c.AppID, opts...); terr != nil {
+		err = errors.Join(err, terr)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
@@ -0,0 +1,143 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"encoding/json"
+	"maps"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestConfig_Options(t *testing.T) {
+	tt := []struct {
+		name   string
+		config Config
+		ok     bool
+		expect *Transport
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:   "app-only",
+			config: Config{AppID: 99},
+			ok:     true,
+			expect: &Transport{appID: 99},
+		},
+		{
+			name: "all",
+			config: Config{
+				AppID:          99,
+				Endpoint:       "https://api.go-githubapp.test/",
+				InstallationID: 12345,
+				Owner:          "gh-integration-tests",
+				Repositories:   []string{"foo", "bar"},
+				Permissions:    map[string]string{"issues": "write", "contents": "read"},
+				UserAgent:      "my-app/1.0",
+			},
+			ok: true,
+			expect: &Transport{
+				appID:     99,
+				installID: 12345,
+				owner:     "gh-integration-tests",
+				repos:     []string{"bar", "foo"},
+				scopes:    map[string]string{"issues": "write", "contents": "read"},
+				ua:        "my-app/1.0",
+			},
+		},
+		{
+			name: "repositories-without-owner",
+			config: Config{
+				AppID:        99,
+				Repositories: []string{"foo"},
+			},
+		},
+		{
+			name: "conflicting-owner",
+			config: Config{
+				AppID:        99,
+				Owner:        "gh-integration-tests",
+				Repositories: []string{"other/foo"},
+			},
+		},
+		{
+			name: "invalid-permissions",
+			config: Config{
+				AppID:       99,
+				Permissions: map[string]string{"issues": "yes"},
+			},
+		},
+		{
+			name: "invalid-endpoint",
+			config: Config{
+				AppID:    99,
+				Endpoint: "ftp://api.go-githubapp.test/",
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			opts, err := tc.config.Options()
+			if !tc.ok {
+				if err == nil {
+					t.Errorf("expected an error, got nil")
+				}
+				if opts != nil {
+					t.Errorf("expected options to be nil on error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error, got %s", err)
+			}
+
+			transport := Transport{appID: tc.config.AppID}
+			for _, opt := range opts {
+				if err = opt.apply(&transport); err != nil {
+					t.Fatalf("expected no error, got %s", err)
+				}
+			}
+
+			slices.Sort(transport.repos)
+			if transport.appID != tc.expect.appID ||
+				transport.installID != tc.expect.installID ||
+				transport.owner != tc.expect.owner ||
+				transport.ua != tc.expect.ua ||
+				!slices.Equal(transport.repos, tc.expect.repos) ||
+				!maps.Equal(transport.scopes, tc.expect.scopes) {
+				t.Errorf("expected=%#v, got=%#v", tc.expect, &transport)
+			}
+		})
+	}
+}
+
+func TestConfig_JSON(t *testing.T) {
+	config := Config{
+		AppID:          99,
+		Endpoint:       "https://api.go-githubapp.test/",
+		InstallationID: 12345,
+		Owner:          "gh-integration-tests",
+		Repositories:   []string{"foo", "bar"},
+		Permissions:    map[string]string{"issues": "write"},
+		UserAgent:      "my-app/1.0",
+	}
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	var got Config
+	err = json.Unmarshal(data, &got)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	if !reflect.DeepEqual(config, got) {
+		t.Errorf("expected=%#v, got=%#v", config, got)
+	}
+}
@@ -0,0 +1,323 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+)
+
+// ctxInstallationIDKey is context key for installation id override.
+type ctxInstallationIDKey struct{}
+
+// ContextWithInstallationID returns a copy of the context, which configures
+// [Transport] to authenticate as the installation specified, instead of its
+// default installation, for requests made with the context. This is useful in
+// multi-tenant handlers, which act as a different installation for each request,
+// and avoids creating a [Transport] per installation.
+//
+// Installation MUST belong to the same app as the [Transport]. It is verified
+// when the installation is first used. Installation access tokens are cached per
+// installation, for up to 1024 most recently used installations, and are scoped
+// to permissions configured via [WithPermissions], if any. Repository options
+// like [WithRepositories] do not apply.
+func ContextWithInstallationID(ctx context.Context, id uint64) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, ctxInstallationIDKey{}, id)
+}
+
+// ctxInstallationID returns installation id override from the context if any.
+func ctxInstallationID(ctx context.Context) uint64 {
+	id, _ := ctx.Value(ctxInstallationIDKey{}).(uint64)
+	return id
+}
+
+// ctxPermissionsKey is context key for per-request permissions.
+type ctxPermissionsKey struct{}
+
+// WithRequestPermissions returns a copy of the context, which configures [Transport]
+// to use an installation access token scoped to the permissions specified, for
+// requests made with the context. This is useful to follow principle of least
+// privilege for sensitive requests, while re-using a [Transport] with broader
+// permissions for other requests. Permissions are specified as a map of scope
+// to access level, same as [WithPermissionsMap].
+//
+// Scoped tokens are cached per permission set, for up to 1024 most recently used
+// permission sets, and are scoped to repositories configured for the [Transport],
+// if any. Permissions are ignored for requests which authenticate as the app
+// (using JWT). Invalid permissions cause requests to fail.
+func WithRequestPermissions(ctx context.Context, permissions map[string]string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, ctxPermissionsKey{}, maps.Clone(permissions))
+}
+
+// ctxPermissions returns per-request permissions from the context if any.
+func ctxPermissions(ctx context.Context) map[string]string {
+	permissions, _ := ctx.Value(ctxPermissionsKey{}).(map[string]string)
+	return permissions
+}
+
+// ctxExistingAuthzKey is context key to preserve existing 'Authorization' header.
+type ctxExistingAuthzKey struct{}
+
+// WithExistingAuthorization returns a copy of the context, which configures
+// [Transport] to leave 'Authorization' header of requests made with the context
+// intact, if it is already set. This is similar to [WithPreserveAuthorizationHeader],
+// but only applies to requests made with the context. Requests without an
+// 'Authorization' header are authenticated as usual.
+func WithExistingAuthorization(ctx context.Context) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, ctxExistingAuthzKey{}, true)
+}
+
+// ctxExistingAuthz returns true if existing 'Authorization' header
+// must be preserved for the request.
+func ctxExistingAuthz(ctx context.Context) bool {
+	v, _ := ctx.Value(ctxExistingAuthzKey{}).(bool)
+	return v
+}
+
+// derive returns a new [Transport] with the same configuration as t,
+// which shares the JWT with t. Installation options and state, including
+// single file configured via [WithSingleFile], are not copied.
+func (t *Transport) derive() *Transport {
+	app := t
+	if t.app != nil {
+		app = t.app
+	}
+
+	return &Transport{
+		appID:           t.appID,
+		appSlug:         t.appSlug,
+		appMeta:         t.appMeta,
+		ua:              t.ua,
+		uaInstallID:     t.uaInstallID,
+		uaComments:      t.uaComments,
+		uaSuffix:        t.uaSuffix,
+		next:            t.next,
+		httpClient:      t.httpClient,
+		baseURL:         t.baseURL,
+		signer:          t.signer,
+		minter:          t.minter,
+		scopes:          maps.Clone(t.scopes),
+		cache:           t.cache,
+		installCache:    t.installCache,
+		bootstrapBudget: t.bootstrapBudget,
+		rateLimitWait:   t.rateLimitWait,
+		logger:          t.logger,
+		apiVersion:      t.apiVersion,
+		metrics:         t.metrics,
+		hosts:           t.hosts,
+		app:             app,
+		skipBot:         t.skipBot,
+		eagerBot:        t.eagerBot,
+		validateOnly:    t.validateOnly,
+		requestTimeout:  t.requestTimeout,
+		onTokenRefresh:  t.onTokenRefresh,
+		expiryMargin:    t.expiryMargin,
+		editors:         t.editors,
+	}
+}
+
+// requestTransport returns [Transport] which provides installation access token
+// for the request, honoring installation id and permissions specified via the
+// context, if any. If none are specified, this returns t.
+func (t *Transport) requestTransport(ctx context.Context) (*Transport, error) {
+	// Static installation tokens cannot be exchanged for other installations
+	// or scopes, as JWT is not available.
+	id := ctxInstallationID(ctx)
+	if t.static != nil && ((id != 0 && id != t.installID) || len(ctxPermissions(ctx)) != 0) {
+		return nil, errors.New("githubapp: installation id and permissions from context " +
+			"are not supported with static installation token")
+	}
+
+	it := t
+	if id != 0 && id != t.installID {
+		var err error
+		it, err = t.installationTransport(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if permissions := ctxPermissions(ctx); len(permissions) != 0 {
+		var err error
+		it, err = it.scopedTransport(permissions)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return it, nil
+}
+
+// maxCachedTransports is the maximum number of transports for installations
+// specified via [ContextWithInstallationID] or permissions specified via
+// [WithRequestPermissions], each cached by a [Transport].
+const maxCachedTransports = 1024
+
+// transportCache is a LRU cache of transports keyed by installation id
+// or permission set. Zero value is ready to use.
+type transportCache[K comparable] struct {
+	mu    sync.Mutex
+	size  int // if zero, maxCachedTransports is used
+	ll    *list.List
+	items map[K]*list.Element
+}
+
+// transportCacheEntry is an entry in [transportCache].
+type transportCacheEntry[K comparable] struct {
+	id K
+	t  *Transport
+}
+
+// load returns transport for the key, if cached.
+func (c *transportCache[K]) load(id K) (*Transport, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.items[id]; ok {
+		c.ll.MoveToFront(e)
+		entry, _ := e.Value.(*transportCacheEntry[K])
+		return entry.t, true
+	}
+	return nil, false
+}
+
+// loadOrStore returns transport for the key if cached, otherwise it caches t
+// and returns it. Least recently used transports are evicted when cache is full.
+// Evicted transports are not closed, as they might be in use.
+func (c *transportCache[K]) loadOrStore(id K, t *Transport) *Transport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.items[id]; ok {
+		c.ll.MoveToFront(e)
+		entry, _ := e.Value.(*transportCacheEntry[K])
+		return entry.t
+	}
+
+	if c.ll == nil {
+		c.ll = list.New()
+		c.items = make(map[K]*list.Element)
+	}
+
+	c.items[id] = c.ll.PushFront(&transportCacheEntry[K]{id: id, t: t})
+
+	size := c.size
+	if size <= 0 {
+		size = maxCachedTransports
+	}
+
+	for c.ll.Len() > size {
+		e := c.ll.Back()
+		entry, _ := e.Value.(*transportCacheEntry[K])
+		c.ll.Remove(e)
+		delete(c.items, entry.id)
+	}
+	return t
+}
+
+// drain removes all cached transports and returns them.
+func (c *transportCache[K]) drain() []*Transport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ll == nil {
+		return nil
+	}
+
+	transports := make([]*Transport, 0, c.ll.Len())
+	for e := c.ll.Front(); e != nil; e = e.Next() {
+		entry, _ := e.Value.(*transportCacheEntry[K])
+		transports = append(transports, entry.t)
+	}
+
+	c.ll.Init()
+	clear(c.items)
+	return transports
+}
+
+// installationTransport returns [Transport] for the installation specified via
+// [ContextWithInstallationID]. Transports are created on first use and are
+// re-used for subsequent requests. Up to 1024 most recently used transports
+// are cached. Returned transport shares JWT with t.
+func (t *Transport) installationTransport(ctx context.Context, id uint64) (*Transport, error) {
+	if it, ok := t.installs.load(id); ok {
+		return it, nil
+	}
+
+	// Concurrent requests for the same installation share the bootstrap,
+	// thus only one installation access token is minted.
+	return t.installFlights.do(ctx, id, func(ctx context.Context) (*Transport, error) {
+		if it, ok := t.installs.load(id); ok {
+			return it, nil
+		}
+
+		it := t.derive()
+		it.installID = id
+
+		// Single file permission only applies to the single file of the
+		// installation of t.
+		if t.singleFile != "" {
+			delete(it.scopes, "single_file")
+			if len(it.scopes) == 0 {
+				it.scopes = nil
+			}
+		}
+
+		// Verify installation belongs to the app, as installation id is not trusted.
+		// GitHub API only returns installations of the authenticated app.
+		ctx, cancel := it.bootstrapContext(ctx)
+		defer cancel()
+
+		err := it.bootstrapInstallation(ctx, it.internalClient(), newRetryBudget(it.bootstrapBudget, it.requestTimeout))
+		if err != nil {
+			return nil, fmt.Errorf("githubapp: installation id %d from context: %w", id, err)
+		}
+		return t.installs.loadOrStore(id, it), nil
+	})
+}
+
+// scopedTransport returns [Transport] for the same installation as t, but with
+// permissions specified via [WithRequestPermissions]. Transports are created on
+// first use and are re-used for subsequent requests with the same permissions.
+// Up to 1024 most recently used transports are cached.
+func (t *Transport) scopedTransport(permissions map[string]string) (*Transport, error) {
+	st := t.derive()
+	st.scopes = nil
+	err := WithPermissionsMap(permissions).apply(st)
+	if err != nil {
+		return nil, fmt.Errorf("githubapp: permissions from context: %w", err)
+	}
+
+	key := permissionsKey(st.scopes)
+	if v, ok := t.scoped.load(key); ok {
+		return v, nil
+	}
+
+	// Installation was already verified by t.
+	st.installID = t.installID
+	st.owner = t.owner
+	st.targetType = t.targetType
+	st.ownerID = t.ownerID
+	st.repos = t.repos
+	st.repoIDs = t.repoIDs
+	st.tokenURL = t.tokenURL
+	st.bot = t.bot
+	st.installPerms = t.installPerms
+	st.singleFile = t.singleFile
+
+	// If another request already created a transport for the permissions, use it.
+	return t.scoped.loadOrStore(key, st), nil
+}
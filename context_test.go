@@ -0,0 +1,365 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tprasadtp/go-githubapp/internal/api"
+	"github.com/tprasadtp/go-githubapp/internal/testdata/apitestdata"
+	"github.com/tprasadtp/go-githubapp/internal/testkeys"
+)
+
+func TestCtxInstallationID(t *testing.T) {
+	if v := ctxInstallationID(context.Background()); v != 0 {
+		t.Errorf("expected no installation id, got %d", v)
+	}
+
+	//nolint:staticcheck // test nil context.
+	if v := ctxInstallationID(ContextWithInstallationID(nil, 99)); v != 99 {
+		t.Errorf("expected installation id 99, got %d", v)
+	}
+}
+
+func TestTransportCache(t *testing.T) {
+	c := transportCache[uint64]{size: 2}
+	if v := c.drain(); len(v) != 0 {
+		t.Errorf("expected empty cache, got %d transports", len(v))
+	}
+
+	a, b, d := &Transport{installID: 1}, &Transport{installID: 2}, &Transport{installID: 3}
+	if v := c.loadOrStore(1, a); v != a {
+		t.Errorf("expected stored transport to be returned")
+	}
+	c.loadOrStore(2, b)
+
+	// Existing transport must be returned.
+	if v := c.loadOrStore(1, &Transport{installID: 1}); v != a {
+		t.Errorf("expected existing transport to be returned")
+	}
+
+	// Installation 2 is least recently used, and must be evicted.
+	c.loadOrStore(3, d)
+	if _, ok := c.load(2); ok {
+		t.Errorf("expected least recently used transport to be evicted")
+	}
+
+	for id, expect := range map[uint64]*Transport{1: a, 3: d} {
+		if v, ok := c.load(id); !ok || v != expect {
+			t.Errorf("expected transport for installation %d to be cached", id)
+		}
+	}
+
+	if v := c.drain(); len(v) != 2 {
+		t.Errorf("expected 2 transports, got %d", len(v))
+	}
+
+	if _, ok := c.load(1); ok {
+		t.Errorf("expected cache to be empty after drain")
+	}
+
+	// Scoped transports are keyed by permission set.
+	sc := transportCache[string]{size: 1}
+	sc.loadOrStore("issues:read", a)
+	sc.loadOrStore("contents:read", b)
+	if _, ok := sc.load("issues:read"); ok {
+		t.Errorf("expected least recently used transport to be evicted")
+	}
+
+	if v, ok := sc.load("contents:read"); !ok || v != b {
+		t.Errorf("expected transport for permissions to be cached")
+	}
+}
+
+func TestTransport_derive(t *testing.T) {
+	transport := &Transport{
+		appID:      apitestdata.AppID,
+		installID:  apitestdata.InstallationID,
+		singleFile: "README.md",
+		scopes:     map[string]string{"issues": "read", "single_file": "read"},
+	}
+
+	dt := transport.derive()
+	if dt.installID != 0 || dt.singleFile != "" {
+		t.Errorf("expected installation and single file not to be copied, got %d, %q",
+			dt.installID, dt.singleFile)
+	}
+
+	if dt.app != transport {
+		t.Errorf("expected derived transport to share JWT with transport")
+	}
+
+	dt.scopes["issues"] = "write"
+	if v := transport.scopes["issues"]; v != "read" {
+		t.Errorf("expected scopes to be cloned, got issues=%s", v)
+	}
+}
+
+func TestTransport_RoundTrip_ContextInstallationIDConcurrent(t *testing.T) {
+	m := apitestdata.Get(t)
+	var lookups, mints atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app":
+			_, _ = w.Write(m["get-app"])
+		case "/app/installations/1001":
+			lookups.Add(1)
+			// Give concurrent requests time to wait for the bootstrap.
+			time.Sleep(50 * time.Millisecond)
+			_, _ = w.Write([]byte(`{"id":1001,"account":{"login":"tenant-1001"},"permissions":{"issues":"write"}}`))
+		case "/app/installations/1001/access_tokens":
+			mints.Add(1)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"token":"ghs_1001","expires_at":"2099-01-01T00:00:00Z"}`))
+		default:
+			_, _ = w.Write([]byte(r.Header.Get(api.AuthzHeader)))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	transport, err := NewTransport(context.Background(), apitestdata.AppID, testkeys.RSA2048(),
+		WithEndpoint(server.URL),
+		WithoutBotMetadata(),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := ContextWithInstallationID(context.Background(), 1001)
+			r, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/repos", nil)
+			resp, err := transport.RoundTrip(r)
+			if err != nil {
+				t.Errorf("expected no error, got %s", err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	if v := lookups.Load(); v != 1 {
+		t.Errorf("expected installation to be verified once, got %d", v)
+	}
+
+	if v := mints.Load(); v != 1 {
+		t.Errorf("expected 1 installation token to be minted, got %d", v)
+	}
+}
+
+func TestCtxExistingAuthz(t *testing.T) {
+	if ctxExistingAuthz(context.Background()) {
+		t.Errorf("expected existing authorization to be not preserved by default")
+	}
+
+	//nolint:staticcheck // test nil context.
+	if !ctxExistingAuthz(WithExistingAuthorization(nil)) {
+		t.Errorf("expected existing authorization to be preserved")
+	}
+}
+
+func TestTransport_RoundTrip_ContextInstallationID(t *testing.T) {
+	m := apitestdata.Get(t)
+	var mints atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/app":
+			_, _ = w.Write(m["get-app"])
+		case r.URL.Path == "/app/installations/1001" || r.URL.Path == "/app/installations/1002":
+			id := strings.TrimPrefix(r.URL.Path, "/app/installations/")
+			_, _ = fmt.Fprintf(w, `{"id":%s,"account":{"login":"tenant-%s"},"permissions":{"issues":"write"}}`, id, id)
+		case strings.HasSuffix(r.URL.Path, "/access_tokens"):
+			mints.Add(1)
+			id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/app/installations/"), "/access_tokens")
+			w.WriteHeader(http.StatusCreated)
+			_, _ = fmt.Fprintf(w, `{"token":"ghs_%s","expires_at":"2099-01-01T00:00:00Z"}`, id)
+		case r.URL.Path == "/repos":
+			_, _ = w.Write([]byte(r.Header.Get(api.AuthzHeader)))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write(m["error-not-found"])
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	transport, err := NewTransport(context.Background(), apitestdata.AppID, testkeys.RSA2048(),
+		WithEndpoint(server.URL),
+		WithoutBotMetadata(),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	authz := func(ctx context.Context) (string, error) {
+		r, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/repos", nil)
+		resp, err := transport.RoundTrip(r)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+
+		data, err := io.ReadAll(resp.Body)
+		return string(data), err
+	}
+
+	for i := 0; i < 2; i++ {
+		for _, id := range []uint64{1001, 1002} {
+			v, err := authz(ContextWithInstallationID(context.Background(), id))
+			if err != nil {
+				t.Fatalf("expected no error, got %s", err)
+			}
+
+			if expect := fmt.Sprintf("Bearer ghs_%d", id); v != expect {
+				t.Errorf("expected authorization=%q, got=%q", expect, v)
+			}
+		}
+	}
+
+	// Tokens must be cached per installation.
+	if v := mints.Load(); v != 2 {
+		t.Errorf("expected 2 installation tokens to be minted, got %d", v)
+	}
+
+	// Requests without override use JWT.
+	v, err := authz(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+	if strings.HasPrefix(v, "Bearer ghs_") {
+		t.Errorf("expected JWT to be used without override, got %q", v)
+	}
+
+	// Installations not belonging to the app are rejected.
+	_, err = authz(ContextWithInstallationID(context.Background(), 1003))
+	if err == nil {
+		t.Errorf("expected an error for unknown installation, got nil")
+	}
+}
+
+func TestTransport_RoundTrip_RequestPermissions(t *testing.T) {
+	var mints atomic.Int64
+	u, _ := url.Parse("https://api.go-githubapp.test/")
+	transport := &Transport{
+		appID:     99,
+		installID: 99,
+		owner:     "gh-integration-tests",
+		repos:     []string{"foo"},
+		scopes:    map[string]string{"contents": "write", "issues": "write"},
+		ua:        api.UAHeaderValue,
+		baseURL:   u,
+		minter:    &jwtRS256{internal: testkeys.RSA2048()},
+		next: api.RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+			resp := httptest.NewRecorder()
+			if strings.HasSuffix(r.URL.Path, "/access_tokens") {
+				mints.Add(1)
+				req := api.InstallationTokenRequest{}
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Errorf("failed to decode token request: %s", err)
+				}
+				if !slices.Equal(req.Repositories, []string{"foo"}) {
+					t.Errorf("expected scoped token to be limited to repositories, got %v", req.Repositories)
+				}
+				resp.WriteHeader(http.StatusCreated)
+				_, _ = fmt.Fprintf(resp, `{"token":"ghs_%s","expires_at":"2099-01-01T00:00:00Z"}`,
+					permissionsKey(req.Permissions))
+				return resp.Result(), nil
+			}
+			_, _ = resp.WriteString(r.Header.Get(api.AuthzHeader))
+			return resp.Result(), nil
+		}),
+	}
+
+	authz := func(ctx context.Context) (string, error) {
+		r, _ := http.NewRequestWithContext(ctx, http.MethodGet, u.JoinPath("repos").String(), nil)
+		resp, err := transport.RoundTrip(r)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+
+		data, err := io.ReadAll(resp.Body)
+		return string(data), err
+	}
+
+	tt := []struct {
+		name        string
+		permissions map[string]string
+		expect      string
+		err         bool
+	}{
+		{
+			name:   "default",
+			expect: "Bearer ghs_contents:write,issues:write",
+		},
+		{
+			name:        "scoped",
+			permissions: map[string]string{"contents": "read"},
+			expect:      "Bearer ghs_contents:read",
+		},
+		{
+			name:        "scoped-cached",
+			permissions: map[string]string{"contents": "read"},
+			expect:      "Bearer ghs_contents:read",
+		},
+		{
+			name:        "scoped-normalized",
+			permissions: map[string]string{"Issues": "WRITE", "contents": "read"},
+			expect:      "Bearer ghs_contents:read,issues:write",
+		},
+		{
+			name:        "invalid",
+			permissions: map[string]string{"contents": "yes"},
+			err:         true,
+		},
+		{
+			name:   "default-cached",
+			expect: "Bearer ghs_contents:write,issues:write",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.permissions != nil {
+				ctx = WithRequestPermissions(ctx, tc.permissions)
+			}
+
+			v, err := authz(ctx)
+			if tc.err {
+				if err == nil {
+					t.Errorf("expected an error, got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error, got %s", err)
+			}
+
+			if v != tc.expect {
+				t.Errorf("expected authorization=%q, got=%q", tc.expect, v)
+			}
+		})
+	}
+
+	// Tokens must be cached per permission set.
+	if v := mints.Load(); v != 3 {
+		t.Errorf("expected 3 installation tokens to be minted, got %d", v)
+	}
+}
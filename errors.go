@@ -3,8 +3,50 @@
 
 package githubapp
 
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/tprasadtp/go-githubapp/internal/api"
+)
+
+const (
+	// ErrAppSuspended is returned when the GitHub app is suspended.
+	ErrAppSuspended = Error("githubapp: app is suspended")
+
+	// ErrInstallationSuspended is returned when the installation is suspended,
+	// but the app itself is not.
+	ErrInstallationSuspended = Error("githubapp: installation is suspended")
+
+	// ErrInvalidAppCredentials is returned when GitHub does not recognize the
+	// app id or the private key, typically because the key was revoked or
+	// regenerated and needs to be rotated. Suspended apps return
+	// [ErrAppSuspended] instead.
+	ErrInvalidAppCredentials = Error("githubapp: invalid app id or credentials")
+
+	// ErrRepositoryNotAccessible is returned when repositories configured via
+	// [WithRepositories] or [WithRepositoryIDs] are not accessible to the
+	// installation. Use [errors.As] with [*RepositoryAccessError] to get
+	// the repositories which are not accessible.
+	ErrRepositoryNotAccessible = Error("githubapp: repositories not accessible to the installation")
+
+	// ErrInstallationTokenExpired is returned when installation access token
+	// specified via [WithStaticInstallationToken] has expired.
+	ErrInstallationTokenExpired = Error("githubapp: installation token has expired")
+
+	// ErrTransportClosed is returned by [Transport] after [Transport.Close]
+	// is called.
+	ErrTransportClosed = Error("githubapp: transport is closed")
+)
+
 var (
 	_ error = Error("")
+	_ error = (*APIError)(nil)
+	_ error = (*RepositoryAccessError)(nil)
 )
 
 // Error is immutable error representation.
@@ -17,3 +59,91 @@ type Error string
 func (e Error) Error() string {
 	return string(e)
 }
+
+// APIError is returned when GitHub API responds with an unexpected status code.
+// Use [errors.As] to extract it from errors returned by this package.
+type APIError struct {
+	// StatusCode is HTTP status code of the response.
+	StatusCode int
+
+	// Message is error message returned by the API, if any.
+	Message string
+
+	// DocumentationURL is documentation url returned by the API, if any.
+	DocumentationURL string
+}
+
+// Implements Error() interface.
+func (e *APIError) Error() string {
+	status := fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Message == "" {
+		return status
+	}
+	return fmt.Sprintf("%s(%s)", e.Message, status)
+}
+
+// newAPIError builds [APIError] from the response and its body.
+// GitHub API error response JSON is inconsistent, thus if body
+// cannot be decoded, only status code is populated.
+func newAPIError(resp *http.Response, data []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+	}
+
+	errResp := api.ErrorResponse{}
+	if err := json.Unmarshal(data, &errResp); err == nil {
+		apiErr.Message = errResp.Message
+		apiErr.DocumentationURL = errResp.DocumentationURL
+	}
+	return apiErr
+}
+
+// suspendedError returns [ErrAppSuspended] or [ErrInstallationSuspended] wrapping
+// the API error, if it indicates that the app or the installation is suspended.
+// GitHub API responds with 403 and a message mentioning suspension in both cases.
+// Otherwise, this returns nil.
+func (e *APIError) suspendedError() error {
+	msg := strings.ToLower(e.Message)
+	if e.StatusCode != http.StatusForbidden || !strings.Contains(msg, "suspended") {
+		return nil
+	}
+
+	if strings.Contains(msg, "installation") {
+		return fmt.Errorf("%w: %w", ErrInstallationSuspended, e)
+	}
+	return fmt.Errorf("%w: %w", ErrAppSuspended, e)
+}
+
+// RepositoryAccessError is returned when repositories configured via [WithRepositories]
+// or [WithRepositoryIDs] are not accessible to the installation, for example, because
+// they do not exist or installation does not have access to them. This matches
+// [ErrRepositoryNotAccessible] with [errors.Is].
+type RepositoryAccessError struct {
+	// Repositories which are not accessible to the installation.
+	Repositories []string
+
+	// RepositoryIDs which are not accessible to the installation.
+	RepositoryIDs []int64
+
+	// Err is error returned by the API when creating installation access token.
+	Err error
+}
+
+// Implements Error() interface.
+func (e *RepositoryAccessError) Error() string {
+	items := slices.Clone(e.Repositories)
+	for _, id := range e.RepositoryIDs {
+		items = append(items, strconv.FormatInt(id, 10))
+	}
+	return fmt.Sprintf("%s: %s", ErrRepositoryNotAccessible, strings.Join(items, ", "))
+}
+
+// Is returns true if target is [ErrRepositoryNotAccessible].
+func (e *RepositoryAccessError) Is(target error) bool {
+	return target == ErrRepositoryNotAccessible
+}
+
+// Unwrap returns the underlying API error.
+func (e *RepositoryAccessError) Unwrap() error {
+	return e.Err
+}
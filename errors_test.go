@@ -0,0 +1,394 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tprasadtp/go-githubapp/internal/api"
+	"github.com/tprasadtp/go-githubapp/internal/testdata/apitestdata"
+	"github.com/tprasadtp/go-githubapp/internal/testkeys"
+)
+
+func TestAPIError(t *testing.T) {
+	tt := []struct {
+		name   string
+		err    *APIError
+		expect string
+	}{
+		{
+			name:   "status-only",
+			err:    &APIError{StatusCode: http.StatusNotFound},
+			expect: "404 Not Found",
+		},
+		{
+			name:   "with-message",
+			err:    &APIError{StatusCode: http.StatusNotFound, Message: "Not Found"},
+			expect: "Not Found(404 Not Found)",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.err.Error() != tc.expect {
+				t.Errorf("expected=%q, got=%q", tc.expect, tc.err.Error())
+			}
+		})
+	}
+}
+
+func TestTransport_InstallationToken_APIError(t *testing.T) {
+	u, _ := url.Parse("https://api.go-githubapp.test/")
+	transport := &Transport{
+		appID:     99,
+		installID: 99,
+		baseURL:   u,
+		minter:    &jwtRS256{internal: testkeys.RSA2048()},
+		next: api.RoundTripFunc(func(_ *http.Request) (*http.Response, error) {
+			resp := httptest.NewRecorder()
+			resp.WriteHeader(http.StatusUnprocessableEntity)
+			_, _ = resp.WriteString(`{"message":"There is at least one repository that does not exist or is not accessible to the parent installation.","documentation_url":"https://docs.github.com/rest"}`)
+			return resp.Result(), nil
+		}),
+	}
+
+	_, err := transport.InstallationToken(context.Background())
+	if err == nil {
+		t.Fatalf("expected an error, got nil")
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected error to be *APIError, got %T", err)
+	}
+
+	if apiErr.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("expected StatusCode=%d, got=%d", http.StatusUnprocessableEntity, apiErr.StatusCode)
+	}
+
+	if apiErr.DocumentationURL != "https://docs.github.com/rest" {
+		t.Errorf("expected DocumentationURL to be populated, got %q", apiErr.DocumentationURL)
+	}
+
+	if !strings.Contains(err.Error(), "422") {
+		t.Errorf("error string should contain \"422\" error code: %s", err)
+	}
+}
+
+func TestAPIError_suspendedError(t *testing.T) {
+	tt := []struct {
+		name   string
+		err    *APIError
+		expect error
+	}{
+		{
+			name: "not-suspended",
+			err:  &APIError{StatusCode: http.StatusForbidden, Message: "Resource not accessible by integration"},
+		},
+		{
+			name: "not-forbidden",
+			err:  &APIError{StatusCode: http.StatusNotFound, Message: "suspended"},
+		},
+		{
+			name:   "app-suspended",
+			err:    &APIError{StatusCode: http.StatusForbidden, Message: "This GitHub App is suspended."},
+			expect: ErrAppSuspended,
+		},
+		{
+			name:   "installation-suspended",
+			err:    &APIError{StatusCode: http.StatusForbidden, Message: "This installation has been suspended"},
+			expect: ErrInstallationSuspended,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.err.suspendedError()
+			if tc.expect == nil {
+				if err != nil {
+					t.Errorf("expected nil, got %s", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, tc.expect) {
+				t.Errorf("expected %s, got %v", tc.expect, err)
+			}
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Errorf("expected error to wrap *APIError, got %T", err)
+			}
+		})
+	}
+}
+
+func TestNewTransport_Suspended(t *testing.T) {
+	m := apitestdata.Get(t)
+
+	// Installation with suspension scheduled in the future.
+	suspendedAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	scheduled := map[string]any{}
+	if err := json.Unmarshal(m["get-installation-by-id"], &scheduled); err != nil {
+		t.Fatalf("failed to unmarshal test data: %s", err)
+	}
+	scheduled["suspended_at"] = suspendedAt.Format(time.RFC3339)
+	scheduledData, _ := json.Marshal(scheduled)
+
+	tt := []struct {
+		name     string
+		handler  http.HandlerFunc
+		expect   error
+		contains string
+	}{
+		{
+			name:   "app-suspended",
+			expect: ErrAppSuspended,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/app" {
+					t.Errorf("Unknown/Invalid Request => %s", r.URL)
+				}
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`{"message":"This GitHub App is suspended.","documentation_url":"https://docs.github.com/rest"}`))
+			},
+		},
+		{
+			name:   "installation-suspended",
+			expect: ErrInstallationSuspended,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/app":
+					_, _ = w.Write(m["get-app"])
+				case fmt.Sprintf("/app/installations/%d", apitestdata.InstallationID):
+					_, _ = w.Write(m["get-installation-disabled"])
+				default:
+					t.Errorf("Unknown/Invalid Request => %s", r.URL)
+				}
+			},
+		},
+		{
+			name:     "installation-suspension-scheduled",
+			expect:   ErrInstallationSuspended,
+			contains: "scheduled to be suspended at " + suspendedAt.Format(time.RFC3339),
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/app":
+					_, _ = w.Write(m["get-app"])
+				case fmt.Sprintf("/app/installations/%d", apitestdata.InstallationID):
+					_, _ = w.Write(scheduledData)
+				default:
+					t.Errorf("Unknown/Invalid Request => %s", r.URL)
+				}
+			},
+		},
+		{
+			name:   "installation-suspended-on-token-creation",
+			expect: ErrInstallationSuspended,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/app":
+					_, _ = w.Write(m["get-app"])
+				case fmt.Sprintf("/app/installations/%d", apitestdata.InstallationID):
+					_, _ = w.Write(m["get-installation-by-id"])
+				case fmt.Sprintf("/app/installations/%d/access_tokens", apitestdata.InstallationID):
+					w.WriteHeader(http.StatusForbidden)
+					_, _ = w.Write([]byte(`{"message":"This installation has been suspended","documentation_url":"https://docs.github.com/rest"}`))
+				default:
+					t.Errorf("Unknown/Invalid Request => %s", r.URL)
+				}
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(tc.handler)
+			t.Cleanup(server.Close)
+
+			_, err := NewTransport(context.Background(), apitestdata.AppID, testkeys.RSA2048(),
+				WithEndpoint(server.URL),
+				WithInstallationID(apitestdata.InstallationID),
+			)
+			if !errors.Is(err, tc.expect) {
+				t.Errorf("expected %s, got %v", tc.expect, err)
+			}
+
+			if tc.contains != "" && (err == nil || !strings.Contains(err.Error(), tc.contains)) {
+				t.Errorf("expected error to contain %q, got %v", tc.contains, err)
+			}
+
+			other := ErrAppSuspended
+			if tc.expect == ErrAppSuspended {
+				other = ErrInstallationSuspended
+			}
+			if errors.Is(err, other) {
+				t.Errorf("error must not be %s: %s", other, err)
+			}
+		})
+	}
+}
+
+func TestNewTransport_InvalidAppCredentials(t *testing.T) {
+	m := apitestdata.Get(t)
+	tt := []struct {
+		name    string
+		status  int
+		body    []byte
+		invalid bool
+	}{
+		{
+			name:    "unauthorized",
+			status:  http.StatusUnauthorized,
+			body:    m["error-invalid-jwt"],
+			invalid: true,
+		},
+		{
+			name:    "forbidden",
+			status:  http.StatusForbidden,
+			body:    m["error-bad-credentials"],
+			invalid: true,
+		},
+		{
+			name:   "suspended",
+			status: http.StatusForbidden,
+			body:   []byte(`{"message":"This GitHub App is suspended."}`),
+		},
+		{
+			name:   "server-error",
+			status: http.StatusInternalServerError,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/app" {
+					t.Errorf("Unknown/Invalid Request => %s", r.URL)
+				}
+				w.WriteHeader(tc.status)
+				_, _ = w.Write(tc.body)
+			}))
+			t.Cleanup(server.Close)
+
+			_, err := NewTransport(context.Background(), apitestdata.AppID, testkeys.RSA2048(),
+				WithEndpoint(server.URL),
+			)
+			if err == nil {
+				t.Fatalf("expected an error, got nil")
+			}
+
+			if errors.Is(err, ErrInvalidAppCredentials) != tc.invalid {
+				t.Errorf("expected errors.Is(err, ErrInvalidAppCredentials)=%t, got %s", tc.invalid, err)
+			}
+
+			if tc.invalid && !strings.Contains(err.Error(), fmt.Sprintf("app id %d", apitestdata.AppID)) {
+				t.Errorf("expected error to include app id, got %s", err)
+			}
+		})
+	}
+}
+
+func TestNewTransport_RepositoryNotAccessible(t *testing.T) {
+	m := apitestdata.Get(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app":
+			_, _ = w.Write(m["get-app"])
+		case fmt.Sprintf("/app/installations/%d", apitestdata.InstallationID):
+			_, _ = w.Write(m["get-installation-by-id"])
+		case fmt.Sprintf("/app/installations/%d/access_tokens", apitestdata.InstallationID):
+			req := api.InstallationTokenRequest{}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Errorf("failed to decode token request: %s", err)
+			}
+
+			// Tokens scoped to repositories are rejected.
+			if len(req.Repositories) > 0 || len(req.RepositoryIDs) > 0 {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				_, _ = w.Write(m["error-installation-token-no-access"])
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"token":"ghs_token","expires_at":"2099-01-01T00:00:00Z"}`))
+		case "/installation/repositories":
+			_, _ = w.Write(m["get-installation-repositories"])
+		case "/installation/token":
+			// Tokens used to list repositories are revoked.
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("Unknown/Invalid Request => %s", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	tt := []struct {
+		name  string
+		opts  []Option
+		repos []string
+		ids   []int64
+	}{
+		{
+			name:  "repositories",
+			opts:  []Option{WithRepositories(apitestdata.InstallationRepository, "typo-repo")},
+			repos: []string{"typo-repo"},
+		},
+		{
+			name: "repository-ids",
+			opts: []Option{WithRepositoryIDs(699035785, 1)},
+			ids:  []int64{1},
+		},
+		{
+			// Repositories are accessible, but token creation failed.
+			name: "accessible",
+			opts: []Option{WithRepositories(apitestdata.InstallationRepository)},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := append([]Option{
+				WithEndpoint(server.URL),
+				WithInstallationID(apitestdata.InstallationID),
+				WithoutBotMetadata(),
+			}, tc.opts...)
+
+			_, err := NewTransport(context.Background(), apitestdata.AppID, testkeys.RSA2048(), opts...)
+			if err == nil {
+				t.Fatalf("expected an error, got nil")
+			}
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnprocessableEntity {
+				t.Errorf("expected error to wrap API error, got %s", err)
+			}
+
+			var repoErr *RepositoryAccessError
+			if len(tc.repos) == 0 && len(tc.ids) == 0 {
+				if errors.Is(err, ErrRepositoryNotAccessible) || errors.As(err, &repoErr) {
+					t.Errorf("expected error not to be %s, got %s", ErrRepositoryNotAccessible, err)
+				}
+				return
+			}
+
+			if !errors.Is(err, ErrRepositoryNotAccessible) {
+				t.Errorf("expected %s, got %s", ErrRepositoryNotAccessible, err)
+			}
+
+			if !errors.As(err, &repoErr) {
+				t.Fatalf("expected *RepositoryAccessError, got %T", err)
+			}
+
+			if !slices.Equal(repoErr.Repositories, tc.repos) || !slices.Equal(repoErr.RepositoryIDs, tc.ids) {
+				t.Errorf("expected inaccessible repositories=%v, ids=%v, got repositories=%v, ids=%v",
+					tc.repos, tc.ids, repoErr.Repositories, repoErr.RepositoryIDs)
+			}
+		})
+	}
+}
@@ -6,8 +6,7 @@ package main
 
 import (
 	"context"
-	"crypto/x509"
-	"encoding/pem"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -47,25 +46,16 @@ func run() error {
 		if flag.NArg() < 1 {
 			return fmt.Errorf("no tokens provided")
 		}
-		ec := 0
+		tokens := make([]githubapp.InstallationToken, 0, flag.NArg())
 		for _, item := range flag.Args() {
-			token := githubapp.InstallationToken{Token: item}
-			err := token.Revoke(ctx)
-			if err != nil {
-				slog.Error("Failed to revoke token",
-					"token", fmt.Sprintf("%s****", token.Token[0:8]),
-					"err", err,
-				)
-				ec++
-			} else {
-				slog.Info("Token successfully revoked",
-					"token", fmt.Sprintf("%s****", token.Token[0:8]),
-				)
-			}
+			tokens = append(tokens, githubapp.InstallationToken{Token: item})
 		}
-		if ec != 0 {
-			return fmt.Errorf("failed to revoke %d tokens", ec)
+
+		err := githubapp.RevokeTokens(ctx, tokens)
+		if err != nil {
+			return err
 		}
+		slog.Info("Tokens successfully revoked", "count", len(tokens))
 		return nil
 	}
 
@@ -96,14 +86,13 @@ func run() error {
 		return fmt.Errorf("failed to read private key: %w", err)
 	}
 
-	block, _ := pem.Decode(slurp)
-	if block == nil {
-		return fmt.Errorf("invalid private key: %w", err)
-	}
-
-	// Try to parse key as a private key.
-	signer, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	// Encrypted private keys are decrypted with passphrase from the environment.
+	passphrase := os.Getenv("GITHUB_APP_PRIVATE_KEY_PASSPHRASE")
+	signer, err := githubapp.NewSignerFromEncryptedPEM(slurp, []byte(passphrase))
 	if err != nil {
+		if errors.Is(err, githubapp.ErrPrivateKeyPassphrase) {
+			return fmt.Errorf("%w: set GITHUB_APP_PRIVATE_KEY_PASSPHRASE", err)
+		}
 		return fmt.Errorf("invalid private key: %w", err)
 	}
 
@@ -136,7 +125,8 @@ func run() error {
 
 	// One of repos/owner or installation id is specified.
 	// Get installation access token,
-	var opts []githubapp.Option
+	// Bot user metadata is printed along with the token.
+	opts := []githubapp.Option{githubapp.WithEagerBotMetadata()}
 	if installation != 0 {
 		opts = append(opts, githubapp.WithInstallationID(installation))
 	}
@@ -169,13 +159,15 @@ func run() error {
 		fmt.Printf("Repositories      : %v\n", token.Repositories)
 		fmt.Printf("Permissions       : %v\n", token.Permissions)
 		fmt.Printf("BotUsername       : %s\n", token.BotUsername)
+		fmt.Printf("BotUserID         : %d\n", token.BotUserID)
+		fmt.Printf("BotCommitterName  : %s\n", token.BotCommitterName)
 		fmt.Printf("BotCommitterEmail : %s\n", token.BotCommitterEmail)
 	}
 	return nil
 }
 
 func main() {
-	flag.StringVar(&privFile, "private-key", "", "Path to PKCS1 private key file (required)")
+	flag.StringVar(&privFile, "private-key", "", "Path to PEM encoded private key file (required)")
 	flag.Uint64Var(&app, "app-id", 0, "GitHub app ID (required)")
 	flag.Uint64Var(&installation, "installation-id", 0, "Installation ID")
 	flag.StringVar(&repos, "repos", "", "Comma separated list of repositories")
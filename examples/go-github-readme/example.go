@@ -14,7 +14,6 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"net/http"
 	"os"
 	"os/signal"
 	"strings"
@@ -88,7 +87,7 @@ func main() {
 	}
 
 	// Build a new client
-	client := github.NewClient(&http.Client{Transport: transport})
+	client := github.NewClient(transport.Client())
 
 	// Use client
 	readme, _, err := client.Repositories.GetReadme(ctx, username, repository, nil)
@@ -0,0 +1,114 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// errFlightPanic is returned to waiters if the call panicked.
+const errFlightPanic = Error("githubapp: concurrent call panicked")
+
+// flight suppresses duplicate concurrent calls, similar to
+// golang.org/x/sync/singleflight, but for a single key. While a call is in
+// progress, other callers wait for it and share its result. Unlike singleflight,
+// waiters stop waiting when their context is done.
+type flight[T any] struct {
+	mu   sync.Mutex
+	call *flightCall[T]
+}
+
+// flightCall is an in-progress or completed call.
+type flightCall[T any] struct {
+	done chan struct{}
+	val  T
+	err  error
+}
+
+// do executes fn, unless a call is already in progress, in which case it waits
+// for the call to complete and returns its result. fn is executed with the
+// context of the caller which executes it. If that context is done, waiters
+// retry the call with their own context.
+func (f *flight[T]) do(ctx context.Context, fn func(context.Context) (T, error)) (T, error) {
+	for {
+		f.mu.Lock()
+		c := f.call
+		if c == nil {
+			break
+		}
+		f.mu.Unlock()
+
+		select {
+		case <-c.done:
+			// If the call failed only because context of its caller was done,
+			// retry with the context of this caller.
+			if ctx.Err() == nil && (errors.Is(c.err, context.Canceled) || errors.Is(c.err, context.DeadlineExceeded)) {
+				continue
+			}
+			return c.val, c.err
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		}
+	}
+
+	c := &flightCall[T]{done: make(chan struct{})}
+	f.call = c
+	f.mu.Unlock()
+
+	// Panics in fn must not leave waiters blocked forever.
+	defer func() {
+		f.mu.Lock()
+		f.call = nil
+		f.mu.Unlock()
+		close(c.done)
+	}()
+
+	c.err = errFlightPanic
+	c.val, c.err = fn(ctx)
+	return c.val, c.err
+}
+
+// flightGroup suppresses duplicate concurrent calls with the same key, like
+// installation id, using a [flight] per key. Flights are discarded once no
+// callers are waiting for them. Zero value is ready to use.
+type flightGroup[T any] struct {
+	mu      sync.Mutex
+	flights map[uint64]*flightGroupEntry[T]
+}
+
+// flightGroupEntry is a [flight] and number of callers using it.
+type flightGroupEntry[T any] struct {
+	flight flight[T]
+	refs   int
+}
+
+// do executes fn, unless a call with the same key is already in progress.
+// See [flight.do] for more info.
+func (g *flightGroup[T]) do(ctx context.Context, key uint64, fn func(context.Context) (T, error)) (T, error) {
+	g.mu.Lock()
+	if g.flights == nil {
+		g.flights = make(map[uint64]*flightGroupEntry[T])
+	}
+	e, ok := g.flights[key]
+	if !ok {
+		e = &flightGroupEntry[T]{}
+		g.flights[key] = e
+	}
+	e.refs++
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		e.refs--
+		if e.refs == 0 {
+			delete(g.flights, key)
+		}
+		g.mu.Unlock()
+	}()
+
+	return e.flight.do(ctx, fn)
+}
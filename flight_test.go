@@ -0,0 +1,191 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFlight(t *testing.T) {
+	t.Run("shared", func(t *testing.T) {
+		var f flight[int]
+		var calls atomic.Int64
+		release := make(chan struct{})
+
+		var wg sync.WaitGroup
+		results := make([]int, 16)
+		for i := range results {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				v, err := f.do(context.Background(), func(context.Context) (int, error) {
+					calls.Add(1)
+					<-release
+					return 42, nil
+				})
+				if err != nil {
+					t.Errorf("expected no error, got %s", err)
+				}
+				results[i] = v
+			}(i)
+		}
+
+		// Wait for the first call to start, and give others time to wait for it.
+		for calls.Load() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		if v := calls.Load(); v != 1 {
+			t.Errorf("expected fn to be called once, got %d", v)
+		}
+
+		for i, v := range results {
+			if v != 42 {
+				t.Errorf("caller %d: expected 42, got %d", i, v)
+			}
+		}
+	})
+
+	t.Run("sequential", func(t *testing.T) {
+		var f flight[int]
+		for i := 1; i <= 2; i++ {
+			v, _ := f.do(context.Background(), func(context.Context) (int, error) {
+				return i, nil
+			})
+			if v != i {
+				t.Errorf("expected completed calls not to be shared, got %d", v)
+			}
+		}
+	})
+
+	t.Run("waiter-context-done", func(t *testing.T) {
+		var f flight[int]
+		started := make(chan struct{})
+		release := make(chan struct{})
+		defer close(release)
+
+		go func() {
+			_, _ = f.do(context.Background(), func(context.Context) (int, error) {
+				close(started)
+				<-release
+				return 0, nil
+			})
+		}()
+		<-started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := f.do(ctx, func(context.Context) (int, error) {
+			t.Errorf("fn must not be called while a call is in progress")
+			return 0, nil
+		})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected %s, got %v", context.DeadlineExceeded, err)
+		}
+	})
+
+	t.Run("caller-context-canceled", func(t *testing.T) {
+		var f flight[int]
+		started := make(chan struct{})
+		ctx, cancel := context.WithCancel(context.Background())
+
+		go func() {
+			_, _ = f.do(ctx, func(ctx context.Context) (int, error) {
+				close(started)
+				<-ctx.Done()
+				return 0, ctx.Err()
+			})
+		}()
+		<-started
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			cancel()
+		}()
+
+		// Waiter must retry, as it's context is not done.
+		v, err := f.do(context.Background(), func(context.Context) (int, error) {
+			return 42, nil
+		})
+		if err != nil || v != 42 {
+			t.Errorf("expected waiter to retry, got %d, %v", v, err)
+		}
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		var f flight[int]
+		started := make(chan struct{})
+		release := make(chan struct{})
+
+		go func() {
+			defer func() { _ = recover() }()
+			_, _ = f.do(context.Background(), func(context.Context) (int, error) {
+				close(started)
+				<-release
+				panic("boom")
+			})
+		}()
+		<-started
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			close(release)
+		}()
+
+		_, err := f.do(context.Background(), func(context.Context) (int, error) {
+			return 42, nil
+		})
+		if !errors.Is(err, errFlightPanic) {
+			t.Errorf("expected %s, got %v", errFlightPanic, err)
+		}
+	})
+}
+
+func TestFlightGroup(t *testing.T) {
+	var g flightGroup[uint64]
+	var calls atomic.Int64
+	release := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(key uint64) {
+			defer wg.Done()
+			v, err := g.do(context.Background(), key, func(context.Context) (uint64, error) {
+				calls.Add(1)
+				<-release
+				return key, nil
+			})
+			if err != nil || v != key {
+				t.Errorf("expected %d, got %d, %v", key, v, err)
+			}
+		}(uint64(i % 2))
+	}
+
+	// Wait for calls for both keys to start, and give others time to wait for them.
+	for calls.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if v := calls.Load(); v != 2 {
+		t.Errorf("expected fn to be called once per key, got %d", v)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.flights) != 0 {
+		t.Errorf("expected completed flights to be discarded, got %d", len(g.flights))
+	}
+}
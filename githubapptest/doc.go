@@ -0,0 +1,17 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+// Package githubapptest provides a mock GitHub API server, which can be used
+// to test code using [github.com/tprasadtp/go-githubapp] without
+// access to GitHub.
+//
+//	server := githubapptest.NewServer()
+//	defer server.Close()
+//
+//	transport, err := githubapp.NewTransport(ctx, githubapptest.AppID, signer,
+//		githubapp.WithEndpoint(server.URL),
+//		githubapp.WithInstallationID(githubapptest.InstallationID),
+//	)
+//
+// Server does not verify JWT signatures, thus any RSA private key can be used.
+package githubapptest
@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapptest
+
+import (
+	"maps"
+	"time"
+)
+
+// Default metadata of the app and its installation served by [NewServer].
+const (
+	AppID           = 145695471
+	AppSlug         = "gh-integration-tests-app"
+	AppOwner        = "gh-integration-tests"
+	InstallationID  = 42101303
+	Owner           = "gh-integration-tests"
+	OwnerID         = 145695471
+	OwnerType       = "Organization"
+	BotUserID       = 145777326
+	Repository      = "go-githubapp-repo-one"
+	DefaultTokenTTL = time.Hour
+)
+
+// Option to configure the mock server.
+type Option func(*server)
+
+// WithAppID configures app id and app slug of the app.
+func WithAppID(id uint64, slug string) Option {
+	return func(s *server) {
+		s.appID = id
+		s.appSlug = slug
+	}
+}
+
+// WithInstallationID configures installation id of the app's installation.
+func WithInstallationID(id uint64) Option {
+	return func(s *server) {
+		s.installID = id
+	}
+}
+
+// WithAccount configures the account app is installed on. Account type
+// is either "Organization" or "User".
+func WithAccount(login string, id uint64, accountType string) Option {
+	return func(s *server) {
+		s.owner = login
+		s.ownerID = id
+		s.ownerType = accountType
+	}
+}
+
+// WithRepositories configures repositories accessible to the installation.
+// Repository ids are assigned in the order specified. If not specified,
+// installation can access a single repository, [Repository].
+func WithRepositories(repos ...string) Option {
+	return func(s *server) {
+		s.repos = append([]string(nil), repos...)
+	}
+}
+
+// WithPermissions configures permissions of the app and its installation.
+// If not specified, "contents", "issues" and "metadata" are granted
+// with "read" access.
+func WithPermissions(permissions map[string]string) Option {
+	return func(s *server) {
+		s.permissions = maps.Clone(permissions)
+	}
+}
+
+// WithBotUserID configures user id of the app's bot user.
+func WithBotUserID(id uint64) Option {
+	return func(s *server) {
+		s.botUserID = id
+	}
+}
+
+// WithTokenTTL configures lifetime of installation access tokens,
+// which defaults to [DefaultTokenTTL].
+func WithTokenTTL(ttl time.Duration) Option {
+	return func(s *server) {
+		s.tokenTTL = ttl
+	}
+}
@@ -0,0 +1,382 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapptest
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tprasadtp/go-githubapp/internal/api"
+)
+
+// repositoryIDOffset is added to index of the repository to build repository ids.
+const repositoryIDOffset = 700000000
+
+// server is a mock GitHub API server for a single app and its installation.
+type server struct {
+	appID       uint64
+	appSlug     string
+	installID   uint64
+	owner       string
+	ownerID     uint64
+	ownerType   string
+	repos       []string
+	permissions map[string]string
+	botUserID   uint64
+	tokenTTL    time.Duration
+
+	mu     sync.Mutex
+	tokens map[string]time.Time // installation access tokens and their expiry
+}
+
+// NewServer returns a started [httptest.Server] which mocks the GitHub REST API
+// endpoints used by [github.com/tprasadtp/go-githubapp]. Server responds to
+//
+//   - GET /app
+//   - GET /app/installations/{installation_id}
+//   - GET /users/{owner}/installation
+//   - GET /orgs/{owner}/installation
+//   - GET /repos/{owner}/{repo}/installation
+//   - POST /app/installations/{installation_id}/access_tokens
+//   - GET /users/{app_slug}[bot]
+//   - GET /installation/repositories
+//   - DELETE /installation/token
+//
+// App endpoints require a JWT and installation endpoints require an installation
+// access token minted by the server. JWT signatures are not verified. Installation
+// access tokens can be scoped to repositories and permissions accessible to the
+// installation. Any other requests return 404.
+//
+// Caller MUST close the server when done.
+func NewServer(opts ...Option) *httptest.Server {
+	s := &server{
+		appID:     AppID,
+		appSlug:   AppSlug,
+		installID: InstallationID,
+		owner:     Owner,
+		ownerID:   OwnerID,
+		ownerType: OwnerType,
+		repos:     []string{Repository},
+		permissions: map[string]string{
+			"contents": api.PermissionLevelRead,
+			"issues":   api.PermissionLevelRead,
+			"metadata": api.PermissionLevelRead,
+		},
+		botUserID: BotUserID,
+		tokenTTL:  DefaultTokenTTL,
+		tokens:    make(map[string]time.Time),
+	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(s)
+		}
+	}
+	return httptest.NewServer(s)
+}
+
+// ServeHTTP implements [net/http.Handler].
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	installID := strconv.FormatUint(s.installID, 10)
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/app":
+		if s.authorizeJWT(w, r) {
+			s.writeJSON(w, http.StatusOK, s.app())
+		}
+	case r.Method == http.MethodGet && match(segments, "app", "installations", "*"):
+		if !s.authorizeJWT(w, r) {
+			return
+		}
+		if segments[2] != installID {
+			s.writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		s.writeJSON(w, http.StatusOK, s.installation(r))
+	case r.Method == http.MethodGet &&
+		(match(segments, "users", "*", "installation") || match(segments, "orgs", "*", "installation")):
+		if !s.authorizeJWT(w, r) {
+			return
+		}
+		if !strings.EqualFold(segments[1], s.owner) ||
+			(segments[0] == "orgs" && s.ownerType != "Organization") {
+			s.writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		s.writeJSON(w, http.StatusOK, s.installation(r))
+	case r.Method == http.MethodGet && match(segments, "repos", "*", "*", "installation"):
+		if !s.authorizeJWT(w, r) {
+			return
+		}
+		if !strings.EqualFold(segments[1], s.owner) || s.repositoryID(segments[2]) == 0 {
+			s.writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		s.writeJSON(w, http.StatusOK, s.installation(r))
+	case r.Method == http.MethodPost && match(segments, "app", "installations", "*", "access_tokens"):
+		if !s.authorizeJWT(w, r) {
+			return
+		}
+		if segments[2] != installID {
+			s.writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		s.createToken(w, r)
+	case r.Method == http.MethodGet && match(segments, "users", "*") && segments[1] == s.appSlug+"[bot]":
+		id := int64(s.botUserID)
+		s.writeJSON(w, http.StatusOK, api.User{Login: &segments[1], ID: &id})
+	case r.Method == http.MethodGet && r.URL.Path == "/installation/repositories":
+		if s.authorizeToken(w, r) {
+			s.listRepositories(w)
+		}
+	case r.Method == http.MethodDelete && r.URL.Path == "/installation/token":
+		if s.authorizeToken(w, r) {
+			s.mu.Lock()
+			delete(s.tokens, bearer(r))
+			s.mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		}
+	default:
+		s.writeError(w, http.StatusNotFound, "Not Found")
+	}
+}
+
+// match reports whether path segments match the pattern. Pattern segment "*"
+// matches any non-empty segment.
+func match(segments []string, pattern ...string) bool {
+	if len(segments) != len(pattern) {
+		return false
+	}
+
+	for i := range pattern {
+		if segments[i] == "" || (pattern[i] != "*" && segments[i] != pattern[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// bearer returns bearer token from the Authorization header of the request.
+func bearer(r *http.Request) string {
+	v := r.Header.Get(api.AuthzHeader)
+	for _, prefix := range []string{"Bearer ", "token "} {
+		if strings.HasPrefix(v, prefix) {
+			return strings.TrimPrefix(v, prefix)
+		}
+	}
+	return ""
+}
+
+// authorizeJWT checks if request is authenticated with a JWT. Signature
+// of the JWT is not verified, but issuer must match the app id.
+func (s *server) authorizeJWT(w http.ResponseWriter, r *http.Request) bool {
+	parts := strings.Split(bearer(r), ".")
+	if len(parts) == 3 {
+		var payload api.JWTPayload
+		data, err := base64.RawURLEncoding.DecodeString(parts[1])
+		if err == nil && json.Unmarshal(data, &payload) == nil &&
+			payload.Issuer == strconv.FormatUint(s.appID, 10) {
+			return true
+		}
+	}
+
+	s.writeError(w, http.StatusUnauthorized, "A JSON web token could not be decoded")
+	return false
+}
+
+// authorizeToken checks if request is authenticated with a valid installation
+// access token minted by the server.
+func (s *server) authorizeToken(w http.ResponseWriter, r *http.Request) bool {
+	s.mu.Lock()
+	exp, ok := s.tokens[bearer(r)]
+	s.mu.Unlock()
+
+	if ok && time.Now().Before(exp) {
+		return true
+	}
+
+	s.writeError(w, http.StatusUnauthorized, "Bad credentials")
+	return false
+}
+
+// app returns app metadata.
+func (s *server) app() api.App {
+	id := int64(s.appID)
+	owner := AppOwner
+	return api.App{
+		ID:          &id,
+		Slug:        &s.appSlug,
+		Name:        &s.appSlug,
+		Owner:       &api.User{Login: &owner},
+		Permissions: s.permissions,
+		Events:      []string{},
+	}
+}
+
+// installation returns installation metadata. Access tokens URL is built from
+// the request, as server URL is not known until it is started.
+func (s *server) installation(r *http.Request) api.Installation {
+	id := int64(s.installID)
+	appID := int64(s.appID)
+	ownerID := int64(s.ownerID)
+	selection := "selected"
+	tokensURL := fmt.Sprintf("http://%s/app/installations/%d/access_tokens", r.Host, s.installID)
+	return api.Installation{
+		ID:                  &id,
+		AppID:               &appID,
+		AppSlug:             &s.appSlug,
+		TargetID:            &ownerID,
+		TargetType:          &s.ownerType,
+		Account:             &api.User{Login: &s.owner, ID: &ownerID},
+		AccessTokensURL:     &tokensURL,
+		Permissions:         s.permissions,
+		RepositorySelection: &selection,
+	}
+}
+
+// repositoryID returns id of the repository accessible to the installation,
+// or 0 if repository is not accessible.
+func (s *server) repositoryID(name string) int64 {
+	for i, repo := range s.repos {
+		if strings.EqualFold(repo, name) {
+			return int64(repositoryIDOffset + i + 1)
+		}
+	}
+	return 0
+}
+
+// repository returns repository accessible to the installation.
+func (s *server) repository(name string) *api.Repository {
+	id := s.repositoryID(name)
+	fullName := s.owner + "/" + name
+	return &api.Repository{
+		ID:       &id,
+		Owner:    &api.User{Login: &s.owner},
+		Name:     &name,
+		FullName: &fullName,
+	}
+}
+
+// createToken mints an installation access token for the requested
+// repositories and permissions.
+func (s *server) createToken(w http.ResponseWriter, r *http.Request) {
+	var req api.InstallationTokenRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeError(w, http.StatusBadRequest, "Problems parsing JSON")
+			return
+		}
+	}
+
+	repos := make([]*api.Repository, 0, len(s.repos))
+	switch {
+	case len(req.Repositories) > 0 || len(req.RepositoryIDs) > 0:
+		for _, name := range req.Repositories {
+			if s.repositoryID(name) == 0 {
+				s.writeError(w, http.StatusUnprocessableEntity,
+					"There is at least one repository that does not exist or is not accessible to the parent installation.")
+				return
+			}
+			repos = append(repos, s.repository(name))
+		}
+
+	ids:
+		for _, id := range req.RepositoryIDs {
+			for _, name := range s.repos {
+				if s.repositoryID(name) == id {
+					repos = append(repos, s.repository(name))
+					continue ids
+				}
+			}
+			s.writeError(w, http.StatusUnprocessableEntity,
+				"There is at least one repository that does not exist or is not accessible to the parent installation.")
+			return
+		}
+	default:
+		for _, name := range s.repos {
+			repos = append(repos, s.repository(name))
+		}
+	}
+
+	permissions := s.permissions
+	if len(req.Permissions) > 0 {
+		for k, v := range req.Permissions {
+			if level(s.permissions[k]) < level(v) {
+				s.writeError(w, http.StatusUnprocessableEntity,
+					"The permissions requested are not granted to this installation.")
+				return
+			}
+		}
+		permissions = req.Permissions
+	}
+
+	buf := make([]byte, 18)
+	_, _ = rand.Read(buf)
+	token := "ghs_" + hex.EncodeToString(buf)
+	exp := time.Now().Add(s.tokenTTL).Truncate(time.Second)
+
+	s.mu.Lock()
+	s.tokens[token] = exp
+	s.mu.Unlock()
+
+	s.writeJSON(w, http.StatusCreated, api.InstallationTokenResponse{
+		Token:        token,
+		Exp:          &api.Timestamp{Time: exp},
+		Permissions:  permissions,
+		Repositories: repos,
+	})
+}
+
+// listRepositories lists repositories accessible to the installation.
+// All repositories are returned in a single page.
+func (s *server) listRepositories(w http.ResponseWriter) {
+	resp := api.ListInstallationRepositoriesResponse{
+		TotalCount:   int64(len(s.repos)),
+		Repositories: make([]*api.Repository, 0, len(s.repos)),
+	}
+	for _, name := range s.repos {
+		resp.Repositories = append(resp.Repositories, s.repository(name))
+	}
+	s.writeJSON(w, http.StatusOK, resp)
+}
+
+// level returns numeric value of the permission level for comparison.
+func level(v string) int {
+	switch v {
+	case api.PermissionLevelRead:
+		return 1
+	case api.PermissionLevelWrite:
+		return 2
+	case api.PermissionLevelAdmin:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// writeJSON writes v as JSON response with status code.
+func (s *server) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set(api.ContentTypeHeader, api.ContentTypeJSON)
+	w.Header().Set(api.VersionHeader, api.VersionHeaderValue)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes API error response with status code.
+func (s *server) writeError(w http.ResponseWriter, status int, message string) {
+	s.writeJSON(w, status, api.ErrorResponse{
+		Message:          message,
+		DocumentationURL: "https://docs.github.com/rest",
+	})
+}
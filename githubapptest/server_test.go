@@ -0,0 +1,173 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapptest
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/tprasadtp/go-githubapp"
+	"github.com/tprasadtp/go-githubapp/internal/testkeys"
+)
+
+func TestNewServer(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		server := NewServer()
+		t.Cleanup(server.Close)
+
+		ctx := context.Background()
+		transport, err := githubapp.NewTransport(ctx, AppID, testkeys.RSA2048(),
+			githubapp.WithEndpoint(server.URL),
+			githubapp.WithInstallationID(InstallationID),
+		)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		if v := transport.AppName(); v != AppSlug {
+			t.Errorf("expected app name=%s, got=%s", AppSlug, v)
+		}
+
+		if v := transport.Owner(); v != Owner {
+			t.Errorf("expected owner=%s, got=%s", Owner, v)
+		}
+
+		if v := transport.BotUserID(); v != BotUserID {
+			t.Errorf("expected bot user id=%d, got=%d", BotUserID, v)
+		}
+
+		repos, err := transport.Repositories(ctx)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		if len(repos) != 1 || repos[0].Name != Repository {
+			t.Errorf("expected repositories=[%s], got=%v", Repository, repos)
+		}
+
+		token, err := transport.InstallationToken(ctx)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		if err := token.Revoke(ctx); err != nil {
+			t.Errorf("expected no error revoking token, got %s", err)
+		}
+	})
+
+	t.Run("options", func(t *testing.T) {
+		scopes := map[string]string{"contents": "write", "metadata": "read"}
+		server := NewServer(
+			WithAppID(99, "test-app"),
+			WithInstallationID(42),
+			WithAccount("octocat", 7, "User"),
+			WithRepositories("foo", "bar"),
+			WithPermissions(scopes),
+			WithBotUserID(8),
+		)
+		t.Cleanup(server.Close)
+
+		ctx := context.Background()
+		transport, err := githubapp.NewTransport(ctx, 99, testkeys.RSA2048(),
+			githubapp.WithEndpoint(server.URL),
+			githubapp.WithRepositories("octocat/bar"),
+			githubapp.WithPermissions("contents:read"),
+		)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		if v := transport.InstallationID(); v != 42 {
+			t.Errorf("expected installation id=42, got=%d", v)
+		}
+
+		account := transport.InstallationAccount()
+		if account.ID != 7 || account.Type != "User" {
+			t.Errorf("expected installation account to be populated, got %#v", account)
+		}
+
+		if v := transport.InstallationPermissions(); !maps.Equal(v, scopes) {
+			t.Errorf("expected installation permissions=%v, got=%v", scopes, v)
+		}
+
+		token, err := transport.InstallationToken(ctx)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		if !slices.Equal(token.Repositories, []string{"bar"}) {
+			t.Errorf("expected token repositories=[bar], got=%v", token.Repositories)
+		}
+
+		if v := token.Permissions["contents"]; v != "read" {
+			t.Errorf("expected token permissions contents=read, got=%s", v)
+		}
+
+		if token.BotUserID != 8 {
+			t.Errorf("expected bot user id=8, got=%d", token.BotUserID)
+		}
+	})
+
+	t.Run("not-accessible", func(t *testing.T) {
+		server := NewServer()
+		t.Cleanup(server.Close)
+
+		_, err := githubapp.NewTransport(context.Background(), AppID, testkeys.RSA2048(),
+			githubapp.WithEndpoint(server.URL),
+			githubapp.WithRepositories(Owner+"/does-not-exist"),
+		)
+		if err == nil {
+			t.Fatalf("expected an error, got nil")
+		}
+	})
+
+	t.Run("permissions-not-granted", func(t *testing.T) {
+		server := NewServer()
+		t.Cleanup(server.Close)
+
+		_, err := githubapp.NewTransport(context.Background(), AppID, testkeys.RSA2048(),
+			githubapp.WithEndpoint(server.URL),
+			githubapp.WithInstallationID(InstallationID),
+			githubapp.WithPermissions("issues:write"),
+		)
+		if err == nil {
+			t.Fatalf("expected an error, got nil")
+		}
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		server := NewServer()
+		t.Cleanup(server.Close)
+
+		for _, path := range []string{"/app", "/installation/repositories"} {
+			resp, err := http.Get(server.URL + path)
+			if err != nil {
+				t.Fatalf("expected no error, got %s", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != http.StatusUnauthorized {
+				t.Errorf("%s: expected status=401, got=%d", path, resp.StatusCode)
+			}
+		}
+	})
+
+	t.Run("wrong-app", func(t *testing.T) {
+		server := NewServer()
+		t.Cleanup(server.Close)
+
+		_, err := githubapp.NewTransport(context.Background(), 99, testkeys.RSA2048(),
+			githubapp.WithEndpoint(server.URL),
+		)
+
+		var apiErr *githubapp.APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+			t.Errorf("expected API error with status 401, got %v", err)
+		}
+	})
+}
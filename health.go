@@ -0,0 +1,67 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrHealthUnknown is returned by [Transport.Healthy] when health of the
+// transport has never been checked.
+const ErrHealthUnknown = Error("githubapp: transport health is unknown")
+
+// healthState is last known health state of the [Transport].
+// It is always stored by value in an atomic.Value, thus err may be nil.
+type healthState struct {
+	err error
+}
+
+// setHealth records the health state of the transport. A nil error
+// marks the transport as healthy.
+func (t *Transport) setHealth(err error) {
+	t.health.Store(healthState{err: err})
+}
+
+// setHealthFromResponse records health state of the transport based on the
+// outcome of a round trip. Only errors from the underlying round tripper and
+// responses indicating rejected credentials mark the transport unhealthy.
+// Errors caused by the request context being canceled or its deadline being
+// exceeded are ignored, as they say nothing about health of the transport.
+func (t *Transport) setHealthFromResponse(ctx context.Context, resp *http.Response, err error) {
+	switch {
+	case err != nil && (ctx.Err() != nil || errors.Is(err, context.Canceled)):
+		return
+	case err != nil:
+		t.setHealth(fmt.Errorf("githubapp: request failed: %w", err))
+	case resp != nil && resp.StatusCode == http.StatusUnauthorized:
+		t.setHealth(fmt.Errorf("githubapp: credentials rejected: %s", resp.Status))
+	default:
+		t.setHealth(nil)
+	}
+}
+
+// Healthy returns the last known health state of the [Transport]. This does not
+// make any network calls and is cheap enough to be used by readiness probes.
+//
+// Health state is updated by minting of JWT and installation access tokens and
+// by the outcome of the most recent request made via the [Transport].
+//
+//   - If health has never been checked, this returns false and [ErrHealthUnknown].
+//   - If the transport is healthy, this returns true and nil error.
+//   - If the transport is unhealthy, this returns false and the reason as error.
+func (t *Transport) Healthy() (bool, error) {
+	v := t.health.Load()
+	if v == nil {
+		return false, ErrHealthUnknown
+	}
+
+	state, _ := v.(healthState)
+	if state.err != nil {
+		return false, state.err
+	}
+	return true, nil
+}
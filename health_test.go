@@ -0,0 +1,130 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/tprasadtp/go-githubapp/internal/api"
+	"github.com/tprasadtp/go-githubapp/internal/testkeys"
+)
+
+func TestTransport_Healthy(t *testing.T) {
+	var status int
+	var rtErr error
+	u, _ := url.Parse("https://api.go-githubapp.test/")
+	transport := &Transport{
+		appID:   99,
+		baseURL: u,
+		minter:  &jwtRS256{internal: testkeys.RSA2048()},
+		next: api.RoundTripFunc(func(_ *http.Request) (*http.Response, error) {
+			if rtErr != nil {
+				return nil, rtErr
+			}
+			resp := httptest.NewRecorder()
+			resp.WriteHeader(status)
+			return resp.Result(), nil
+		}),
+	}
+
+	doRequest := func(t *testing.T) {
+		t.Helper()
+		r, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, u.JoinPath("app").String(), nil)
+		resp, err := transport.RoundTrip(r)
+		if err == nil {
+			resp.Body.Close()
+		}
+	}
+
+	t.Run("never-checked", func(t *testing.T) {
+		ok, err := transport.Healthy()
+		if ok {
+			t.Errorf("expected transport to be not healthy")
+		}
+		if !errors.Is(err, ErrHealthUnknown) {
+			t.Errorf("expected error %s, got %s", ErrHealthUnknown, err)
+		}
+	})
+
+	t.Run("success", func(t *testing.T) {
+		status, rtErr = http.StatusOK, nil
+		doRequest(t)
+		ok, err := transport.Healthy()
+		if !ok || err != nil {
+			t.Errorf("expected transport to be healthy, got ok=%t, err=%s", ok, err)
+		}
+	})
+
+	t.Run("round-tripper-error", func(t *testing.T) {
+		status, rtErr = 0, os.ErrDeadlineExceeded
+		doRequest(t)
+		ok, err := transport.Healthy()
+		if ok {
+			t.Errorf("expected transport to be not healthy")
+		}
+		if !errors.Is(err, os.ErrDeadlineExceeded) {
+			t.Errorf("expected error to wrap %s, got %s", os.ErrDeadlineExceeded, err)
+		}
+	})
+
+	t.Run("recover", func(t *testing.T) {
+		status, rtErr = http.StatusNotFound, nil
+		doRequest(t)
+		ok, err := transport.Healthy()
+		if !ok || err != nil {
+			t.Errorf("expected transport to be healthy, got ok=%t, err=%s", ok, err)
+		}
+	})
+
+	t.Run("context-done", func(t *testing.T) {
+		status, rtErr = http.StatusOK, nil
+		doRequest(t)
+
+		for _, v := range []error{context.Canceled, context.DeadlineExceeded} {
+			status, rtErr = 0, v
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			r, _ := http.NewRequestWithContext(ctx, http.MethodGet, u.JoinPath("app").String(), nil)
+			resp, err := transport.RoundTrip(r)
+			if err == nil {
+				resp.Body.Close()
+			}
+
+			ok, err := transport.Healthy()
+			if !ok || err != nil {
+				t.Errorf("expected caller's context to be ignored, got ok=%t, err=%s", ok, err)
+			}
+		}
+	})
+
+	t.Run("credentials-rejected", func(t *testing.T) {
+		status, rtErr = http.StatusUnauthorized, nil
+		doRequest(t)
+		ok, err := transport.Healthy()
+		if ok || err == nil {
+			t.Errorf("expected transport to be not healthy, got ok=%t, err=%s", ok, err)
+		}
+	})
+
+	t.Run("signer-errors", func(t *testing.T) {
+		transport := &Transport{
+			appID:  99,
+			minter: &jwtRS256{internal: &errSigner{signer: testkeys.RSA2048()}},
+		}
+		_, _ = transport.JWT(context.Background())
+		ok, err := transport.Healthy()
+		if ok {
+			t.Errorf("expected transport to be not healthy")
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expected error to wrap %s, got %s", os.ErrNotExist, err)
+		}
+	})
+}
@@ -0,0 +1,293 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tprasadtp/go-githubapp/internal/api"
+)
+
+// Installation is a GitHub app installation.
+type Installation struct {
+	// GitHub app ID.
+	AppID uint64 `json:"app_id,omitempty" yaml:"appID,omitempty"`
+
+	// Installation ID.
+	InstallationID uint64 `json:"installation_id,omitempty" yaml:"installationID,omitempty"`
+
+	// Account login of the installation owner.
+	Account string `json:"account,omitempty" yaml:"account,omitempty"`
+
+	// TargetType is type of the installation target, typically
+	// "Organization" or "User".
+	TargetType string `json:"target_type,omitempty" yaml:"targetType,omitempty"`
+
+	// Permissions granted to the installation.
+	Permissions map[string]string `json:"permissions,omitempty" yaml:"permissions,omitempty"`
+
+	// RepositorySelection is either "all" or "selected", depending on whether
+	// installation has access to all repositories of the account.
+	RepositorySelection string `json:"repository_selection,omitempty" yaml:"repositorySelection,omitempty"`
+
+	// SuspendedAt is time at which installation was suspended.
+	// This is zero if installation is not suspended.
+	SuspendedAt time.Time `json:"suspended_at,omitempty" yaml:"suspendedAt,omitempty"`
+}
+
+// InstallationAccount is the account (user or organization) the app is installed on.
+type InstallationAccount struct {
+	// Account login.
+	Login string `json:"login,omitempty" yaml:"login,omitempty"`
+
+	// Account ID.
+	ID uint64 `json:"id,omitempty" yaml:"id,omitempty"`
+
+	// Type is type of the account, typically "Organization" or "User".
+	Type string `json:"type,omitempty" yaml:"type,omitempty"`
+}
+
+// Repository is a repository accessible to an installation.
+type Repository struct {
+	// Repository ID.
+	ID uint64 `json:"id,omitempty" yaml:"id,omitempty"`
+
+	// Repository name without the owner.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+
+	// FullName is repository name in "owner/name" format.
+	FullName string `json:"full_name,omitempty" yaml:"fullName,omitempty"`
+
+	// Private is true if repository is private or internal.
+	Private bool `json:"private,omitempty" yaml:"private,omitempty"`
+}
+
+// nextPageURL returns URL of the next page from the Link header.
+// If there is no next page, this returns empty string. Relation types
+// are matched case-insensitively and may be unquoted or may contain
+// multiple space separated relation types as per RFC 8288.
+//
+// https://docs.github.com/en/rest/using-the-rest-api/using-pagination-in-the-rest-api
+func nextPageURL(h http.Header) string {
+	for _, link := range h.Values("Link") {
+		for _, item := range strings.Split(link, ",") {
+			target, params, ok := strings.Cut(strings.TrimSpace(item), ";")
+			if !ok {
+				continue
+			}
+
+			target = strings.TrimSpace(target)
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+
+			for _, param := range strings.Split(params, ";") {
+				if isNextRel(param) {
+					return strings.TrimSuffix(strings.TrimPrefix(target, "<"), ">")
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// isNextRel returns true if link parameter is a relation type parameter
+// which includes "next".
+func isNextRel(param string) bool {
+	key, value, ok := strings.Cut(param, "=")
+	if !ok || !strings.EqualFold(strings.TrimSpace(key), "rel") {
+		return false
+	}
+
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+		value = value[1 : len(value)-1]
+	}
+
+	for _, rel := range strings.Fields(value) {
+		if strings.EqualFold(rel, "next") {
+			return true
+		}
+	}
+	return false
+}
+
+// Installations returns all installations of the app. This always authenticates
+// as app (using JWT), even if installation options are specified.
+//
+// https://docs.github.com/en/rest/apps/apps?apiVersion=2022-11-28#list-installations-for-the-authenticated-app
+func (t *Transport) Installations(ctx context.Context) ([]Installation, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	client := t.internalClient()
+
+	u := t.baseURL.JoinPath("app", "installations")
+	u.RawQuery = "per_page=100"
+
+	var installations []Installation
+	for next := u.String(); next != ""; {
+		// Set context to use JWT.
+		r, err := http.NewRequestWithContext(ctxWithJWTKey(ctx), http.MethodGet, next, nil)
+		if err != nil {
+			return nil, fmt.Errorf("githubapp(installations): failed to build request: %w", err)
+		}
+
+		resp, err := client.Do(r)
+		if err != nil {
+			return nil, fmt.Errorf("githubapp(installations): failed to list installations: %w", err)
+		}
+
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("githubapp(installations): failed to read response: %w", err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("githubapp(installations): failed to list installations: %w",
+				newAPIError(resp, data))
+		}
+
+		page := []api.Installation{}
+		err = json.Unmarshal(data, &page)
+		if err != nil {
+			return nil, fmt.Errorf("githubapp(installations): failed to unmarshal response: %w", err)
+		}
+
+		for _, item := range page {
+			if item.ID == nil {
+				continue
+			}
+
+			installation := Installation{
+				InstallationID: uint64(*item.ID),
+				Permissions:    item.Permissions,
+			}
+			if item.AppID != nil {
+				installation.AppID = uint64(*item.AppID)
+			}
+			if item.Account != nil && item.Account.Login != nil {
+				installation.Account = *item.Account.Login
+			}
+			if item.TargetType != nil {
+				installation.TargetType = *item.TargetType
+			}
+			if item.RepositorySelection != nil {
+				installation.RepositorySelection = *item.RepositorySelection
+			}
+			if item.SuspendedAt != nil {
+				installation.SuspendedAt = item.SuspendedAt.Time
+			}
+			installations = append(installations, installation)
+		}
+
+		next = nextPageURL(resp.Header)
+	}
+
+	return installations, nil
+}
+
+// Repositories returns all repositories accessible to the installation, even if
+// [Transport] is configured with [WithRepositories] or [WithRepositoryIDs]. In such
+// cases, repositories are listed with a new installation access token, which is not
+// scoped to repositories, and is revoked once done. If [WithStaticInstallationToken]
+// is specified, only repositories accessible to the static token are returned.
+//
+// https://docs.github.com/en/rest/apps/installations?apiVersion=2022-11-28#list-repositories-accessible-to-the-app-installation
+func (t *Transport) Repositories(ctx context.Context) ([]Repository, error) {
+	if t.installID == 0 {
+		return nil, errors.New("githubapp(repositories): installation id is not configured")
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	lt := t
+	if t.static == nil && (len(t.repos) > 0 || len(t.repoIDs) > 0) {
+		lt = t.unscopedTransport()
+		defer func() {
+			if err := lt.RevokeToken(context.WithoutCancel(ctx)); err != nil {
+				t.debug(ctx, "githubapp: failed to revoke installation token", slog.Any("err", err))
+			}
+		}()
+	}
+
+	client := lt.internalClient()
+
+	u := t.baseURL.JoinPath("installation", "repositories")
+	u.RawQuery = "per_page=100"
+
+	var repos []Repository
+	for next := u.String(); next != ""; {
+		r, err := http.NewRequestWithContext(ctx, http.MethodGet, next, nil)
+		if err != nil {
+			return nil, fmt.Errorf("githubapp(repositories): failed to build request: %w", err)
+		}
+
+		// Installation token requests do not set these headers.
+		r.Header.Set(api.AcceptHeader, api.AcceptHeaderValue)
+		r.Header.Set(api.VersionHeader, t.versionHeaderValue())
+		r.Header.Set(api.UAHeader, t.userAgent())
+
+		resp, err := client.Do(r)
+		if err != nil {
+			return nil, fmt.Errorf("githubapp(repositories): failed to list repositories: %w", err)
+		}
+
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("githubapp(repositories): failed to read response: %w", err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("githubapp(repositories): failed to list repositories: %w",
+				newAPIError(resp, data))
+		}
+
+		page := api.ListInstallationRepositoriesResponse{}
+		err = json.Unmarshal(data, &page)
+		if err != nil {
+			return nil, fmt.Errorf("githubapp(repositories): failed to unmarshal response: %w", err)
+		}
+
+		for _, item := range page.Repositories {
+			if item == nil || item.ID == nil || item.Name == nil {
+				continue
+			}
+
+			repo := Repository{
+				ID:   uint64(*item.ID),
+				Name: *item.Name,
+			}
+
+			switch {
+			case item.FullName != nil:
+				repo.FullName = *item.FullName
+			case item.Owner != nil && item.Owner.Login != nil:
+				repo.FullName = *item.Owner.Login + "/" + *item.Name
+			}
+
+			if item.Private != nil {
+				repo.Private = *item.Private
+			}
+			repos = append(repos, repo)
+		}
+
+		next = nextPageURL(resp.Header)
+	}
+
+	return repos, nil
+}
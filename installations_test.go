@@ -0,0 +1,331 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tprasadtp/go-githubapp/internal/api"
+	"github.com/tprasadtp/go-githubapp/internal/testkeys"
+)
+
+func TestNextPageURL(t *testing.T) {
+	tt := []struct {
+		name   string
+		links  []string
+		expect string
+	}{
+		{
+			name: "no-link-header",
+		},
+		{
+			name:  "last-page",
+			links: []string{`<https://api.github.com/app/installations?page=1>; rel="prev", <https://api.github.com/app/installations?page=1>; rel="first"`},
+		},
+		{
+			name:   "next-page",
+			links:  []string{`<https://api.github.com/app/installations?page=2>; rel="next", <https://api.github.com/app/installations?page=5>; rel="last"`},
+			expect: "https://api.github.com/app/installations?page=2",
+		},
+		{
+			name:   "next-page-not-first",
+			links:  []string{`<https://api.github.com/app/installations?page=1>; rel="prev", <https://api.github.com/app/installations?page=3>; rel="next"`},
+			expect: "https://api.github.com/app/installations?page=3",
+		},
+		{
+			name:   "multiple-headers",
+			links:  []string{`<https://api.github.com/app/installations?page=1>; rel="prev"`, `<https://api.github.com/app/installations?page=3>; rel="next"`},
+			expect: "https://api.github.com/app/installations?page=3",
+		},
+		{
+			name:   "multiple-rels",
+			links:  []string{`<https://api.github.com/app/installations?page=2>; rel="next last"`},
+			expect: "https://api.github.com/app/installations?page=2",
+		},
+		{
+			name:   "unquoted-rel",
+			links:  []string{`<https://api.github.com/app/installations?page=2>; rel=next`},
+			expect: "https://api.github.com/app/installations?page=2",
+		},
+		{
+			name:   "mixed-case-and-spaces",
+			links:  []string{`<https://api.github.com/app/installations?page=2> ; REL = "Next"`},
+			expect: "https://api.github.com/app/installations?page=2",
+		},
+		{
+			name:   "other-params",
+			links:  []string{`<https://api.github.com/app/installations?page=2>; title="next"; rel="next"`},
+			expect: "https://api.github.com/app/installations?page=2",
+		},
+		{
+			name:  "rel-not-next",
+			links: []string{`<https://api.github.com/app/installations?page=2>; rel="nextpage"`},
+		},
+		{
+			name:  "malformed",
+			links: []string{`https://api.github.com/app/installations?page=2; rel="next"`},
+		},
+		{
+			name:  "malformed-no-params",
+			links: []string{`<https://api.github.com/app/installations?page=2>`},
+		},
+		{
+			name:  "malformed-empty-rel",
+			links: []string{`<https://api.github.com/app/installations?page=2>; rel=`},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := make(http.Header)
+			for _, item := range tc.links {
+				h.Add("Link", item)
+			}
+			if v := nextPageURL(h); v != tc.expect {
+				t.Errorf("expected=%q, got=%q", tc.expect, v)
+			}
+		})
+	}
+}
+
+func TestTransport_Installations(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/error/app/installations" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if r.URL.Path != "/app/installations" {
+			t.Errorf("Unknown/Invalid Request => %s", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if r.Header.Get("Authorization") == "" {
+			t.Errorf("Authorization header is empty")
+		}
+
+		switch r.URL.Query().Get("page") {
+		case "":
+			w.Header().Set("Link",
+				fmt.Sprintf(`<http://%s/app/installations?per_page=100&page=2>; rel="next"`, r.Host))
+			_, _ = w.Write([]byte(`[
+				{"id":1,"app_id":99,"target_type":"Organization","account":{"login":"org"},"permissions":{"issues":"read"},
+				 "repository_selection":"selected"},
+				{"id":2,"app_id":99,"target_type":"User","account":{"login":"user"},"repository_selection":"all",
+				 "suspended_at":"2024-01-02T03:04:05Z"}
+			]`))
+		case "2":
+			_, _ = w.Write([]byte(`[{"id":3,"app_id":99,"target_type":"User","account":{"login":"another-user"}}]`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	u, _ := url.Parse(server.URL)
+	transport := &Transport{
+		appID:   99,
+		baseURL: u,
+		minter:  &jwtRS256{internal: testkeys.RSA2048()},
+		next:    http.DefaultTransport,
+	}
+
+	installations, err := transport.Installations(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(installations) != 3 {
+		t.Fatalf("expected 3 installations, got %d", len(installations))
+	}
+
+	if installations[0].Account != "org" || installations[0].TargetType != "Organization" ||
+		installations[0].AppID != 99 || installations[0].InstallationID != 1 ||
+		installations[0].Permissions["issues"] != "read" {
+		t.Errorf("unexpected installation: %#v", installations[0])
+	}
+
+	if installations[0].RepositorySelection != "selected" || !installations[0].SuspendedAt.IsZero() {
+		t.Errorf("unexpected installation: %#v", installations[0])
+	}
+
+	if installations[1].RepositorySelection != "all" ||
+		!installations[1].SuspendedAt.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("unexpected installation: %#v", installations[1])
+	}
+
+	if installations[2].Account != "another-user" || installations[2].InstallationID != 3 {
+		t.Errorf("unexpected installation: %#v", installations[2])
+	}
+
+	t.Run("scoped-to-repositories", func(t *testing.T) {
+		var revoked atomic.Int64
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/app/installations/99/access_tokens":
+				req := api.InstallationTokenRequest{}
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Errorf("failed to decode token request: %s", err)
+				}
+
+				if len(req.Repositories) != 0 || len(req.RepositoryIDs) != 0 {
+					t.Errorf("expected token not to be scoped to repositories, got %v", req.Repositories)
+				}
+				w.WriteHeader(http.StatusCreated)
+				_, _ = w.Write([]byte(`{"token":"ghs_unscoped","expires_at":"2099-01-01T00:00:00Z"}`))
+			case "/installation/repositories":
+				if v := r.Header.Get("Authorization"); v != "Bearer ghs_unscoped" {
+					t.Errorf("expected unscoped installation token, got %q", v)
+				}
+				_, _ = w.Write([]byte(`{"total_count":2,"repositories":[
+					{"id":1,"name":"foo","full_name":"org/foo"},
+					{"id":2,"name":"bar","full_name":"org/bar"}
+				]}`))
+			case "/installation/token":
+				revoked.Add(1)
+				w.WriteHeader(http.StatusNoContent)
+			default:
+				t.Errorf("Unknown/Invalid Request => %s", r.URL)
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		t.Cleanup(server.Close)
+
+		u, _ := url.Parse(server.URL)
+		transport := &Transport{
+			appID:     99,
+			installID: 99,
+			owner:     "org",
+			repos:     []string{"foo"},
+			baseURL:   u,
+			minter:    &jwtRS256{internal: testkeys.RSA2048()},
+			next:      http.DefaultTransport,
+		}
+
+		repos, err := transport.Repositories(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if len(repos) != 2 {
+			t.Errorf("expected all repositories of the installation, got %v", repos)
+		}
+
+		if v := revoked.Load(); v != 1 {
+			t.Errorf("expected unscoped token to be revoked, got %d revocations", v)
+		}
+	})
+
+	t.Run("api-error", func(t *testing.T) {
+		transport := &Transport{
+			appID:   99,
+			baseURL: u.JoinPath("error"),
+			minter:  &jwtRS256{internal: testkeys.RSA2048()},
+			next:    http.DefaultTransport,
+		}
+		_, err := transport.Installations(context.Background())
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("expected *APIError, got %T(%s)", err, err)
+		}
+	})
+}
+
+func TestTransport_Repositories(t *testing.T) {
+	t.Run("no-installation-id", func(t *testing.T) {
+		transport := &Transport{appID: 99}
+		_, err := transport.Repositories(context.Background())
+		if err == nil {
+			t.Errorf("expected an error, got nil")
+		}
+	})
+
+	t.Run("paginated", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/app/installations/99/access_tokens":
+				w.WriteHeader(http.StatusCreated)
+				_, _ = w.Write([]byte(`{"token":"ghs_token","expires_at":"2099-01-01T00:00:00Z"}`))
+			case "/installation/repositories":
+				if v := r.Header.Get("Authorization"); v != "Bearer ghs_token" {
+					t.Errorf("expected installation token, got %q", v)
+				}
+				switch r.URL.Query().Get("page") {
+				case "":
+					w.Header().Set("Link",
+						fmt.Sprintf(`<http://%s/installation/repositories?per_page=100&page=2>; rel="next"`, r.Host))
+					_, _ = w.Write([]byte(`{"total_count":3,"repositories":[
+						{"id":1,"name":"foo","full_name":"org/foo","private":true,"owner":{"login":"org"}},
+						{"id":2,"name":"bar","full_name":"org/bar","private":false,"owner":{"login":"org"}}
+					]}`))
+				case "2":
+					_, _ = w.Write([]byte(`{"total_count":3,"repositories":[{"id":3,"name":"baz","owner":{"login":"org"}}]}`))
+				}
+			default:
+				t.Errorf("Unknown/Invalid Request => %s", r.URL)
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		t.Cleanup(server.Close)
+
+		u, _ := url.Parse(server.URL)
+		transport := &Transport{
+			appID:     99,
+			installID: 99,
+			baseURL:   u,
+			minter:    &jwtRS256{internal: testkeys.RSA2048()},
+			next:      http.DefaultTransport,
+		}
+
+		repos, err := transport.Repositories(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		expect := []Repository{
+			{ID: 1, Name: "foo", FullName: "org/foo", Private: true},
+			{ID: 2, Name: "bar", FullName: "org/bar"},
+			{ID: 3, Name: "baz", FullName: "org/baz"},
+		}
+		if !slices.Equal(repos, expect) {
+			t.Errorf("expected=%v, got=%v", expect, repos)
+		}
+	})
+
+	t.Run("api-error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/app/installations/99/access_tokens" {
+				w.WriteHeader(http.StatusCreated)
+				_, _ = w.Write([]byte(`{"token":"ghs_token","expires_at":"2099-01-01T00:00:00Z"}`))
+				return
+			}
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message":"Resource not accessible by integration"}`))
+		}))
+		t.Cleanup(server.Close)
+
+		u, _ := url.Parse(server.URL)
+		transport := &Transport{
+			appID:     99,
+			installID: 99,
+			baseURL:   u,
+			minter:    &jwtRS256{internal: testkeys.RSA2048()},
+			next:      http.DefaultTransport,
+		}
+
+		_, err := transport.Repositories(context.Background())
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden {
+			t.Errorf("expected *APIError with status 403, got %T(%s)", err, err)
+		}
+	})
+}
@@ -356,6 +356,10 @@ func TestIntegration(t *testing.T) {
 			t.Errorf("BotCommitterEmail() returns empty")
 		}
 
+		if transport.BotUserID() == 0 {
+			t.Errorf("BotUserID() returns zero")
+		}
+
 		if transport.AppID() != appID {
 			t.Errorf("Expected app id=%d, got=%d", appID, transport.AppID())
 		}
@@ -363,6 +367,12 @@ func TestIntegration(t *testing.T) {
 		if transport.AppName() == "" {
 			t.Errorf("Expected app id to be populated, but got empty")
 		}
+
+		switch v := transport.InstallationAccountType(); v {
+		case "User", "Organization":
+		default:
+			t.Errorf("Expected installation account type to be User or Organization, got %q", v)
+		}
 	})
 
 	t.Run("ScopedRepositories", func(t *testing.T) {
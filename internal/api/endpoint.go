@@ -5,3 +5,10 @@ package api
 
 // DefaultEndpoint is default GitHub REST API endpoint.
 const DefaultEndpoint = "https://api.github.com/"
+
+// DefaultGraphQLEndpoint is default GitHub GraphQL API endpoint.
+const DefaultGraphQLEndpoint = "https://api.github.com/graphql"
+
+// DefaultUploadEndpoint is default GitHub upload endpoint used
+// for uploading release assets.
+const DefaultUploadEndpoint = "https://uploads.github.com/"
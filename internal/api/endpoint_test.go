@@ -11,8 +11,10 @@ import (
 )
 
 func TestDefaultEndpoint(t *testing.T) {
-	_, err := url.Parse(api.DefaultEndpoint)
-	if err != nil {
-		t.Errorf("DefaultEndpoint URL(%s) is invalid: %s", api.DefaultEndpoint, err)
+	for _, item := range []string{api.DefaultEndpoint, api.DefaultGraphQLEndpoint, api.DefaultUploadEndpoint} {
+		_, err := url.Parse(item)
+		if err != nil {
+			t.Errorf("Endpoint URL(%s) is invalid: %s", item, err)
+		}
 	}
 }
@@ -0,0 +1,97 @@
+// SPDX-FileCopyrightText: Copyright 2024 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+//go:build ignore
+
+// This program generates permission_names.go from the "app-permissions" schema
+// in the GitHub REST API OpenAPI description. Run it via "go generate".
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+)
+
+const defaultSource = "https://raw.githubusercontent.com/github/rest-api-description/main/descriptions/api.github.com/api.github.com.json"
+
+func main() {
+	source := flag.String("source", defaultSource, "OpenAPI description URL or file")
+	output := flag.String("output", "permission_names.go", "output file")
+	flag.Parse()
+
+	data, err := read(*source)
+	if err != nil {
+		log.Fatalf("failed to read OpenAPI description: %s", err)
+	}
+
+	var description struct {
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]json.RawMessage `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	err = json.Unmarshal(data, &description)
+	if err != nil {
+		log.Fatalf("failed to parse OpenAPI description: %s", err)
+	}
+
+	schema, ok := description.Components.Schemas["app-permissions"]
+	if !ok || len(schema.Properties) == 0 {
+		log.Fatalf("app-permissions schema not found in OpenAPI description")
+	}
+
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var buf bytes.Buffer
+	buf.WriteString("// SPDX-FileCopyrightText: Copyright 2024 Prasad Tengse\n")
+	buf.WriteString("// SPDX-License-Identifier: MIT\n\n")
+	buf.WriteString("// Code generated by gen_permissions.go; DO NOT EDIT.\n\n")
+	buf.WriteString("package api\n\n")
+	buf.WriteString("// PermissionNames is a sorted list of known GitHub app permission names.\n")
+	buf.WriteString("var PermissionNames = []string{\n")
+	for _, name := range names {
+		fmt.Fprintf(&buf, "\t%q,\n", name)
+	}
+	buf.WriteString("}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatalf("failed to format generated code: %s", err)
+	}
+
+	err = os.WriteFile(*output, src, 0o644)
+	if err != nil {
+		log.Fatalf("failed to write %s: %s", *output, err)
+	}
+}
+
+// read reads OpenAPI description from URL or file.
+func read(source string) ([]byte, error) {
+	if _, err := os.Stat(source); err == nil {
+		return os.ReadFile(source)
+	}
+
+	resp, err := http.Get(source) //nolint:gosec,noctx // only used by go generate.
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -4,6 +4,8 @@
 package api
 
 // Common headers used by this package.
+//
+// VersionHeaderValue is only the default API version. It can be overridden.
 const (
 	VersionHeader      = "X-GitHub-Api-Version"
 	VersionHeaderValue = "2022-11-28"
@@ -14,11 +16,21 @@ const (
 	AuthzHeader        = "Authorization"
 	ContentTypeHeader  = "Content-Type"
 	ContentTypeJSON    = "application/json"
+	ContentTypeForm    = "application/x-www-form-urlencoded"
+)
+
+// Conditional request headers.
+const (
+	ETagHeader            = "ETag"
+	LastModifiedHeader    = "Last-Modified"
+	IfNoneMatchHeader     = "If-None-Match"
+	IfModifiedSinceHeader = "If-Modified-Since"
 )
 
 // GitHub webhook headers in canonical form.
 const (
 	SignatureSHA256Header        = "X-Hub-Signature-256"
+	SignatureSHA1Header          = "X-Hub-Signature"
 	EventHeader                  = "X-GitHub-Event"
 	HookIDHeader                 = "X-GitHub-Hook-ID"
 	DeliveryHeader               = "X-GitHub-Delivery"
@@ -26,6 +38,10 @@ const (
 	InstallationTargetTypeHeader = "X-GitHub-Hook-Installation-Target-Type"
 )
 
+// VersionHeaderFormat is layout of the X-GitHub-Api-Version header value
+// for use with [time.Parse].
+const VersionHeaderFormat = "2006-01-02"
+
 // AuthzHeaderValue is a convenience function to return Authorization header as value.
 // If the token is empty, this returns empty string. Token is assumed to be
 // bearer token.
@@ -3,6 +3,8 @@
 
 package api
 
+//go:generate go run gen_permissions.go
+
 const (
 	PermissionLevelNone  = "none"
 	PermissionLevelRead  = "read"
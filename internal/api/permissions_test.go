@@ -0,0 +1,27 @@
+// SPDX-FileCopyrightText: Copyright 2024 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package api_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/tprasadtp/go-githubapp/internal/api"
+)
+
+func TestPermissionNames(t *testing.T) {
+	if !slices.IsSorted(api.PermissionNames) {
+		t.Errorf("PermissionNames must be sorted")
+	}
+
+	if len(slices.Compact(slices.Clone(api.PermissionNames))) != len(api.PermissionNames) {
+		t.Errorf("PermissionNames must not have duplicates")
+	}
+
+	for _, name := range []string{"contents", "issues", "metadata", "pull_requests"} {
+		if _, ok := slices.BinarySearch(api.PermissionNames, name); !ok {
+			t.Errorf("PermissionNames must include %s", name)
+		}
+	}
+}
@@ -5,9 +5,11 @@ package api
 
 // Repository represents a GitHub repository. This is incomplete!
 type Repository struct {
-	ID    *int64  `json:"id,omitempty"`
-	Owner *User   `json:"owner,omitempty"`
-	Name  *string `json:"name,omitempty"`
+	ID       *int64  `json:"id,omitempty"`
+	Owner    *User   `json:"owner,omitempty"`
+	Name     *string `json:"name,omitempty"`
+	FullName *string `json:"full_name,omitempty"`
+	Private  *bool   `json:"private,omitempty"`
 }
 
 // User represents a GitHub user. This is incomplete!
@@ -20,8 +22,9 @@ type User struct {
 //
 // https://docs.github.com/en/rest/apps/apps?apiVersion=2022-11-28#create-an-installation-access-token-for-an-app
 type InstallationTokenRequest struct {
-	Repositories []string          `json:"repositories,omitempty"`
-	Permissions  map[string]string `json:"permissions,omitempty"`
+	Repositories  []string          `json:"repositories,omitempty"`
+	RepositoryIDs []int64           `json:"repository_ids,omitempty"`
+	Permissions   map[string]string `json:"permissions,omitempty"`
 }
 
 // InstallationTokenResponse is returned by the API for [InstallationTokenRequest].
@@ -38,15 +41,18 @@ type InstallationTokenResponse struct {
 //
 // https://docs.github.com/en/rest/apps/apps?apiVersion=2022-11-28#get-a-repository-installation-for-the-authenticated-app
 type Installation struct {
-	ID              *int64            `json:"id,omitempty"`
-	AppID           *int64            `json:"app_id,omitempty"`
-	AppSlug         *string           `json:"app_slug,omitempty"`
-	TargetID        *int64            `json:"target_id,omitempty"`
-	TargetType      *string           `json:"target_type,omitempty"`
-	Account         *User             `json:"account,omitempty"`
-	AccessTokensURL *string           `json:"access_tokens_url,omitempty"`
-	Permissions     map[string]string `json:"permissions,omitempty"`
-	SuspendedAt     *Timestamp        `json:"suspended_at,omitempty"`
+	ID                  *int64            `json:"id,omitempty"`
+	AppID               *int64            `json:"app_id,omitempty"`
+	AppSlug             *string           `json:"app_slug,omitempty"`
+	TargetID            *int64            `json:"target_id,omitempty"`
+	TargetType          *string           `json:"target_type,omitempty"`
+	Account             *User             `json:"account,omitempty"`
+	AccessTokensURL     *string           `json:"access_tokens_url,omitempty"`
+	Permissions         map[string]string `json:"permissions,omitempty"`
+	SuspendedAt         *Timestamp        `json:"suspended_at,omitempty"`
+	RepositorySelection *string           `json:"repository_selection,omitempty"`
+	SingleFileName      *string           `json:"single_file_name,omitempty"`
+	SingleFilePaths     []string          `json:"single_file_paths,omitempty"`
 }
 
 type ErrorResponse struct {
@@ -76,3 +82,36 @@ type ListInstallationRepositoriesResponse struct {
 	TotalCount   int64         `json:"total_count,omitempty"`
 	Repositories []*Repository `json:"repositories,omitempty"`
 }
+
+// ManifestConversion is returned by the API when converting app manifest code.
+//
+// https://docs.github.com/en/rest/apps/apps?apiVersion=2022-11-28#create-a-github-app-from-a-manifest
+type ManifestConversion struct {
+	ID            *int64  `json:"id,omitempty"`
+	Slug          *string `json:"slug,omitempty"`
+	Name          *string `json:"name,omitempty"`
+	Owner         *User   `json:"owner,omitempty"`
+	HTMLURL       *string `json:"html_url,omitempty"`
+	ClientID      *string `json:"client_id,omitempty"`
+	ClientSecret  *string `json:"client_secret,omitempty"`
+	WebhookSecret *string `json:"webhook_secret,omitempty"`
+	PEM           *string `json:"pem,omitempty"`
+}
+
+// RateLimit is rate limit status of a resource.
+//
+// https://docs.github.com/en/rest/rate-limit/rate-limit?apiVersion=2022-11-28#get-rate-limit-status-for-the-authenticated-user
+type RateLimit struct {
+	Limit     int    `json:"limit"`
+	Remaining int    `json:"remaining"`
+	Used      int    `json:"used"`
+	Reset     int64  `json:"reset"`
+	Resource  string `json:"resource,omitempty"`
+}
+
+// RateLimitResponse is returned by the rate limit API.
+//
+// https://docs.github.com/en/rest/rate-limit/rate-limit?apiVersion=2022-11-28#get-rate-limit-status-for-the-authenticated-user
+type RateLimitResponse struct {
+	Resources map[string]*RateLimit `json:"resources,omitempty"`
+}
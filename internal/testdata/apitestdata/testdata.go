@@ -19,6 +19,9 @@ const AppOwner = "gh-integration-tests"
 // InstallationOwner Testdata installation owner.
 const InstallationOwner = "gh-integration-tests"
 
+// InstallationOwnerID Testdata installation owner account ID.
+const InstallationOwnerID = 145695471
+
 // Installation repository.
 const InstallationRepository = "go-githubapp-repo-one"
 
@@ -31,6 +34,9 @@ const AppID = 145695471
 // AppSlug Test App slug.
 const AppSlug = "gh-integration-tests-app"
 
+// BotUserID Test App bot user ID.
+const BotUserID = 145777326
+
 // Read api data once.
 var once sync.Once
 
@@ -17,6 +17,7 @@ import (
 	"io"
 	"log/slog"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/tprasadtp/go-githubapp/internal/api"
@@ -27,6 +28,29 @@ var (
 	_ slog.LogValuer = (*JWT)(nil)
 )
 
+// ErrSignerUnusable is returned by [NewTransport] when signer fails to sign.
+// This typically indicates missing permissions on KMS backed keys.
+const ErrSignerUnusable = Error("githubapp: signer is unusable")
+
+// ErrInvalidJWT is returned by [VerifyJWT] when JWT is malformed, its signature
+// is invalid, or it has expired.
+const ErrInvalidJWT = Error("githubapp(jwt): invalid JWT")
+
+// JWTClaims are claims of the JWT verified by [VerifyJWT].
+type JWTClaims struct {
+	// Issuer of the JWT. This is the GitHub app ID.
+	Issuer string `json:"iss" yaml:"iss"`
+
+	// GitHub app ID. This is zero if issuer is not a numeric app id.
+	AppID uint64 `json:"id,omitempty" yaml:"id,omitempty"`
+
+	// Token exp time.
+	Exp time.Time `json:"exp,omitempty" yaml:"exp,omitempty"`
+
+	// Token issue time.
+	IssuedAt time.Time `json:"iat,omitempty" yaml:"iat,omitempty"`
+}
+
 // JWT is JWT token used to authenticate as app.
 type JWT struct {
 	// JWT token.
@@ -68,21 +92,48 @@ type contextSigner interface {
 }
 
 // jwtMinter mints GitHub app JWT.
+//
+// Returned [JWT] MUST report accurate Exp and IssuedAt, matching the claims
+// encoded in the token. [Transport] relies on them to cache the JWT and
+// verifies them via [verifyJWTClaims].
 type jwtMinter interface {
 	MintJWT(ctx context.Context, iss uint64, now time.Time) (JWT, error)
 }
 
+const (
+	// defaultJWTExpiry is default lifetime of JWT.
+	defaultJWTExpiry = 2 * time.Minute
+
+	// defaultJWTSkew is default duration by which iat claim is backdated.
+	defaultJWTSkew = 30 * time.Second
+
+	// maxJWTExpiry is maximum lifetime of JWT allowed by GitHub.
+	maxJWTExpiry = 10 * time.Minute
+)
+
 // jwtRS256 mints JWT tokens using RS256.
 type jwtRS256 struct {
 	internal crypto.Signer
+	expiry   time.Duration // if zero, defaultJWTExpiry is used
+	skew     time.Duration // if zero, defaultJWTSkew is used
 }
 
 // MintJWT mints new  JWT token.
 func (s *jwtRS256) MintJWT(ctx context.Context, iss uint64, now time.Time) (JWT, error) {
+	expiry := defaultJWTExpiry
+	if s.expiry > 0 {
+		expiry = min(s.expiry, maxJWTExpiry)
+	}
+
+	skew := defaultJWTSkew
+	if s.skew > 0 {
+		skew = s.skew
+	}
+
 	// GitHub rejects timestamps that are not an integer.
 	now = now.Truncate(time.Second)
-	iat := now.Add(-30 * time.Second)
-	exp := now.Add(2 * time.Minute)
+	iat := now.Add(-skew)
+	exp := now.Add(expiry)
 
 	buf := bytes.NewBuffer(make([]byte, 0, 1024))
 	encoder := base64.NewEncoder(base64.RawURLEncoding, buf)
@@ -139,15 +190,143 @@ func (s *jwtRS256) MintJWT(ctx context.Context, iss uint64, now time.Time) (JWT,
 	return JWT{Token: buf.String(), Exp: exp, IssuedAt: iat, AppID: iss}, nil
 }
 
+// verifyJWTClaims decodes the JWT payload and verifies that exp and iat claims
+// match the values reported by [JWT]. This does NOT verify the signature.
+func verifyJWTClaims(bearer JWT) error {
+	parts := strings.Split(bearer.Token, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("malformed JWT has %d parts", len(parts))
+	}
+
+	payload := api.JWTPayload{}
+	err := decodeJWTSegment(parts[1], &payload)
+	if err != nil {
+		return fmt.Errorf("failed to decode JWT payload: %w", err)
+	}
+
+	if payload.Exp != bearer.Exp.Unix() {
+		return fmt.Errorf("JWT exp claim(%d) does not match reported exp(%d)",
+			payload.Exp, bearer.Exp.Unix())
+	}
+
+	if payload.IssuedAt != bearer.IssuedAt.Unix() {
+		return fmt.Errorf("JWT iat claim(%d) does not match reported iat(%d)",
+			payload.IssuedAt, bearer.IssuedAt.Unix())
+	}
+	return nil
+}
+
+// decodeJWTSegment decodes base64 url encoded JSON segment of the JWT into v.
+func decodeJWTSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return fmt.Errorf("not base64 url encoded: %w", err)
+	}
+	return json.Unmarshal(data, v)
+}
+
+// jwtVerifyLeeway is the allowed clock skew when verifying iat claim.
+const jwtVerifyLeeway = time.Minute
+
+// VerifyJWT verifies the JWT minted by [NewJWT] or [Transport.JWT] and returns
+// its claims. This verifies RS256 signature of the JWT against the public key,
+// and checks that JWT has not expired and was not issued in the future,
+// allowing for a small clock skew. Public key MUST be an RSA public key.
+// It can be obtained via [Transport.PublicKeyPEM] or from the signer.
+//
+// If JWT is malformed, its signature is invalid or it has expired, returned
+// error matches [ErrInvalidJWT].
+func VerifyJWT(token string, pub crypto.PublicKey) (JWTClaims, error) {
+	key, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return JWTClaims{}, fmt.Errorf("githubapp(jwt): unsupported key type: %T", pub)
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return JWTClaims{}, fmt.Errorf("%w: malformed JWT has %d parts", ErrInvalidJWT, len(parts))
+	}
+
+	header := api.JWTHeader{}
+	err := decodeJWTSegment(parts[0], &header)
+	if err != nil {
+		return JWTClaims{}, fmt.Errorf("%w: failed to decode JWT header: %w", ErrInvalidJWT, err)
+	}
+
+	if header.Alg != "RS256" {
+		return JWTClaims{}, fmt.Errorf("%w: unsupported algorithm: %q", ErrInvalidJWT, header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return JWTClaims{}, fmt.Errorf("%w: JWT signature is not base64 url encoded: %w", ErrInvalidJWT, err)
+	}
+
+	digest := sha256.Sum256([]byte(token[:len(parts[0])+len(parts[1])+1]))
+	err = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature)
+	if err != nil {
+		return JWTClaims{}, fmt.Errorf("%w: %w", ErrInvalidJWT, err)
+	}
+
+	payload := api.JWTPayload{}
+	err = decodeJWTSegment(parts[1], &payload)
+	if err != nil {
+		return JWTClaims{}, fmt.Errorf("%w: failed to decode JWT payload: %w", ErrInvalidJWT, err)
+	}
+
+	claims := JWTClaims{
+		Issuer:   payload.Issuer,
+		Exp:      time.Unix(payload.Exp, 0),
+		IssuedAt: time.Unix(payload.IssuedAt, 0),
+	}
+	claims.AppID, _ = strconv.ParseUint(payload.Issuer, 10, 64)
+
+	now := time.Now()
+	if !claims.Exp.After(now) {
+		return JWTClaims{}, fmt.Errorf("%w: JWT has expired at %s",
+			ErrInvalidJWT, claims.Exp.Format(time.RFC3339))
+	}
+
+	if claims.IssuedAt.After(now.Add(jwtVerifyLeeway)) {
+		return JWTClaims{}, fmt.Errorf("%w: JWT is issued in the future at %s",
+			ErrInvalidJWT, claims.IssuedAt.Format(time.RFC3339))
+	}
+
+	if !claims.Exp.After(claims.IssuedAt) {
+		return JWTClaims{}, fmt.Errorf("%w: JWT exp claim(%d) is not after iat claim(%d)",
+			ErrInvalidJWT, payload.Exp, payload.IssuedAt)
+	}
+	return claims, nil
+}
+
+// checkSigner verifies that signer can actually sign by signing a dummy digest.
+// This does not make any network calls, except those made by signer itself.
+func checkSigner(ctx context.Context, signer crypto.Signer) error {
+	digest := sha256.Sum256([]byte("github.com/tprasadtp/go-githubapp"))
+
+	var err error
+	if cs, ok := signer.(contextSigner); ok {
+		_, err = cs.SignContext(ctx, rand.Reader, digest[:], crypto.SHA256)
+	} else {
+		_, err = signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrSignerUnusable, err)
+	}
+	return nil
+}
+
 // NewJWT returns new JWT bearer token signed by the signer.
 //
-// Returned JWT is valid for at least 5min. Ensure that your machine's clock is accurate.
+// Returned JWT is valid for 2 minutes, unless configured via [WithJWTExpiry].
+// Ensure that your machine's clock is accurate or use [WithJWTClockSkew].
 //
 //   - Unlike [NewTransport], this does not validate app id and signer. This simply
 //     mints the JWT as required by GitHub app authentication.
 //   - RSA keys of length less than 2048 bits are not supported.
 //   - Only RSA keys are supported. Using ECDSA, ED25519 or other keys will return error.
-func NewJWT(ctx context.Context, appid uint64, signer crypto.Signer) (JWT, error) {
+func NewJWT(ctx context.Context, appid uint64, signer crypto.Signer, opts ...JWTOption) (JWT, error) {
 	if signer == nil {
 		return JWT{}, errors.New("githubapp(jwt): signer cannot be nil")
 	}
@@ -156,13 +335,28 @@ func NewJWT(ctx context.Context, appid uint64, signer crypto.Signer) (JWT, error
 		return JWT{}, errors.New("githubapp(jwt): appid cannot be zero")
 	}
 
+	minter := &jwtRS256{internal: signer}
+	var errs []error
+	for i, opt := range opts {
+		if opt == nil {
+			continue
+		}
+
+		if err := opt.applyJWT(minter); err != nil {
+			errs = append(errs, fmt.Errorf("option[%d]: %w", i, err))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return JWT{}, fmt.Errorf("githubapp(jwt): invalid options: %w", err)
+	}
+
 	switch v := signer.Public().(type) {
 	case *rsa.PublicKey:
 		if v.N.BitLen() < 2048 {
 			return JWT{},
 				fmt.Errorf("githubapp(jwt): rsa keys size(%d) < 2048 bits", v.N.BitLen())
 		}
-		minter := &jwtRS256{internal: signer}
 		return minter.MintJWT(ctx, appid, time.Now())
 	default:
 		return JWT{}, fmt.Errorf("githubapp(jwt): unsupported key type: %T", v)
@@ -11,6 +11,7 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -306,6 +307,107 @@ func TestJWT(t *testing.T) {
 	})
 }
 
+func TestJWTSignerRS256_Expiry(t *testing.T) {
+	tt := []struct {
+		name   string
+		expiry time.Duration
+		skew   time.Duration
+		exp    time.Duration
+		iat    time.Duration
+	}{
+		{
+			name: "defaults",
+			exp:  defaultJWTExpiry,
+			iat:  -defaultJWTSkew,
+		},
+		{
+			name:   "custom",
+			expiry: 8 * time.Minute,
+			skew:   time.Minute,
+			exp:    8 * time.Minute,
+			iat:    -time.Minute,
+		},
+		{
+			name:   "clamped",
+			expiry: time.Hour,
+			exp:    maxJWTExpiry,
+			iat:    -defaultJWTSkew,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			minter := &jwtRS256{internal: testkeys.RSA2048(), expiry: tc.expiry, skew: tc.skew}
+			now := time.Now().Truncate(time.Second)
+			bearer, err := minter.MintJWT(context.Background(), 99, now)
+			if err != nil {
+				t.Fatalf("failed to mint JWT: %s", err)
+			}
+
+			if err = verifyJWTClaims(bearer); err != nil {
+				t.Errorf("expected no error, got %s", err)
+			}
+
+			if v := bearer.Exp.Sub(now); v != tc.exp {
+				t.Errorf("expected exp=now+%s, got=now+%s", tc.exp, v)
+			}
+
+			if v := bearer.IssuedAt.Sub(now); v != tc.iat {
+				t.Errorf("expected iat=now%s, got=now%s", tc.iat, v)
+			}
+		})
+	}
+}
+
+func TestNewJWT_Options(t *testing.T) {
+	tt := []struct {
+		name     string
+		options  []JWTOption
+		lifetime time.Duration
+		ok       bool
+	}{
+		{
+			name:     "defaults",
+			lifetime: defaultJWTExpiry + defaultJWTSkew,
+			ok:       true,
+		},
+		{
+			name:     "nil-options",
+			options:  []JWTOption{nil, nil},
+			lifetime: defaultJWTExpiry + defaultJWTSkew,
+			ok:       true,
+		},
+		{
+			name:     "custom",
+			options:  []JWTOption{WithJWTExpiry(8 * time.Minute), WithJWTClockSkew(time.Minute)},
+			lifetime: 9 * time.Minute,
+			ok:       true,
+		},
+		{
+			name:    "invalid",
+			options: []JWTOption{WithJWTExpiry(time.Second)},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			bearer, err := NewJWT(context.Background(), 99, testkeys.RSA2048(), tc.options...)
+			if !tc.ok {
+				if err == nil {
+					t.Errorf("expected an error, got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error, got %s", err)
+			}
+
+			if v := bearer.Exp.Sub(bearer.IssuedAt); v != tc.lifetime {
+				t.Errorf("expected exp-iat=%s, got=%s", tc.lifetime, v)
+			}
+		})
+	}
+}
+
 func BenchmarkMintJWT(b *testing.B) {
 	key, err := rsa.GenerateKey(rand.Reader, 2048)
 	if err != nil {
@@ -322,3 +424,186 @@ func BenchmarkMintJWT(b *testing.B) {
 	}
 	_ = v
 }
+
+var _ jwtMinter = (*lyingMinter)(nil)
+
+// lyingMinter reports exp which does not match the claims in the token.
+type lyingMinter struct {
+	internal jwtMinter
+}
+
+func (m *lyingMinter) MintJWT(ctx context.Context, iss uint64, now time.Time) (JWT, error) {
+	bearer, err := m.internal.MintJWT(ctx, iss, now)
+	if err != nil {
+		return JWT{}, err
+	}
+	bearer.Exp = bearer.Exp.Add(time.Hour)
+	return bearer, nil
+}
+
+func TestVerifyJWTClaims(t *testing.T) {
+	minter := &jwtRS256{internal: testkeys.RSA2048()}
+	bearer, err := minter.MintJWT(context.Background(), 99, time.Now())
+	if err != nil {
+		t.Fatalf("failed to mint JWT: %s", err)
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		if err := verifyJWTClaims(bearer); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+	})
+
+	t.Run("exp-mismatch", func(t *testing.T) {
+		v := bearer
+		v.Exp = v.Exp.Add(time.Hour)
+		if err := verifyJWTClaims(v); err == nil {
+			t.Errorf("expected an error, got nil")
+		}
+	})
+
+	t.Run("iat-mismatch", func(t *testing.T) {
+		v := bearer
+		v.IssuedAt = v.IssuedAt.Add(-time.Hour)
+		if err := verifyJWTClaims(v); err == nil {
+			t.Errorf("expected an error, got nil")
+		}
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		v := bearer
+		v.Token = "foo.bar"
+		if err := verifyJWTClaims(v); err == nil {
+			t.Errorf("expected an error, got nil")
+		}
+	})
+
+	t.Run("transport-detects-lying-minter", func(t *testing.T) {
+		transport := &Transport{
+			appID:  99,
+			minter: &lyingMinter{internal: minter},
+		}
+		token, err := transport.JWT(context.Background())
+		if err == nil {
+			t.Errorf("expected an error, got nil")
+		}
+
+		if !reflect.DeepEqual(token, JWT{}) {
+			t.Errorf("on error JWT should returns empty jwt")
+		}
+
+		if transport.jwt.Load() != nil {
+			t.Errorf("inconsistent JWT must not be cached")
+		}
+	})
+}
+
+func TestVerifyJWT(t *testing.T) {
+	key := testkeys.RSA2048()
+	now := time.Now()
+	bearer, err := NewJWT(context.Background(), 99, key)
+	if err != nil {
+		t.Fatalf("failed to mint JWT: %s", err)
+	}
+
+	// sign returns JWT with the payload, signed by the key.
+	sign := func(t *testing.T, header string, payload api.JWTPayload) string {
+		t.Helper()
+		data, err := json.Marshal(payload)
+		if err != nil {
+			t.Fatalf("failed to encode payload: %s", err)
+		}
+
+		unsigned := header + "." + base64.RawURLEncoding.EncodeToString(data)
+		digest := sha256.Sum256([]byte(unsigned))
+		signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatalf("failed to sign: %s", err)
+		}
+		return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		claims, err := VerifyJWT(bearer.Token, key.Public())
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		expect := JWTClaims{Issuer: "99", AppID: 99, Exp: bearer.Exp, IssuedAt: bearer.IssuedAt}
+		if !claims.Exp.Equal(expect.Exp) || !claims.IssuedAt.Equal(expect.IssuedAt) ||
+			claims.Issuer != expect.Issuer || claims.AppID != expect.AppID {
+			t.Errorf("expected claims=%#v, got=%#v", expect, claims)
+		}
+	})
+
+	parts := strings.Split(bearer.Token, ".")
+	tt := []struct {
+		name  string
+		token string
+		pub   crypto.PublicKey
+		err   error
+	}{
+		{
+			name:  "unsupported-key",
+			token: bearer.Token,
+			pub:   testkeys.ECP256().Public(),
+		},
+		{
+			name:  "wrong-key",
+			token: bearer.Token,
+			pub:   testkeys.RSA1024().Public(),
+			err:   ErrInvalidJWT,
+		},
+		{
+			name:  "malformed",
+			token: "foo.bar",
+			err:   ErrInvalidJWT,
+		},
+		{
+			name:  "tampered-payload",
+			token: parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"100"}`)) + "." + parts[2],
+			err:   ErrInvalidJWT,
+		},
+		{
+			name: "unsupported-algorithm",
+			token: sign(t, base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`)),
+				api.JWTPayload{Issuer: "99", IssuedAt: now.Unix(), Exp: now.Add(time.Minute).Unix()}),
+			err: ErrInvalidJWT,
+		},
+		{
+			name: "expired",
+			token: sign(t, api.EncodedJWTHeader,
+				api.JWTPayload{Issuer: "99", IssuedAt: now.Add(-time.Hour).Unix(), Exp: now.Add(-time.Minute).Unix()}),
+			err: ErrInvalidJWT,
+		},
+		{
+			name: "issued-in-future",
+			token: sign(t, api.EncodedJWTHeader,
+				api.JWTPayload{Issuer: "99", IssuedAt: now.Add(time.Hour).Unix(), Exp: now.Add(2 * time.Hour).Unix()}),
+			err: ErrInvalidJWT,
+		},
+		{
+			name: "exp-before-iat",
+			token: sign(t, api.EncodedJWTHeader,
+				api.JWTPayload{Issuer: "99", IssuedAt: now.Add(10 * time.Second).Unix(), Exp: now.Add(5 * time.Second).Unix()}),
+			err: ErrInvalidJWT,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			pub := tc.pub
+			if pub == nil {
+				pub = key.Public()
+			}
+
+			_, err := VerifyJWT(tc.token, pub)
+			if err == nil {
+				t.Fatalf("expected an error, got nil")
+			}
+
+			if tc.err != nil && !errors.Is(err, tc.err) {
+				t.Errorf("expected error %s, got %s", tc.err, err)
+			}
+		})
+	}
+}
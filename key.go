@@ -0,0 +1,248 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec // PBKDF2 with HMAC-SHA1 is used by older keys.
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+)
+
+// ErrPrivateKeyPassphrase is returned by [NewSignerFromEncryptedPEM] when the
+// private key is encrypted, but passphrase is not specified. Callers can use it
+// to prompt for the passphrase.
+const ErrPrivateKeyPassphrase = Error("githubapp(key): private key is encrypted, passphrase is required")
+
+// Object identifiers for PKCS#5 v2.0 (PBES2), used by encrypted PKCS#8 keys.
+//
+// https://datatracker.ietf.org/doc/html/rfc8018
+//
+//nolint:gochecknoglobals // ASN.1 object identifiers cannot be constants.
+var (
+	oidPBES2          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+	oidAES128CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+// encryptedPrivateKeyInfo is PKCS#8 EncryptedPrivateKeyInfo.
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+// pbes2Params is PBES2-params.
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+// pbkdf2Params is PBKDF2-params. Salt MUST be an octet string,
+// other sources of salt are not supported.
+type pbkdf2Params struct {
+	Salt       []byte
+	Iterations int
+	KeyLength  int                      `asn1:"optional"`
+	PRF        pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// NewSignerFromEncryptedPEM returns a [crypto.Signer] from PEM encoded RSA
+// private key, which can be used with [NewTransport]. Keys can be encrypted
+// with legacy PEM encryption ('openssl rsa -aes256') or can be encrypted PKCS#8
+// keys ('openssl pkcs8 -topk8 -v2 aes256'). Encrypted PKCS#8 keys only support
+// PBES2 with PBKDF2 and AES-CBC, which is default for OpenSSL 1.1 and later.
+//
+// If key is encrypted and passphrase is empty, [ErrPrivateKeyPassphrase] is
+// returned. Passphrase is ignored if the key is not encrypted.
+func NewSignerFromEncryptedPEM(data []byte, passphrase []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("githubapp(key): no PEM data found")
+	}
+
+	der := block.Bytes
+	switch {
+	case block.Type == "ENCRYPTED PRIVATE KEY":
+		if len(passphrase) == 0 {
+			return nil, ErrPrivateKeyPassphrase
+		}
+
+		var err error
+		der, err = decryptPKCS8(block.Bytes, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("githubapp(key): failed to decrypt private key: %w", err)
+		}
+	//nolint:staticcheck // legacy PEM encryption is insecure, but is still widely used.
+	case x509.IsEncryptedPEMBlock(block):
+		if len(passphrase) == 0 {
+			return nil, ErrPrivateKeyPassphrase
+		}
+
+		var err error
+		//nolint:staticcheck // legacy PEM encryption is insecure, but is still widely used.
+		der, err = x509.DecryptPEMBlock(block, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("githubapp(key): failed to decrypt private key: %w", err)
+		}
+	}
+
+	// Keys can be PKCS#1 or PKCS#8 irrespective of PEM block type,
+	// as decrypted PKCS#8 keys do not have a PEM block type.
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("githubapp(key): invalid private key: %w", err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("githubapp(key): unsupported private key type: %T", key)
+	}
+	return signer, nil
+}
+
+// decryptPKCS8 decrypts PKCS#8 EncryptedPrivateKeyInfo encrypted with PBES2.
+func decryptPKCS8(der []byte, passphrase []byte) ([]byte, error) {
+	var info encryptedPrivateKeyInfo
+	rest, err := asn1.Unmarshal(der, &info)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encrypted private key info: %w", err)
+	}
+
+	if len(rest) != 0 {
+		return nil, errors.New("invalid encrypted private key info: trailing data")
+	}
+
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("unsupported encryption algorithm: %s", info.Algorithm.Algorithm)
+	}
+
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, fmt.Errorf("invalid PBES2 parameters: %w", err)
+	}
+
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, fmt.Errorf("unsupported key derivation function: %s", params.KeyDerivationFunc.Algorithm)
+	}
+
+	var kdf pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		return nil, fmt.Errorf("invalid PBKDF2 parameters: %w", err)
+	}
+
+	if kdf.Iterations <= 0 {
+		return nil, fmt.Errorf("invalid PBKDF2 iteration count: %d", kdf.Iterations)
+	}
+
+	// PRF defaults to HMAC-SHA1 if not specified.
+	var prf func() hash.Hash
+	switch {
+	case len(kdf.PRF.Algorithm) == 0, kdf.PRF.Algorithm.Equal(oidHMACWithSHA1):
+		prf = sha1.New
+	case kdf.PRF.Algorithm.Equal(oidHMACWithSHA256):
+		prf = sha256.New
+	case kdf.PRF.Algorithm.Equal(oidHMACWithSHA512):
+		prf = sha512.New
+	default:
+		return nil, fmt.Errorf("unsupported PBKDF2 PRF: %s", kdf.PRF.Algorithm)
+	}
+
+	var keyLen int
+	switch {
+	case params.EncryptionScheme.Algorithm.Equal(oidAES128CBC):
+		keyLen = 16
+	case params.EncryptionScheme.Algorithm.Equal(oidAES192CBC):
+		keyLen = 24
+	case params.EncryptionScheme.Algorithm.Equal(oidAES256CBC):
+		keyLen = 32
+	default:
+		return nil, fmt.Errorf("unsupported encryption scheme: %s", params.EncryptionScheme.Algorithm)
+	}
+
+	if kdf.KeyLength != 0 && kdf.KeyLength != keyLen {
+		return nil, fmt.Errorf("invalid PBKDF2 key length: %d", kdf.KeyLength)
+	}
+
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, fmt.Errorf("invalid encryption scheme parameters: %w", err)
+	}
+
+	if len(iv) != aes.BlockSize {
+		return nil, fmt.Errorf("invalid IV length: %d", len(iv))
+	}
+
+	if len(info.EncryptedData) == 0 || len(info.EncryptedData)%aes.BlockSize != 0 {
+		return nil, errors.New("encrypted data is not a multiple of block size")
+	}
+
+	block, err := aes.NewCipher(pbkdf2(prf, passphrase, kdf.Salt, kdf.Iterations, keyLen))
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, len(info.EncryptedData))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, info.EncryptedData)
+
+	// Remove PKCS#7 padding. Invalid padding usually indicates
+	// incorrect passphrase.
+	pad := int(out[len(out)-1])
+	if pad == 0 || pad > aes.BlockSize || !bytes.Equal(out[len(out)-pad:], bytes.Repeat([]byte{byte(pad)}, pad)) {
+		return nil, x509.IncorrectPasswordError
+	}
+	return out[:len(out)-pad], nil
+}
+
+// pbkdf2 derives a key from the password as per RFC 8018 section 5.2.
+func pbkdf2(prf func() hash.Hash, password, salt []byte, iterations, keyLen int) []byte {
+	mac := hmac.New(prf, password)
+	size := mac.Size()
+	blocks := (keyLen + size - 1) / size
+
+	var buf [4]byte
+	dk := make([]byte, 0, blocks*size)
+	u := make([]byte, size)
+	for i := 1; i <= blocks; i++ {
+		// U_1 = PRF(P, S || INT(i))
+		mac.Reset()
+		mac.Write(salt)
+		binary.BigEndian.PutUint32(buf[:], uint32(i))
+		mac.Write(buf[:])
+		dk = mac.Sum(dk)
+		t := dk[len(dk)-size:]
+		copy(u, t)
+
+		// U_n = PRF(P, U_{n-1}), T_i = U_1 ^ U_2 ^ ... ^ U_c
+		for n := 2; n <= iterations; n++ {
+			mac.Reset()
+			mac.Write(u)
+			u = mac.Sum(u[:0])
+			for x := range u {
+				t[x] ^= u[x]
+			}
+		}
+	}
+	return dk[:keyLen]
+}
@@ -0,0 +1,197 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1" //nolint:gosec // RFC 6070 test vectors.
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"testing"
+
+	"github.com/tprasadtp/go-githubapp/internal/testkeys"
+)
+
+// encryptTestPKCS8 encrypts PKCS#8 private key with PBES2 using
+// PBKDF2 with HMAC-SHA256 and AES-256-CBC.
+func encryptTestPKCS8(t *testing.T, der, passphrase []byte) []byte {
+	t.Helper()
+
+	salt := make([]byte, 8)
+	iv := make([]byte, aes.BlockSize)
+	_, _ = rand.Read(salt)
+	_, _ = rand.Read(iv)
+
+	block, err := aes.NewCipher(pbkdf2(sha256.New, passphrase, salt, 2048, 32))
+	if err != nil {
+		t.Fatalf("failed to create cipher: %s", err)
+	}
+
+	pad := aes.BlockSize - len(der)%aes.BlockSize
+	data := append(bytes.Clone(der), bytes.Repeat([]byte{byte(pad)}, pad)...)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(data, data)
+
+	marshal := func(v any) asn1.RawValue {
+		buf, err := asn1.Marshal(v)
+		if err != nil {
+			t.Fatalf("failed to marshal: %s", err)
+		}
+		return asn1.RawValue{FullBytes: buf}
+	}
+
+	info := encryptedPrivateKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{
+			Algorithm: oidPBES2,
+			Parameters: marshal(pbes2Params{
+				KeyDerivationFunc: pkix.AlgorithmIdentifier{
+					Algorithm: oidPBKDF2,
+					Parameters: marshal(pbkdf2Params{
+						Salt:       salt,
+						Iterations: 2048,
+						PRF: pkix.AlgorithmIdentifier{
+							Algorithm:  oidHMACWithSHA256,
+							Parameters: asn1.NullRawValue,
+						},
+					}),
+				},
+				EncryptionScheme: pkix.AlgorithmIdentifier{
+					Algorithm:  oidAES256CBC,
+					Parameters: marshal(iv),
+				},
+			}),
+		},
+		EncryptedData: data,
+	}
+
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  "ENCRYPTED PRIVATE KEY",
+		Bytes: marshal(info).FullBytes,
+	})
+}
+
+func TestPBKDF2(t *testing.T) {
+	// https://datatracker.ietf.org/doc/html/rfc6070
+	tt := []struct {
+		iterations int
+		expect     string
+	}{
+		{iterations: 1, expect: "0c60c80f961f0e71f3a9b524af6012062fe037a6"},
+		{iterations: 2, expect: "ea6c014dc72d6f8ccd1ed92ace1d41f0d8de8957"},
+		{iterations: 4096, expect: "4b007901b765489abead49d926f721d065a429c1"},
+	}
+	for _, tc := range tt {
+		v := hex.EncodeToString(pbkdf2(sha1.New, []byte("password"), []byte("salt"), tc.iterations, 20))
+		if v != tc.expect {
+			t.Errorf("iterations=%d: expected=%s, got=%s", tc.iterations, tc.expect, v)
+		}
+	}
+}
+
+func TestNewSignerFromEncryptedPEM(t *testing.T) {
+	key := testkeys.RSA2048()
+	passphrase := []byte("It's a Secret to Everybody")
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %s", err)
+	}
+
+	//nolint:staticcheck // legacy PEM encryption is tested.
+	legacy, err := x509.EncryptPEMBlock(rand.Reader, "RSA PRIVATE KEY",
+		x509.MarshalPKCS1PrivateKey(key), passphrase, x509.PEMCipherAES256)
+	if err != nil {
+		t.Fatalf("failed to encrypt key: %s", err)
+	}
+
+	tt := []struct {
+		name       string
+		pem        []byte
+		passphrase []byte
+		err        error
+		ok         bool
+	}{
+		{
+			name: "pkcs1",
+			pem: pem.EncodeToMemory(&pem.Block{
+				Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key),
+			}),
+			ok: true,
+		},
+		{
+			name:       "pkcs8-passphrase-ignored",
+			pem:        pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}),
+			passphrase: passphrase,
+			ok:         true,
+		},
+		{
+			name:       "legacy-encrypted",
+			pem:        pem.EncodeToMemory(legacy),
+			passphrase: passphrase,
+			ok:         true,
+		},
+		{
+			name: "legacy-encrypted-no-passphrase",
+			pem:  pem.EncodeToMemory(legacy),
+			err:  ErrPrivateKeyPassphrase,
+		},
+		{
+			name:       "legacy-encrypted-invalid-passphrase",
+			pem:        pem.EncodeToMemory(legacy),
+			passphrase: []byte("invalid"),
+		},
+		{
+			name:       "pkcs8-encrypted",
+			pem:        encryptTestPKCS8(t, pkcs8, passphrase),
+			passphrase: passphrase,
+			ok:         true,
+		},
+		{
+			name: "pkcs8-encrypted-no-passphrase",
+			pem:  encryptTestPKCS8(t, pkcs8, passphrase),
+			err:  ErrPrivateKeyPassphrase,
+		},
+		{
+			name:       "pkcs8-encrypted-invalid-passphrase",
+			pem:        encryptTestPKCS8(t, pkcs8, passphrase),
+			passphrase: []byte("invalid"),
+		},
+		{
+			name: "not-pem",
+			pem:  []byte("not a pem"),
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			signer, err := NewSignerFromEncryptedPEM(tc.pem, tc.passphrase)
+			if tc.ok {
+				if err != nil {
+					t.Fatalf("expected no error, got %s", err)
+				}
+
+				v, ok := signer.(*rsa.PrivateKey)
+				if !ok || !v.Equal(key) {
+					t.Errorf("expected signer to be the same as test key")
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("expected an error, got nil")
+			}
+
+			if tc.err != nil && !errors.Is(err, tc.err) {
+				t.Errorf("expected error %s, got %s", tc.err, err)
+			}
+		})
+	}
+}
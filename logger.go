@@ -0,0 +1,53 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"context"
+	"log/slog"
+)
+
+// WithLogger configures [Transport] to emit debug level events for token
+// lifecycle like minting JWT, minting installation access token, token cache hits
+// and renewals of expired tokens. Tokens are always redacted from the logs.
+// Errors which cannot be returned to the caller, like panics in token refresh
+// callbacks are logged at warning level.
+//
+// When not specified or nil, nothing is logged.
+func WithLogger(logger *slog.Logger) Option {
+	if logger == nil {
+		return nil
+	}
+	return &funcOption{
+		name: "WithLogger",
+		f: func(t *Transport) error {
+			t.logger = logger
+			return nil
+		},
+	}
+}
+
+// debug emits debug level event if logger is configured.
+func (t *Transport) debug(ctx context.Context, msg string, attrs ...slog.Attr) {
+	if t.logger == nil {
+		return
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	t.logger.LogAttrs(ctx, slog.LevelDebug, msg, attrs...)
+}
+
+// warn emits warning level event if logger is configured.
+func (t *Transport) warn(ctx context.Context, msg string, attrs ...slog.Attr) {
+	if t.logger == nil {
+		return
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	t.logger.LogAttrs(ctx, slog.LevelWarn, msg, attrs...)
+}
@@ -0,0 +1,121 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tprasadtp/go-githubapp/internal/api"
+	"github.com/tprasadtp/go-githubapp/internal/testkeys"
+)
+
+func TestWithLogger(t *testing.T) {
+	t.Run("nil", func(t *testing.T) {
+		if WithLogger(nil) != nil {
+			t.Errorf("WithLogger with nil logger must return nil")
+		}
+	})
+
+	t.Run("non-nil", func(t *testing.T) {
+		transport := Transport{}
+		err := Options(WithLogger(slog.Default())).apply(&transport)
+		if err != nil {
+			t.Errorf("expected no error, got %s", err)
+		}
+
+		if transport.logger == nil {
+			t.Errorf("transport.logger should be non nil")
+		}
+	})
+}
+
+func TestTransport_Logger(t *testing.T) {
+	const token = "ghs_5b91a4a3-unique-token-value"
+	var buf bytes.Buffer
+	var jwts []string
+
+	u, _ := url.Parse("https://api.go-githubapp.test/")
+	cache := NewMemoryCache()
+	transport := &Transport{
+		appID:     99,
+		installID: 99,
+		baseURL:   u,
+		cache:     cache,
+		logger: slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+			Level: slog.LevelDebug,
+		})),
+		minter: &jwtRS256{internal: testkeys.RSA2048()},
+		next: api.RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+			resp := httptest.NewRecorder()
+			if strings.HasSuffix(r.URL.Path, "/access_tokens") {
+				jwts = append(jwts, strings.TrimPrefix(r.Header.Get(api.AuthzHeader), "Bearer "))
+				resp.WriteHeader(http.StatusCreated)
+				_, _ = resp.WriteString(`{"token":"` + token + `","expires_at":"2099-01-01T00:00:00Z"}`)
+				return resp.Result(), nil
+			}
+			resp.WriteHeader(http.StatusOK)
+			return resp.Result(), nil
+		}),
+	}
+
+	doRequest := func(t *testing.T) {
+		t.Helper()
+		r, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, u.JoinPath("repos").String(), nil)
+		resp, err := transport.RoundTrip(r)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		resp.Body.Close()
+	}
+
+	// Mint JWT and installation token.
+	doRequest(t)
+
+	// Expire cached token, which results in a renewal and a cache hit.
+	transport.token.Store(InstallationToken{Token: token, Exp: time.Now().Add(-time.Minute)})
+	doRequest(t)
+
+	// Expire cached JWT, which triggers renewal.
+	transport.jwt.Store(JWT{Token: "expired", Exp: time.Now().Add(-time.Minute)})
+	_, err := transport.JWT(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	logs := buf.String()
+	t.Logf("logs:\n%s", logs)
+	for _, item := range []string{
+		"githubapp: minted JWT",
+		"githubapp: minted installation token",
+		"githubapp: renewing expired installation token",
+		"githubapp: renewing expired JWT",
+		"githubapp: installation token cache hit",
+	} {
+		if !strings.Contains(logs, item) {
+			t.Errorf("logs do not contain %q", item)
+		}
+	}
+
+	if strings.Contains(logs, token) {
+		t.Errorf("logs must not contain installation token")
+	}
+
+	if len(jwts) == 0 {
+		t.Fatalf("no JWTs were used")
+	}
+
+	for _, item := range jwts {
+		if strings.Contains(logs, item) {
+			t.Errorf("logs must not contain JWT")
+		}
+	}
+}
@@ -0,0 +1,178 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/tprasadtp/go-githubapp/internal/api"
+)
+
+var (
+	_ slog.LogValuer = (*ManifestConversion)(nil)
+)
+
+// ManifestConversion is returned by [ConvertManifest], and contains
+// credentials of the app created via the app manifest flow.
+//
+// PEM, ClientSecret and WebhookSecret are only returned once by GitHub API,
+// thus they MUST be persisted securely by the caller.
+type ManifestConversion struct {
+	// GitHub app ID.
+	AppID uint64 `json:"app_id,omitempty" yaml:"appID,omitempty"`
+
+	// GitHub app slug/name used in URLs.
+	AppSlug string `json:"app_slug,omitempty" yaml:"appSlug,omitempty"`
+
+	// GitHub app display name.
+	AppName string `json:"app_name,omitempty" yaml:"appName,omitempty"`
+
+	// Owner is login of the account which owns the app.
+	Owner string `json:"owner,omitempty" yaml:"owner,omitempty"`
+
+	// HTMLURL is URL of the app on GitHub.
+	HTMLURL string `json:"html_url,omitempty" yaml:"htmlURL,omitempty"`
+
+	// ClientID is OAuth client ID of the app.
+	ClientID string `json:"client_id,omitempty" yaml:"clientID,omitempty"`
+
+	// ClientSecret is OAuth client secret of the app.
+	ClientSecret string `json:"client_secret,omitempty" yaml:"clientSecret,omitempty"`
+
+	// WebhookSecret is secret used to sign webhooks, if any.
+	WebhookSecret string `json:"webhook_secret,omitempty" yaml:"webhookSecret,omitempty"`
+
+	// PEM is PEM encoded private key of the app.
+	PEM string `json:"pem,omitempty" yaml:"pem,omitempty"`
+}
+
+// LogValue implements [log/slog.LogValuer].
+func (m *ManifestConversion) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Uint64("app_id", m.AppID),
+		slog.String("app_slug", m.AppSlug),
+		slog.String("app_name", m.AppName),
+		slog.String("owner", m.Owner),
+		slog.String("client_id", m.ClientID),
+		slog.String("client_secret", "REDACTED"),
+		slog.String("webhook_secret", "REDACTED"),
+		slog.String("pem", "REDACTED"),
+	)
+}
+
+// ConvertManifest completes the GitHub app manifest flow by exchanging the temporary
+// code for the app credentials. Code is only valid for an hour and can only be
+// used once. This does not require any authentication other than the code.
+//
+// Endpoint is REST API(v3) endpoint, same as [WithEndpoint]. When empty,
+// "https://api.github.com/" is used.
+//
+// https://docs.github.com/en/apps/sharing-github-apps/registering-a-github-app-from-a-manifest
+func ConvertManifest(ctx context.Context, endpoint, code string) (ManifestConversion, error) {
+	return convertManifest(ctx, endpoint, code, nil)
+}
+
+// convertManifest is an internal version of ConvertManifest, which supports
+// custom round tripper for testing and customization.
+func convertManifest(ctx context.Context, endpoint, code string, rt http.RoundTripper) (ManifestConversion, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if code == "" {
+		return ManifestConversion{}, errors.New("githubapp(manifest): code is empty")
+	}
+
+	// Code is used as a path segment, ensure it cannot modify the path.
+	if url.PathEscape(code) != code || code == "." || code == ".." {
+		return ManifestConversion{}, fmt.Errorf("githubapp(manifest): invalid code: %q", code)
+	}
+
+	// Re-use endpoint validation from WithEndpoint.
+	t := &Transport{}
+	if opt := WithEndpoint(endpoint); opt != nil {
+		if err := opt.apply(t); err != nil {
+			return ManifestConversion{}, fmt.Errorf("githubapp(manifest): %w", err)
+		}
+	} else {
+		t.baseURL, _ = url.Parse(api.DefaultEndpoint)
+	}
+
+	u := t.baseURL.JoinPath("app-manifests", code, "conversions")
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), nil)
+	if err != nil {
+		return ManifestConversion{},
+			fmt.Errorf("githubapp(manifest): failed to build request: %w", err)
+	}
+
+	r.Header.Set(api.AcceptHeader, api.AcceptHeaderValue)
+	r.Header.Set(api.VersionHeader, api.VersionHeaderValue)
+	r.Header.Set(api.UAHeader, api.UAHeaderValue)
+
+	client := newInternalClient(rt)
+	resp, err := client.Do(r)
+	if err != nil {
+		return ManifestConversion{},
+			fmt.Errorf("githubapp(manifest): failed to convert manifest: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return ManifestConversion{},
+			fmt.Errorf("githubapp(manifest): failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusCreated {
+		return ManifestConversion{},
+			fmt.Errorf("githubapp(manifest): failed to convert manifest: %w", newAPIError(resp, data))
+	}
+
+	conversion := api.ManifestConversion{}
+	err = json.Unmarshal(data, &conversion)
+	if err != nil {
+		return ManifestConversion{},
+			fmt.Errorf("githubapp(manifest): failed to unmarshal response: %w", err)
+	}
+
+	if conversion.ID == nil || conversion.PEM == nil {
+		return ManifestConversion{},
+			errors.New("githubapp(manifest): response is missing app id or private key")
+	}
+
+	m := ManifestConversion{
+		AppID: uint64(*conversion.ID),
+		PEM:   *conversion.PEM,
+	}
+
+	if conversion.Slug != nil {
+		m.AppSlug = *conversion.Slug
+	}
+	if conversion.Name != nil {
+		m.AppName = *conversion.Name
+	}
+	if conversion.Owner != nil && conversion.Owner.Login != nil {
+		m.Owner = *conversion.Owner.Login
+	}
+	if conversion.HTMLURL != nil {
+		m.HTMLURL = *conversion.HTMLURL
+	}
+	if conversion.ClientID != nil {
+		m.ClientID = *conversion.ClientID
+	}
+	if conversion.ClientSecret != nil {
+		m.ClientSecret = *conversion.ClientSecret
+	}
+	if conversion.WebhookSecret != nil {
+		m.WebhookSecret = *conversion.WebhookSecret
+	}
+	return m, nil
+}
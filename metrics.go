@@ -0,0 +1,72 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"time"
+)
+
+// Token kinds reported to [Recorder].
+const (
+	TokenKindJWT          = "jwt"
+	TokenKindInstallation = "installation"
+)
+
+// Recorder records metrics for minting tokens. This can be used to adapt
+// metrics to Prometheus, OpenTelemetry or any other metrics library.
+//
+// Implementations MUST be safe for concurrent use and SHOULD NOT block.
+type Recorder interface {
+	// ObserveTokenMint is called after every attempt to mint a token
+	// with kind of the token (one of [TokenKindJWT] or [TokenKindInstallation]),
+	// time taken and error if any. Cached tokens are not reported.
+	ObserveTokenMint(kind string, d time.Duration, err error)
+}
+
+// WithMetrics configures [Transport] to report metrics for minting
+// JWTs and installation access tokens to recorder. This includes tokens
+// minted by [NewTransport] to verify the app and installation.
+//
+// When not specified or nil, no metrics are recorded.
+func WithMetrics(recorder Recorder) Option {
+	if recorder == nil {
+		return nil
+	}
+	return &funcOption{
+		name: "WithMetrics",
+		f: func(t *Transport) error {
+			t.metrics = recorder
+			return nil
+		},
+	}
+}
+
+// observeTokenMint reports token minted at start to metrics recorder if configured.
+func (t *Transport) observeTokenMint(kind string, start time.Time, err error) {
+	if t.metrics == nil {
+		return
+	}
+	t.metrics.ObserveTokenMint(kind, time.Since(start), err)
+}
+
+// CredentialExpiry returns expiry of the cached JWT and installation access token.
+// Zero value is returned for tokens which are not cached yet. This does not make
+// any API requests and can be used by metrics collectors to report time until
+// credentials expire, for example as a gauge.
+func (t *Transport) CredentialExpiry() (jwtExp, installExp time.Time) {
+	// Installation transports created via App share the JWT.
+	app := t
+	if t.app != nil {
+		app = t.app
+	}
+
+	if v, ok := app.jwt.Load().(JWT); ok {
+		jwtExp = v.Exp
+	}
+
+	if v, ok := t.token.Load().(InstallationToken); ok {
+		installExp = v.Exp
+	}
+	return jwtExp, installExp
+}
@@ -0,0 +1,195 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tprasadtp/go-githubapp/internal/api"
+	"github.com/tprasadtp/go-githubapp/internal/testkeys"
+)
+
+var (
+	_ Recorder = (*testRecorder)(nil)
+)
+
+type testObservation struct {
+	kind string
+	err  error
+}
+
+// testRecorder records all observations.
+type testRecorder struct {
+	mu           sync.Mutex
+	observations []testObservation
+}
+
+func (r *testRecorder) ObserveTokenMint(kind string, _ time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.observations = append(r.observations, testObservation{kind: kind, err: err})
+}
+
+func (r *testRecorder) count(kind string, failed bool) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var n int
+	for _, item := range r.observations {
+		if item.kind == kind && (item.err != nil) == failed {
+			n++
+		}
+	}
+	return n
+}
+
+func TestWithMetrics(t *testing.T) {
+	t.Run("nil", func(t *testing.T) {
+		if WithMetrics(nil) != nil {
+			t.Errorf("WithMetrics with nil recorder must return nil")
+		}
+	})
+
+	t.Run("non-nil", func(t *testing.T) {
+		transport := Transport{}
+		err := Options(WithMetrics(&testRecorder{})).apply(&transport)
+		if err != nil {
+			t.Errorf("expected no error, got %s", err)
+		}
+
+		if transport.metrics == nil {
+			t.Errorf("transport.metrics should be non nil")
+		}
+	})
+}
+
+func TestTransport_Metrics(t *testing.T) {
+	u, _ := url.Parse("https://api.go-githubapp.test/")
+	next := api.RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+		resp := httptest.NewRecorder()
+		if strings.HasSuffix(r.URL.Path, "/access_tokens") {
+			resp.WriteHeader(http.StatusCreated)
+			_, _ = resp.WriteString(`{"token":"ghs_token","expires_at":"2099-01-01T00:00:00Z"}`)
+			return resp.Result(), nil
+		}
+		resp.WriteHeader(http.StatusOK)
+		return resp.Result(), nil
+	})
+
+	t.Run("cached-tokens-are-not-observed", func(t *testing.T) {
+		recorder := &testRecorder{}
+		transport := &Transport{
+			appID:     99,
+			installID: 99,
+			baseURL:   u,
+			minter:    &jwtRS256{internal: testkeys.RSA2048()},
+			next:      next,
+			metrics:   recorder,
+		}
+
+		for i := 0; i < 3; i++ {
+			r, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, u.JoinPath("repos").String(), nil)
+			resp, err := transport.RoundTrip(r)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			resp.Body.Close()
+		}
+
+		if v := recorder.count(TokenKindJWT, false); v != 1 {
+			t.Errorf("expected 1 JWT mint, got %d", v)
+		}
+
+		if v := recorder.count(TokenKindInstallation, false); v != 1 {
+			t.Errorf("expected 1 installation token mint, got %d", v)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		recorder := &testRecorder{}
+		transport := &Transport{
+			appID:     99,
+			installID: 99,
+			baseURL:   u,
+			minter:    &jwtRS256{internal: &errSigner{signer: testkeys.RSA2048()}},
+			next:      next,
+			metrics:   recorder,
+		}
+
+		_, err := transport.InstallationToken(context.Background())
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expected error to wrap os.ErrNotExist, got %v", err)
+		}
+
+		if v := recorder.count(TokenKindJWT, true); v != 1 {
+			t.Errorf("expected 1 failed JWT mint, got %d", v)
+		}
+
+		if v := recorder.count(TokenKindInstallation, true); v != 1 {
+			t.Errorf("expected 1 failed installation token mint, got %d", v)
+		}
+	})
+}
+
+func TestTransport_CredentialExpiry(t *testing.T) {
+	u, _ := url.Parse("https://api.go-githubapp.test/")
+	transport := &Transport{
+		appID:     99,
+		installID: 99,
+		baseURL:   u,
+		minter:    &jwtRS256{internal: testkeys.RSA2048()},
+		next: api.RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+			resp := httptest.NewRecorder()
+			if strings.HasSuffix(r.URL.Path, "/access_tokens") {
+				resp.WriteHeader(http.StatusCreated)
+				_, _ = resp.WriteString(`{"token":"ghs_token","expires_at":"2099-01-01T00:00:00Z"}`)
+				return resp.Result(), nil
+			}
+			resp.WriteHeader(http.StatusOK)
+			return resp.Result(), nil
+		}),
+	}
+
+	jwtExp, installExp := transport.CredentialExpiry()
+	if !jwtExp.IsZero() || !installExp.IsZero() {
+		t.Errorf("expected zero expiry before minting tokens, got jwt=%s, installation=%s", jwtExp, installExp)
+	}
+
+	r, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, u.JoinPath("repos").String(), nil)
+	resp, err := transport.RoundTrip(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	resp.Body.Close()
+
+	jwt, _ := transport.jwt.Load().(JWT)
+	token, _ := transport.token.Load().(InstallationToken)
+	jwtExp, installExp = transport.CredentialExpiry()
+	if jwtExp.IsZero() || !jwtExp.Equal(jwt.Exp) {
+		t.Errorf("expected jwt expiry=%s, got=%s", jwt.Exp, jwtExp)
+	}
+
+	if installExp.Year() != 2099 || !installExp.Equal(token.Exp) {
+		t.Errorf("expected installation token expiry=%s, got=%s", token.Exp, installExp)
+	}
+
+	t.Run("app-transport", func(t *testing.T) {
+		installation := &Transport{app: transport}
+		jwtExp, installExp := installation.CredentialExpiry()
+		if !jwtExp.Equal(jwt.Exp) {
+			t.Errorf("expected jwt expiry of the app=%s, got=%s", jwt.Exp, jwtExp)
+		}
+		if !installExp.IsZero() {
+			t.Errorf("expected zero installation token expiry, got=%s", installExp)
+		}
+	})
+}
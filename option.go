@@ -4,13 +4,19 @@
 package githubapp
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"net/http"
 	"net/url"
 	"regexp"
 	"slices"
 	"strings"
+	"time"
+	"unicode"
+
+	"github.com/tprasadtp/go-githubapp/internal/api"
 )
 
 // Options takes a variadic slice of [Options] and returns
@@ -19,6 +25,9 @@ import (
 // are specified, last-specified wins. As a special case,
 // if no options are specified or all specified options are nil,
 // this will returns nil.
+//
+// Errors from all options are aggregated, and include index and
+// name of the option. Identical errors are only reported once.
 func Options(options ...Option) Option {
 	nils := 0
 	for i := range options {
@@ -31,23 +40,40 @@ func Options(options ...Option) Option {
 	}
 
 	return &funcOption{
+		name: "Options",
 		f: func(t *Transport) error {
-			var err error
+			var errs []error
+			seen := make(map[string]struct{})
 			for i := range options {
 				// Not all platforms support all options,
 				// on unsupported platform option function may
 				// return nil.
-				if options[i] != nil {
-					err = errors.Join(options[i].apply(t))
+				if options[i] == nil {
+					continue
 				}
+
+				err := options[i].apply(t)
+				if err == nil {
+					continue
+				}
+
+				// Same option might be specified multiple times,
+				// only report the first occurrence of an error.
+				if _, ok := seen[err.Error()]; ok {
+					continue
+				}
+				seen[err.Error()] = struct{}{}
+				errs = append(errs, fmt.Errorf("option[%d]: %w", i, err))
 			}
-			return err
+			return errors.Join(errs...)
 		},
 	}
 }
 
-// Option is option to apply for [Transport].
+// Option is option to apply for [Transport]. String returns name of
+// the option like "WithRepositories", which is useful for debugging.
 type Option interface {
+	fmt.Stringer
 	apply(t *Transport) error
 }
 
@@ -55,17 +81,70 @@ type Option interface {
 // during its initial configuration. It implements [Option]
 // interface.
 type funcOption struct {
-	f func(*Transport) error
+	name string
+	f    func(*Transport) error
+}
+
+// String returns name of the option.
+func (opt *funcOption) String() string {
+	if opt.name == "" {
+		return "Option"
+	}
+	return opt.name
 }
 
+// apply applies the option. Errors are prefixed with name of the option.
 func (opt *funcOption) apply(t *Transport) error {
-	return opt.f(t)
+	err := opt.f(t)
+	if err != nil && opt.name != "" {
+		return fmt.Errorf("%s: %w", opt.name, err)
+	}
+	return err
+}
+
+// JWTOption is option to configure JWTs minted by [NewJWT]. JWT options
+// are also [Option], which configure JWTs minted by [Transport].
+type JWTOption interface {
+	Option
+	applyJWT(m *jwtRS256) error
+}
+
+// jwtOption wraps a function applied to JWT minter. It implements
+// both [Option] and [JWTOption] interfaces.
+type jwtOption struct {
+	name string
+	f    func(*jwtRS256) error
+}
+
+// String returns name of the option.
+func (opt *jwtOption) String() string {
+	return opt.name
+}
+
+// apply applies the option to JWTs minted by the Transport.
+func (opt *jwtOption) apply(t *Transport) error {
+	m := jwtRS256{expiry: t.jwtExpiry, skew: t.jwtSkew}
+	err := opt.applyJWT(&m)
+	if err != nil {
+		return err
+	}
+	t.jwtExpiry, t.jwtSkew = m.expiry, m.skew
+	return nil
+}
+
+// applyJWT applies the option. Errors are prefixed with name of the option.
+func (opt *jwtOption) applyJWT(m *jwtRS256) error {
+	err := opt.f(m)
+	if err != nil {
+		return fmt.Errorf("%s: %w", opt.name, err)
+	}
+	return nil
 }
 
 var (
 	repoNameRegExp  = regexp.MustCompile("^(((.)[a-z-0-9-.]+)|([a-z0-9-]([a-z0-9-.]+)?))$")
 	userNameRegExp  = regexp.MustCompile("^([a-z0-9]([a-z0-9-]+)?)$")
-	permissionRegEx = regexp.MustCompile("^[a-z]([a-z_]+[a-z])?[:|=](read|write|admin)$")
+	permissionRegEx = regexp.MustCompile("^[a-z]([a-z_]+[a-z])?[:|=](none|read|write|admin)$")
 )
 
 // WithEndpoint configures [Transport] to use custom REST API(v3) endpoint.
@@ -73,12 +152,18 @@ var (
 // installation access tokens. This MUST be REST(v3) endpoint even though
 // a client might be using GitHub GraphQL API.
 //
+// Endpoint is used as is and its path is never modified. For GitHub Enterprise
+// Server, REST API(v3) endpoint is "https://<host>/api/v3/" and not
+// "https://<host>/". Use [WithEnterpriseHost], which derives the correct endpoint
+// from the host, and does not add "/api/v3" if it is already present.
+//
 // When not specified or empty, "https://api.github.com/" is used.
 func WithEndpoint(endpoint string) Option {
 	if endpoint == "" {
 		return nil
 	}
 	return &funcOption{
+		name: "WithEndpoint",
 		f: func(t *Transport) error {
 			u, err := url.Parse(endpoint)
 			if err != nil {
@@ -95,20 +180,105 @@ func WithEndpoint(endpoint string) Option {
 			}
 
 			t.baseURL = u
+			t.graphqlURL = nil
+			t.uploadURL = nil
 			return nil
 		},
 	}
 }
 
+// WithEnterpriseHost configures [Transport] to use GitHub Enterprise Server
+// running at host. Host can be a hostname (optionally with port) or a https URL.
+// REST API(v3) endpoint "https://<host>/api/v3/", GraphQL endpoint
+// "https://<host>/api/graphql" and upload endpoint "https://<host>/api/uploads/"
+// are derived from the host. This is preferable to using [WithEndpoint] for
+// GitHub Enterprise Server.
+//
+// github.com is not a GitHub Enterprise Server, thus it is rejected. Do not
+// specify this option to use github.com.
+func WithEnterpriseHost(host string) Option {
+	return &funcOption{
+		name: "WithEnterpriseHost",
+		f: func(t *Transport) error {
+			v := strings.TrimSpace(host)
+			if !strings.Contains(v, "://") {
+				v = "https://" + v
+			}
+
+			u, err := url.Parse(v)
+			if err != nil {
+				return fmt.Errorf("invalid enterprise host: %w", err)
+			}
+
+			if u.Scheme != "https" {
+				return fmt.Errorf("invalid enterprise host scheme(must be https): %s", host)
+			}
+
+			switch strings.TrimSuffix(u.Path, "/") {
+			case "", "/api/v3":
+			default:
+				return fmt.Errorf("enterprise host cannot have a path: %s", host)
+			}
+
+			if u.Hostname() == "" || u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+				return fmt.Errorf("invalid enterprise host: %s", host)
+			}
+
+			name := strings.ToLower(u.Hostname())
+			if name == "github.com" || strings.HasSuffix(name, ".github.com") {
+				return fmt.Errorf("enterprise host cannot be github.com: %s", host)
+			}
+
+			base := &url.URL{Scheme: u.Scheme, Host: strings.ToLower(u.Host), Path: "/"}
+			t.baseURL = base.JoinPath("api", "v3/")
+			t.graphqlURL = base.JoinPath("api", "graphql")
+			t.uploadURL = base.JoinPath("api", "uploads/")
+			return nil
+		},
+	}
+}
+
+// WithAdditionalHosts configures [Transport] to authenticate requests to hosts
+// specified, in addition to the host of the endpoint. This is useful when GraphQL
+// API is served from a different host than the REST API, or to upload release
+// assets via "uploads.github.com" using the same client. Hosts MUST NOT include
+// scheme or path, but may include port. Hosts are compared case-insensitively,
+// ignoring default ports.
+//
+// By default, [Transport] rejects requests to hosts other than the host of
+// the endpoint configured via [WithEndpoint].
+func WithAdditionalHosts(hosts ...string) Option {
+	if len(hosts) == 0 {
+		return nil
+	}
+	return &funcOption{
+		name: "WithAdditionalHosts",
+		f: func(t *Transport) error {
+			var err error
+			for _, host := range hosts {
+				u, perr := url.Parse("//" + host)
+				if host == "" || perr != nil || u.Host != host || u.User != nil || u.Path != "" {
+					err = errors.Join(err, fmt.Errorf("invalid host: %q", host))
+					continue
+				}
+				t.hosts = append(t.hosts, canonicalHost("", host))
+			}
+			return err
+		},
+	}
+}
+
 // WithRoundTripper configures [Transport] to use next as next [http.RoundTripper].
 //
-// This can be used to further customize headers, add logging or retries. This only
-// applies to authentication API calls and not the http client using the [Transport].
+// This can be used to further customize headers, add logging or retries. This
+// applies to requests made via the [Transport] and to requests made internally
+// by the [Transport], unless [WithHTTPClient] is specified.
 func WithRoundTripper(next http.RoundTripper) Option {
 	if next == nil {
 		return nil
 	}
 	return &funcOption{
+		name: "WithRoundTripper",
 		f: func(t *Transport) error {
 			t.next = next
 			return nil
@@ -116,6 +286,32 @@ func WithRoundTripper(next http.RoundTripper) Option {
 	}
 }
 
+// WithHTTPClient configures [Transport] to use client for requests it makes
+// internally, like verifying the app and installation, minting installation
+// access tokens, and listing installations. This is useful to configure
+// timeouts, proxies or connection pooling for such requests, independent of
+// requests made via the [Transport].
+//
+// Requests made via the [Transport] are not affected and use the round tripper
+// configured via [WithRoundTripper]. Internal requests use client's Transport
+// instead of it, or [http.DefaultTransport] if client's Transport is nil.
+// Client's Timeout applies to each internal request. Client's CheckRedirect
+// and Jar are ignored, as internal requests carry credentials and redirects
+// to other hosts are never followed. Client MUST NOT be a client using the
+// [Transport] itself.
+func WithHTTPClient(client *http.Client) Option {
+	if client == nil {
+		return nil
+	}
+	return &funcOption{
+		name: "WithHTTPClient",
+		f: func(t *Transport) error {
+			t.httpClient = client
+			return nil
+		},
+	}
+}
+
 // WithUserAgent configures user agent header to use for token related API requests.
 //
 // Typically, [Transport] which implements [http.RoundTripper] will re-use the User-Agent
@@ -127,6 +323,7 @@ func WithUserAgent(ua string) Option {
 		return nil
 	}
 	return &funcOption{
+		name: "WithUserAgent",
 		f: func(t *Transport) error {
 			t.ua = ua
 			return nil
@@ -134,23 +331,344 @@ func WithUserAgent(ua string) Option {
 	}
 }
 
+// WithUserAgentSuffix appends product information to the default user agent
+// used for token related API requests. Unlike [WithUserAgent], this retains
+// the library identification, which is useful when contacting GitHub support.
+//
+// For example, suffix "my-app/1.0" results in user agent
+// "github.com/tprasadtp/go-githubapp/v0 my-app/1.0". This cannot be used
+// with [WithUserAgent]. Suffix with control characters is invalid.
+func WithUserAgentSuffix(suffix string) Option {
+	suffix = strings.TrimSpace(suffix)
+	if suffix == "" {
+		return nil
+	}
+	return &funcOption{
+		name: "WithUserAgentSuffix",
+		f: func(t *Transport) error {
+			if strings.IndexFunc(suffix, unicode.IsControl) != -1 {
+				return fmt.Errorf("user agent suffix contains control characters: %q", suffix)
+			}
+			t.uaSuffix = suffix
+			return nil
+		},
+	}
+}
+
+// WithExpiryMargin configures the duration for which a cached installation
+// access token must remain valid to be re-used by the [Transport]. When not
+// specified or zero, defaults to 60 seconds, same as [InstallationToken.IsValid].
+// This is useful when requests take a long time to complete, as token may
+// expire before the request completes. Margin must be less than an hour, which is
+// the lifetime of installation access tokens.
+//
+// This does not apply to JWT, as it is only valid for a couple of minutes
+// and is only used for a limited number of API requests.
+func WithExpiryMargin(d time.Duration) Option {
+	return &funcOption{
+		name: "WithExpiryMargin",
+		f: func(t *Transport) error {
+			if d < 0 || d >= time.Hour {
+				return fmt.Errorf("expiry margin must be between 0 and 1h: %s", d)
+			}
+			t.expiryMargin = d
+			return nil
+		},
+	}
+}
+
+// WithJWTExpiry configures lifetime of JWT minted by [Transport] or [NewJWT].
+// When not specified, JWT is valid for 2 minutes. This is useful with high-latency
+// links and retries, where JWT may expire before token renewal requests complete.
+// Durations longer than 10 minutes, the maximum allowed by GitHub, are clamped to
+// 10 minutes. Duration must be longer than a minute, as JWT is renewed when it is
+// valid for less than a minute.
+func WithJWTExpiry(d time.Duration) JWTOption {
+	return &jwtOption{
+		name: "WithJWTExpiry",
+		f: func(m *jwtRS256) error {
+			if d <= time.Minute {
+				return fmt.Errorf("jwt expiry must be longer than 1m: %s", d)
+			}
+			m.expiry = min(d, maxJWTExpiry)
+			return nil
+		},
+	}
+}
+
+// WithJWTClockSkew configures duration by which issued at time of JWT minted by
+// [Transport] or [NewJWT] is backdated to allow for clock drift between the host
+// and GitHub. When not specified, defaults to 30 seconds. Duration must be positive
+// and at-most 5 minutes.
+func WithJWTClockSkew(d time.Duration) JWTOption {
+	return &jwtOption{
+		name: "WithJWTClockSkew",
+		f: func(m *jwtRS256) error {
+			if d <= 0 || d > 5*time.Minute {
+				return fmt.Errorf("jwt clock skew must be between 0 and 5m: %s", d)
+			}
+			m.skew = d
+			return nil
+		},
+	}
+}
+
+// WithAcceptHeader configures default 'Accept' header used by [Transport]
+// for requests which do not specify one. This is useful when using [Transport]
+// with [net/http] directly and requiring preview media types or media types
+// like "application/vnd.github.raw" for downloading contents.
+//
+// 'Accept' header specified by the requests is never overwritten. Requests
+// made by the [Transport] itself for bootstrapping and token renewals always
+// use library defaults.
+func WithAcceptHeader(value string) Option {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	return &funcOption{
+		name: "WithAcceptHeader",
+		f: func(t *Transport) error {
+			t.accept = value
+			return nil
+		},
+	}
+}
+
+// WithAPIVersion configures REST API version to use. This is sent
+// via 'X-GitHub-Api-Version' header and MUST be in YYYY-MM-DD format.
+//
+// Configured version is used for all requests made by the [Transport] itself
+// and as a default for requests which do not specify 'X-GitHub-Api-Version' header.
+// When not specified or empty, "2022-11-28" is used for requests made by the
+// [Transport] itself and requests are not modified.
+func WithAPIVersion(version string) Option {
+	if version == "" {
+		return nil
+	}
+	return &funcOption{
+		name: "WithAPIVersion",
+		f: func(t *Transport) error {
+			_, err := time.Parse(api.VersionHeaderFormat, version)
+			if err != nil {
+				return fmt.Errorf("invalid api version(must be YYYY-MM-DD): %s", version)
+			}
+			t.apiVersion = version
+			return nil
+		},
+	}
+}
+
+// WithInstallationInUserAgent configures [Transport] to include installation id
+// in the user agent used for token related API requests. This helps correlating
+// requests to installations in multi-tenant deployments. Installation id is not
+// a secret, thus it is safe to include it in the user agent.
+//
+// For example, if user agent is set to "my-app/1.0" via [WithUserAgent],
+// user agent for installation 12345 will be
+// "my-app/1.0 (installation:12345) github.com/tprasadtp/go-githubapp/v0".
+func WithInstallationInUserAgent() Option {
+	return &funcOption{
+		name: "WithInstallationInUserAgent",
+		f: func(t *Transport) error {
+			t.uaInstallID = true
+			return nil
+		},
+	}
+}
+
+// WithUserAgentComment appends a comment to the user agent used for token
+// related API requests. This is useful to include a contact URL as per GitHub's
+// guidelines without replacing the user agent. Comment may be specified with or
+// without the enclosing parentheses and can be specified multiple times.
+//
+// For example, comment "+https://example.com/bot" results in user agent
+// "github.com/tprasadtp/go-githubapp/v0 (+https://example.com/bot)".
+// Comments with control characters or unbalanced parentheses are invalid.
+func WithUserAgentComment(comment string) Option {
+	comment = strings.TrimSpace(comment)
+	if comment == "" {
+		return nil
+	}
+	return &funcOption{
+		name: "WithUserAgentComment",
+		f: func(t *Transport) error {
+			v := comment
+			if strings.HasPrefix(v, "(") && strings.HasSuffix(v, ")") {
+				v = strings.TrimSpace(v[1 : len(v)-1])
+			}
+
+			var depth int
+			for _, c := range v {
+				switch {
+				case unicode.IsControl(c):
+					return fmt.Errorf("user agent comment contains control characters: %q", comment)
+				case c == '(':
+					depth++
+				case c == ')':
+					depth--
+				}
+				if depth < 0 {
+					break
+				}
+			}
+
+			if depth != 0 {
+				return fmt.Errorf("user agent comment has unbalanced parentheses: %q", comment)
+			}
+
+			if v == "" {
+				return fmt.Errorf("user agent comment is empty: %q", comment)
+			}
+
+			t.uaComments = append(t.uaComments, v)
+			return nil
+		},
+	}
+}
+
+// WithoutBotMetadata configures [Transport] to never fetch app's bot user
+// metadata. This avoids an API call, which is useful when app never creates
+// git commits. [Transport.BotUsername] and [Transport.BotCommitterEmail] will
+// return empty strings.
+func WithoutBotMetadata() Option {
+	return &funcOption{
+		name: "WithoutBotMetadata",
+		f: func(t *Transport) error {
+			t.skipBot = true
+			return nil
+		},
+	}
+}
+
+// WithEagerBotMetadata configures [NewTransport] to fetch app's bot user metadata
+// when building the [Transport], instead of on first use. If it cannot be fetched,
+// [NewTransport] returns an error. This is useful to detect errors at startup.
+// This is ignored if [WithoutBotMetadata] or [WithValidateOnly] is specified.
+func WithEagerBotMetadata() Option {
+	return &funcOption{
+		name: "WithEagerBotMetadata",
+		f: func(t *Transport) error {
+			t.eagerBot = true
+			return nil
+		},
+	}
+}
+
+// WithValidateOnly configures [NewTransport] to only validate the app and the
+// installation, without minting an installation access token or fetching app's
+// bot user metadata. This is useful to validate configuration at startup,
+// without minting a token which may never be used. Installation access token is
+// minted on first use. Because installation access token is not minted,
+// repositories specified via [WithRepositories] are not validated. Bot user
+// metadata is fetched on first use, even if [WithEagerBotMetadata] is specified.
+func WithValidateOnly() Option {
+	return &funcOption{
+		name: "WithValidateOnly",
+		f: func(t *Transport) error {
+			t.validateOnly = true
+			return nil
+		},
+	}
+}
+
+// WithTokenRefreshCallback configures a callback which is invoked with the
+// newly minted installation access token, after each successful installation
+// access token mint, either via [Transport.InstallationToken] or when [Transport]
+// renews an expired token. Context is that of the request which triggered minting
+// the token. This can be specified multiple times, and callbacks are invoked
+// in the order they are specified.
+//
+// This is useful to propagate installation access tokens to other components like
+// a git credential helper or a secret store.
+//
+// Callback is invoked synchronously, thus it MUST NOT block for long, as it
+// delays the request which triggered minting the token. Callback may be invoked
+// concurrently, thus it MUST be safe for concurrent use. Panics in callback are
+// recovered and logged via logger configured with [WithLogger], if any.
+func WithTokenRefreshCallback(fn func(ctx context.Context, token InstallationToken)) Option {
+	if fn == nil {
+		return nil
+	}
+	return &funcOption{
+		name: "WithTokenRefreshCallback",
+		f: func(t *Transport) error {
+			t.onTokenRefresh = append(t.onTokenRefresh, fn)
+			return nil
+		},
+	}
+}
+
+// WithRequestEditor configures a function which can modify requests, just before
+// they are sent to the underlying [http.RoundTripper]. This applies to requests made
+// by the [Transport] itself, like verifying the app, installation and minting tokens,
+// as well as requests made by the users of the [Transport]. This is useful to add headers
+// required by proxies or for request correlation, without having to use [WithRoundTripper].
+//
+// Editors are invoked after 'Authorization' and other default headers are set,
+// thus can override them, and are invoked in the order they are specified.
+// Request passed to the editor is already a clone, thus can be modified in place.
+// If editor returns an error, request is aborted.
+func WithRequestEditor(editor func(*http.Request) error) Option {
+	if editor == nil {
+		return nil
+	}
+	return &funcOption{
+		name: "WithRequestEditor",
+		f: func(t *Transport) error {
+			t.editors = append(t.editors, editor)
+			return nil
+		},
+	}
+}
+
+// WithPreserveAuthorizationHeader configures [Transport] to pass through requests
+// which already have an 'Authorization' header as is, instead of replacing it
+// with installation access token or JWT. This is useful when some requests must be
+// made with a different credential, like a user access token, while re-using
+// the same [http.Client]. Requests without an 'Authorization' header are
+// authenticated as usual. Token renewal requests made by the [Transport] itself
+// always use JWT.
+//
+// This changes the security properties of the [Transport]. Any code which can set
+// headers on requests can make requests with arbitrary credentials, and requests
+// which unintentionally carry a stale or wrong credential are no longer
+// authenticated as the app. Only use this when all request producers are trusted.
+// Use [WithExistingAuthorization] to preserve 'Authorization' header only for
+// specific requests.
+func WithPreserveAuthorizationHeader() Option {
+	return &funcOption{
+		name: "WithPreserveAuthorizationHeader",
+		f: func(t *Transport) error {
+			t.preserveAuthz = true
+			return nil
+		},
+	}
+}
+
 // WithRepositories configures [Transport] to use installation for repos specified.
-// Unlike other installation options, this can be used multiple times.
+// Unlike other installation options, this can be used multiple times. Repositories
+// can be specified as "owner/repo" or as "repo". Owner is required if bare names
+// are used, unless [WithInstallationID] is specified, in which case owner is
+// populated from the installation. Duplicate repositories are ignored.
+// This cannot be used with [WithRepositoryIDs]. If any of the repositories
+// are not accessible to the installation, [NewTransport] returns an error
+// matching [ErrRepositoryNotAccessible].
 func WithRepositories(repos ...string) Option {
 	if len(repos) == 0 {
 		return nil
 	}
 	return &funcOption{
+		name: "WithRepositories",
 		f: func(t *Transport) error {
 			refOwner := t.owner
 			invalid := make([]string, 0, len(repos))
 			names := make([]string, 0, len(repos))
 			for _, item := range repos {
-				item = strings.ToLower(item)
 				username, repo, ok := strings.Cut(item, "/")
 				// Repository is in form username/repo.
 				if ok {
-					if !userNameRegExp.MatchString(username) {
+					// Usernames are case-insensitive, but case is preserved.
+					if !userNameRegExp.MatchString(strings.ToLower(username)) {
 						invalid = append(invalid, item)
 						continue
 					}
@@ -161,13 +679,14 @@ func WithRepositories(repos ...string) Option {
 					}
 
 					// Repositories must be under a single installation.
-					if username != refOwner {
+					if !strings.EqualFold(username, refOwner) {
 						return fmt.Errorf("repositories from multiple owners specified: %v", repos)
 					}
 
 					// Assign repo to item if repo is in format username/repo.
 					item = repo
 				}
+				item = strings.ToLower(item)
 
 				// Ensure the repository name is valid.
 				if !repoNameRegExp.MatchString(item) {
@@ -197,18 +716,80 @@ func WithRepositories(repos ...string) Option {
 	}
 }
 
-// WithOwner configures the installation owner to use.
+// WithRepositoryIDs configures [Transport] to scope installation access tokens to
+// repositories with the ids specified. Unlike repository names, ids do not change
+// when repositories are renamed or transferred, which is useful for long-lived
+// automation. Like [WithRepositories], this can be used multiple times and
+// duplicate ids are ignored. This cannot be used with [WithRepositories].
+//
+// As repository ids do not identify the installation, [WithInstallationID] or
+// [WithOwner] MUST also be specified.
+func WithRepositoryIDs(ids ...int64) Option {
+	if len(ids) == 0 {
+		return nil
+	}
+	return &funcOption{
+		name: "WithRepositoryIDs",
+		f: func(t *Transport) error {
+			invalid := make([]int64, 0, len(ids))
+			for _, id := range ids {
+				if id <= 0 {
+					invalid = append(invalid, id)
+				}
+			}
+
+			if len(invalid) > 0 {
+				return fmt.Errorf("invalid repository ids specified: %v", invalid)
+			}
+
+			t.repoIDs = append(t.repoIDs, ids...)
+			slices.Sort(t.repoIDs)
+			t.repoIDs = slices.Clip(slices.Compact(t.repoIDs))
+			return nil
+		},
+	}
+}
+
+// WithSingleFile configures [Transport] to use installation access tokens with
+// "single_file" permission, for the file specified. Path is relative to the
+// repository root, like ".github/config.yml". If "single_file" permission is not
+// specified via [WithPermissions], read access is requested.
+//
+// GitHub API does not support selecting the file when creating installation
+// access tokens, as single files are configured by the app. Thus, [NewTransport]
+// verifies that the file is one of the single files configured for the
+// installation, and returns an error if it is not.
+func WithSingleFile(path string) Option {
+	return &funcOption{
+		name: "WithSingleFile",
+		f: func(t *Transport) error {
+			if strings.TrimSpace(path) == "" || strings.HasPrefix(path, "/") {
+				return fmt.Errorf("invalid single file path: %q", path)
+			}
+
+			if t.singleFile != "" && t.singleFile != path {
+				return fmt.Errorf("single file is already configured(%s): %s", t.singleFile, path)
+			}
+
+			t.singleFile = path
+			return nil
+		},
+	}
+}
+
+// WithOwner configures the installation owner to use. Usernames are
+// case-insensitive, but case is preserved as specified.
 func WithOwner(username string) Option {
 	return &funcOption{
+		name: "WithOwner",
 		f: func(t *Transport) error {
-			username = strings.ToLower(username)
-			if !userNameRegExp.MatchString(username) {
+			if !userNameRegExp.MatchString(strings.ToLower(username)) {
 				return fmt.Errorf("invalid username: %s", username)
 			}
 
 			// If owner was already set, it might have been extracted from repos.
 			// ensure they do not conflict.
-			if t.owner != "" && t.owner != username {
+			if t.owner != "" && !strings.EqualFold(t.owner, username) {
 				return fmt.Errorf("owner is already configured(%s): %s", t.owner, username)
 			}
 
@@ -225,6 +806,7 @@ func WithOwner(username string) Option {
 // from data provided by [WebHook].
 func WithInstallationID(id uint64) Option {
 	return &funcOption{
+		name: "WithInstallationID",
 		f: func(t *Transport) error {
 			if id == 0 {
 				return errors.New("installation id cannot be zero")
@@ -241,25 +823,63 @@ func WithInstallationID(id uint64) Option {
 	}
 }
 
+// WithStaticInstallationToken configures [Transport] to use an already minted
+// installation access token, for example one obtained from a central token broker,
+// instead of minting its own. App id and signer are not required by [NewTransport]
+// in this mode, and no API calls are made to verify the app or the installation.
+//
+// Token is used as is until it expires, after which requests fail with
+// [ErrInstallationTokenExpired], as it cannot be renewed. JWT is not available,
+// thus installation options, [ContextWithInstallationID] and [WithRequestPermissions]
+// are not supported. Installation metadata like installation id and owner are
+// populated from the token.
+func WithStaticInstallationToken(token InstallationToken) Option {
+	return &funcOption{
+		name: "WithStaticInstallationToken",
+		f: func(t *Transport) error {
+			if token.Token == "" {
+				return errors.New("token is empty")
+			}
+
+			if !token.validFor(0) {
+				return fmt.Errorf("%w (expired at %s)", ErrInstallationTokenExpired, token.Exp.Format(time.RFC3339))
+			}
+
+			// Token is copied, so that callers cannot modify it.
+			token.Repositories = slices.Clone(token.Repositories)
+			token.Permissions = maps.Clone(token.Permissions)
+			t.static = &token
+			return nil
+		},
+	}
+}
+
 // WithPermissions configures permission scopes. This is useful when app has
 // a broader set of permissions, a scoped access token is required.
 //
 // Permissions MUST be specified in "<scope>:<access>" or "<scope>=<access>" format.
 // Where scope is permission scope like "issues" and access can be one of
-// "read", "write" or "admin".
+// "none", "read", "write" or "admin". Access level "none" explicitly excludes
+// a permission which is otherwise available to the installation.
 //
 // For example, to request permissions to write issues and pull request can be
 // specified as,
 //
 //	githubapp.WithPermissions("issues:write", "pull_requests:write")
+//
+// This can be used multiple times, and permissions are merged. Requesting the same
+// scope with different access levels, either in the same call or in multiple calls,
+// is an error.
 func WithPermissions(permissions ...string) Option {
 	if len(permissions) == 0 {
 		return nil
 	}
 	return &funcOption{
+		name: "WithPermissions",
 		f: func(t *Transport) error {
 			m := make(map[string]string, len(permissions))
 			invalid := make([]string, 0, len(permissions))
+			var err error
 			for _, item := range permissions {
 				item = strings.ToLower(item)
 				if permissionRegEx.MatchString(item) {
@@ -269,15 +889,196 @@ func WithPermissions(permissions ...string) Option {
 					// Ignore error checks as regex already validates
 					// that permissions are in required format.
 					scope, level, _ := strings.Cut(item, ":")
+
+					// If scope is already requested, ensure levels do not conflict.
+					if v, ok := t.scopes[scope]; ok && v != level {
+						err = errors.Join(err,
+							fmt.Errorf("permission %s is already configured(%s): %s", scope, v, level))
+						continue
+					}
+
+					if v, ok := m[scope]; ok && v != level {
+						err = errors.Join(err,
+							fmt.Errorf("permission %s is specified with different levels: %s, %s", scope, v, level))
+						continue
+					}
 					m[scope] = level
 				} else {
 					invalid = append(invalid, item)
 				}
 			}
 			if len(invalid) != 0 {
-				return fmt.Errorf("invalid permissions: %v", invalid)
+				err = errors.Join(fmt.Errorf("invalid permissions: %v", invalid), err)
+			}
+
+			if err != nil {
+				return err
+			}
+
+			if t.scopes == nil {
+				t.scopes = m
+				return nil
+			}
+			maps.Copy(t.scopes, m)
+			return nil
+		},
+	}
+}
+
+// WithStrictPermissionNames configures [Transport] to validate permission names
+// specified via [WithPermissions] or [WithPermissionsMap] against a catalog of
+// known GitHub app permissions. This catches typos like "content:read" early,
+// instead of failing with missing permissions error when verifying the installation.
+// Error suggests the closest known permission name, if any.
+//
+// Catalog is updated periodically, thus this might reject permissions which were
+// recently added by GitHub. Thus, it is not enabled by default.
+func WithStrictPermissionNames() Option {
+	return &funcOption{
+		name: "WithStrictPermissionNames",
+		f: func(t *Transport) error {
+			t.strictScopes = true
+			return nil
+		},
+	}
+}
+
+// checkPermissionNames checks if permission names are known GitHub app permissions.
+func checkPermissionNames(scopes map[string]string) error {
+	names := make([]string, 0, len(scopes))
+	for scope := range scopes {
+		names = append(names, scope)
+	}
+	slices.Sort(names)
+
+	var err error
+	for _, scope := range names {
+		if _, ok := slices.BinarySearch(api.PermissionNames, scope); ok {
+			continue
+		}
+
+		if suggestion := closestPermissionName(scope); suggestion != "" {
+			err = errors.Join(err, fmt.Errorf("unknown permission %q (did you mean %q?)", scope, suggestion))
+		} else {
+			err = errors.Join(err, fmt.Errorf("unknown permission %q", scope))
+		}
+	}
+	return err
+}
+
+// closestPermissionName returns known permission name closest to the name,
+// by edit distance. If there are no close matches, this returns empty string.
+func closestPermissionName(name string) string {
+	var closest string
+	best := max(2, len(name)/3) + 1
+	for _, candidate := range api.PermissionNames {
+		if d := editDistance(name, candidate); d < best {
+			best = d
+			closest = candidate
+		}
+	}
+	return closest
+}
+
+// editDistance returns levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// WithPermissionsMap configures permission scopes from a map of scope to access
+// level. This is same as [WithPermissions], but is useful when permissions are
+// already available as a map, like from a webhook payload or a config file.
+// Scopes are normalized to lower case, and access level can be one of "none",
+// "read", "write" or "admin".
+//
+// For example, to request permissions to write issues and pull request can be
+// specified as,
+//
+//	githubapp.WithPermissionsMap(map[string]string{
+//		"issues":        "write",
+//		"pull_requests": "write",
+//	})
+//
+// This can be used together with [WithPermissions], and permissions are merged.
+// Requesting the same scope with different access levels is an error.
+func WithPermissionsMap(permissions map[string]string) Option {
+	if len(permissions) == 0 {
+		return nil
+	}
+	return &funcOption{
+		name: "WithPermissionsMap",
+		f: func(t *Transport) error {
+			// Map iteration order is random, sort scopes for stable errors.
+			scopes := make([]string, 0, len(permissions))
+			for scope := range permissions {
+				scopes = append(scopes, scope)
+			}
+			slices.Sort(scopes)
+
+			m := make(map[string]string, len(permissions))
+			invalid := make([]string, 0, len(permissions))
+			var err error
+			for _, item := range scopes {
+				scope := strings.ToLower(item)
+				level := strings.ToLower(permissions[item])
+				switch level {
+				case api.PermissionLevelNone, api.PermissionLevelRead,
+					api.PermissionLevelWrite, api.PermissionLevelAdmin:
+				default:
+					invalid = append(invalid, scope+":"+level)
+					continue
+				}
+
+				if !permissionRegEx.MatchString(scope + ":" + level) {
+					invalid = append(invalid, scope+":"+level)
+					continue
+				}
+
+				// If scope is already requested, ensure levels do not conflict.
+				if v, ok := t.scopes[scope]; ok && v != level {
+					err = errors.Join(err,
+						fmt.Errorf("permission %s is already configured(%s): %s", scope, v, level))
+					continue
+				}
+
+				// Scopes differing only in case may be specified with different levels.
+				if v, ok := m[scope]; ok && v != level {
+					err = errors.Join(err,
+						fmt.Errorf("permission %s is specified with different levels: %s, %s", scope, v, level))
+					continue
+				}
+				m[scope] = level
+			}
+			if len(invalid) != 0 {
+				err = errors.Join(fmt.Errorf("invalid permissions: %v", invalid), err)
+			}
+
+			if err != nil {
+				return err
+			}
+
+			if t.scopes == nil {
+				t.scopes = m
+				return nil
 			}
-			t.scopes = m
+			maps.Copy(t.scopes, m)
 			return nil
 		},
 	}
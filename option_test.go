@@ -4,12 +4,17 @@
 package githubapp
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"maps"
 	"net/http"
 	"net/url"
 	"reflect"
 	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/tprasadtp/go-githubapp/internal/api"
 )
@@ -138,6 +143,13 @@ func TestWithRepositories(t *testing.T) {
 			expect: []string{"bar", "foo"},
 			ok:     true,
 		},
+		{
+			name:   "valid-owner-case-preserved",
+			input:  []string{"UserName/Foo", "username/bar"},
+			owner:  "UserName",
+			expect: []string{"bar", "foo"},
+			ok:     true,
+		},
 	}
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
@@ -165,6 +177,118 @@ func TestWithRepositories(t *testing.T) {
 	}
 }
 
+func TestWithRepositoryIDs(t *testing.T) {
+	tt := []struct {
+		name   string
+		input  []int64
+		expect []int64
+		ok     bool
+	}{
+		{
+			name:  "zero",
+			input: []int64{1, 0},
+		},
+		{
+			name:  "negative",
+			input: []int64{-1},
+		},
+		{
+			name:   "valid",
+			input:  []int64{3, 1, 2},
+			expect: []int64{1, 2, 3},
+			ok:     true,
+		},
+		{
+			name:   "valid-deduplicate",
+			input:  []int64{2, 1, 2},
+			expect: []int64{1, 2},
+			ok:     true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			transport := Transport{}
+			err := WithRepositoryIDs(tc.input...).apply(&transport)
+			if tc.ok {
+				if err != nil {
+					t.Fatalf("unexpected error %s", err)
+				}
+
+				if !slices.Equal(tc.expect, transport.repoIDs) {
+					t.Errorf("expected Transport.repoIDs=%v, got=%v", tc.expect, transport.repoIDs)
+				}
+			} else if err == nil {
+				t.Errorf("expected error, got nil")
+			}
+		})
+	}
+
+	t.Run("no-args", func(t *testing.T) {
+		if WithRepositoryIDs() != nil {
+			t.Errorf("WithRepositoryIDs with no-args must return nil")
+		}
+	})
+}
+
+func TestWithSingleFile(t *testing.T) {
+	tt := []struct {
+		name   string
+		input  string
+		opts   []Option
+		expect map[string]string
+		ok     bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:  "absolute",
+			input: "/.github/config.yml",
+		},
+		{
+			name:   "valid",
+			input:  ".github/config.yml",
+			expect: map[string]string{"single_file": "read"},
+			ok:     true,
+		},
+		{
+			name:   "valid-with-single-file-write",
+			input:  ".github/config.yml",
+			opts:   []Option{WithPermissions("single_file:write")},
+			expect: map[string]string{"single_file": "write"},
+			ok:     true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			transport, err := newTransport(99, append([]Option{WithSingleFile(tc.input)}, tc.opts...)...)
+			if tc.ok {
+				if err != nil {
+					t.Fatalf("unexpected error %s", err)
+				}
+
+				if transport.singleFile != tc.input {
+					t.Errorf("expected Transport.singleFile=%s, got=%s", tc.input, transport.singleFile)
+				}
+
+				if !maps.Equal(tc.expect, transport.scopes) {
+					t.Errorf("expected Transport.scopes=%v, got=%v", tc.expect, transport.scopes)
+				}
+			} else if err == nil {
+				t.Errorf("expected error, got nil")
+			}
+		})
+	}
+
+	t.Run("conflict", func(t *testing.T) {
+		transport := Transport{}
+		err := Options(WithSingleFile("a.yml"), WithSingleFile("b.yml")).apply(&transport)
+		if err == nil {
+			t.Errorf("expected error, got nil")
+		}
+	})
+}
+
 func TestWithOwner(t *testing.T) {
 	tt := []struct {
 		name   string
@@ -204,6 +328,12 @@ func TestWithOwner(t *testing.T) {
 			expect: "user-name-org",
 			ok:     true,
 		},
+		{
+			name:   "username-preserves-case",
+			input:  "User-Name-Org",
+			expect: "User-Name-Org",
+			ok:     true,
+		},
 	}
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
@@ -238,6 +368,20 @@ func TestWithOwner(t *testing.T) {
 			t.Errorf("expected error, got nil")
 		}
 	})
+
+	t.Run("multiple-owners-different-case", func(t *testing.T) {
+		transport := Transport{}
+		opts := Options(WithRepositories("GitHub/foo"), WithOwner("github"))
+		err := opts.apply(&transport)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		// Owner specified via WithOwner takes precedence.
+		if transport.owner != "github" {
+			t.Errorf("expected Transport.owner=github, got=%s", transport.owner)
+		}
+	})
 }
 
 func TestWithEndpoint(t *testing.T) {
@@ -311,10 +455,197 @@ func TestWithEndpoint(t *testing.T) {
 	}
 }
 
+func TestWithEnterpriseHost(t *testing.T) {
+	tt := []struct {
+		name    string
+		input   string
+		ok      bool
+		rest    string
+		graphql string
+		upload  string
+	}{
+		{
+			name:    "bare-host",
+			input:   "ghes.go-githubapp.test",
+			ok:      true,
+			rest:    "https://ghes.go-githubapp.test/api/v3/",
+			graphql: "https://ghes.go-githubapp.test/api/graphql",
+			upload:  "https://ghes.go-githubapp.test/api/uploads/",
+		},
+		{
+			name:    "bare-host-with-port",
+			input:   "GHES.go-githubapp.test:8443",
+			ok:      true,
+			rest:    "https://ghes.go-githubapp.test:8443/api/v3/",
+			graphql: "https://ghes.go-githubapp.test:8443/api/graphql",
+			upload:  "https://ghes.go-githubapp.test:8443/api/uploads/",
+		},
+		{
+			name:    "url",
+			input:   "https://ghes.go-githubapp.test",
+			ok:      true,
+			rest:    "https://ghes.go-githubapp.test/api/v3/",
+			graphql: "https://ghes.go-githubapp.test/api/graphql",
+			upload:  "https://ghes.go-githubapp.test/api/uploads/",
+		},
+		{
+			name:    "url-with-trailing-slash",
+			input:   "https://ghes.go-githubapp.test/",
+			ok:      true,
+			rest:    "https://ghes.go-githubapp.test/api/v3/",
+			graphql: "https://ghes.go-githubapp.test/api/graphql",
+			upload:  "https://ghes.go-githubapp.test/api/uploads/",
+		},
+		{
+			name:    "url-with-api-path",
+			input:   "https://ghes.go-githubapp.test/api/v3",
+			ok:      true,
+			rest:    "https://ghes.go-githubapp.test/api/v3/",
+			graphql: "https://ghes.go-githubapp.test/api/graphql",
+			upload:  "https://ghes.go-githubapp.test/api/uploads/",
+		},
+		{
+			name:    "url-with-api-path-trailing-slash",
+			input:   "https://ghes.go-githubapp.test/api/v3/",
+			ok:      true,
+			rest:    "https://ghes.go-githubapp.test/api/v3/",
+			graphql: "https://ghes.go-githubapp.test/api/graphql",
+			upload:  "https://ghes.go-githubapp.test/api/uploads/",
+		},
+		{
+			name:  "url-with-api-path-prefix",
+			input: "https://ghes.go-githubapp.test/api/v3/api/v3",
+		},
+		{
+			name:  "empty",
+			input: "",
+		},
+		{
+			name:  "http",
+			input: "http://ghes.go-githubapp.test",
+		},
+		{
+			name:  "unsupported-scheme",
+			input: "ftp://ghes.go-githubapp.test",
+		},
+		{
+			name:  "with-path",
+			input: "https://ghes.go-githubapp.test/foo",
+		},
+		{
+			name:  "with-query",
+			input: "https://ghes.go-githubapp.test/?foo=bar",
+		},
+		{
+			name:  "github.com",
+			input: "github.com",
+		},
+		{
+			name:  "api.github.com",
+			input: "https://API.github.com/",
+		},
+		{
+			name:  "invalid",
+			input: "host is invalid",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			transport := Transport{}
+			err := WithEnterpriseHost(tc.input).apply(&transport)
+			if tc.ok {
+				if err != nil {
+					t.Fatalf("expected no error, got %s", err)
+				}
+
+				if v := transport.baseURL.String(); v != tc.rest {
+					t.Errorf("expected rest endpoint=%s, got=%s", tc.rest, v)
+				}
+
+				if v := transport.GraphQLEndpoint(); v != tc.graphql {
+					t.Errorf("expected graphql endpoint=%s, got=%s", tc.graphql, v)
+				}
+
+				if v := transport.UploadEndpoint(); v != tc.upload {
+					t.Errorf("expected upload endpoint=%s, got=%s", tc.upload, v)
+				}
+			} else {
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				if transport.baseURL != nil {
+					t.Errorf("transport baseURL should not be modified")
+				}
+			}
+		})
+	}
+}
+
+func TestWithAdditionalHosts(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		if WithAdditionalHosts() != nil {
+			t.Errorf("WithAdditionalHosts with no hosts must return nil")
+		}
+	})
+
+	tt := []struct {
+		name   string
+		input  []string
+		ok     bool
+		expect []string
+	}{
+		{
+			name:   "valid",
+			input:  []string{"graphql.go-githubapp.test", "GHES.go-githubapp.test:8443"},
+			ok:     true,
+			expect: []string{"graphql.go-githubapp.test", "ghes.go-githubapp.test:8443"},
+		},
+		{
+			name:  "empty-host",
+			input: []string{""},
+		},
+		{
+			name:  "with-scheme",
+			input: []string{"https://graphql.go-githubapp.test"},
+		},
+		{
+			name:  "with-path",
+			input: []string{"graphql.go-githubapp.test/graphql"},
+		},
+		{
+			name:  "with-userinfo",
+			input: []string{"user@graphql.go-githubapp.test"},
+		},
+		{
+			name:  "invalid-host",
+			input: []string{"host is invalid"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			transport := Transport{}
+			err := Options(WithAdditionalHosts(tc.input...)).apply(&transport)
+			if tc.ok {
+				if err != nil {
+					t.Errorf("expected no error, got %s", err)
+				}
+				if !reflect.DeepEqual(transport.hosts, tc.expect) {
+					t.Errorf("expected=%v, got=%v", tc.expect, transport.hosts)
+				}
+			} else {
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+			}
+		})
+	}
+}
+
 func TestWithPermissions(t *testing.T) {
 	tt := []struct {
 		name   string
 		input  []string
+		more   []string
 		ok     bool
 		expect map[string]string
 	}{
@@ -363,10 +694,64 @@ func TestWithPermissions(t *testing.T) {
 				"contents": "read",
 			},
 		},
-		// none scope is not allowed.
 		{
 			name:  "with-scope-none",
 			input: []string{"contents:none"},
+			ok:    true,
+			expect: map[string]string{
+				"contents": "none",
+			},
+		},
+		{
+			name:  "with-scope-none-mixed",
+			input: []string{"contents=none", "issues:write"},
+			ok:    true,
+			expect: map[string]string{
+				"contents": "none",
+				"issues":   "write",
+			},
+		},
+		{
+			name:  "duplicate-identical",
+			input: []string{"issues:write", "issues=write"},
+			ok:    true,
+			expect: map[string]string{
+				"issues": "write",
+			},
+		},
+		{
+			name:  "duplicate-conflicting-level",
+			input: []string{"issues:write", "issues:read"},
+		},
+		{
+			name:  "merge",
+			input: []string{"issues:write"},
+			more:  []string{"contents:read"},
+			ok:    true,
+			expect: map[string]string{
+				"contents": "read",
+				"issues":   "write",
+			},
+		},
+		{
+			name:  "merge-identical-duplicate",
+			input: []string{"issues:write", "contents:read"},
+			more:  []string{"contents:read", "metadata:read"},
+			ok:    true,
+			expect: map[string]string{
+				"contents": "read",
+				"issues":   "write",
+				"metadata": "read",
+			},
+		},
+		{
+			name:  "merge-conflicting-level",
+			input: []string{"issues:write", "contents:read"},
+			more:  []string{"contents:write"},
+			expect: map[string]string{
+				"contents": "read",
+				"issues":   "write",
+			},
 		},
 	}
 
@@ -374,6 +759,9 @@ func TestWithPermissions(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			transport := Transport{}
 			opts := Options(WithPermissions(tc.input...))
+			if tc.more != nil {
+				opts = Options(WithPermissions(tc.input...), WithPermissions(tc.more...))
+			}
 			err := opts.apply(&transport)
 			if tc.ok {
 				if err != nil {
@@ -387,43 +775,240 @@ func TestWithPermissions(t *testing.T) {
 				if err == nil {
 					t.Errorf("expected an error, got nil")
 				}
-				if transport.scopes != nil {
+
+				// Permissions from failed options must not be applied.
+				if tc.expect == nil && transport.scopes != nil {
 					t.Errorf("transport.scopes should be nil: %v", transport.scopes)
 				}
+
+				if tc.expect != nil && !maps.Equal(transport.scopes, tc.expect) {
+					t.Errorf("expected=%v, got=%v", tc.expect, transport.scopes)
+				}
 			}
 		})
 	}
 }
 
-func TestWithRoundTripper(t *testing.T) {
-	t.Run("non-nil", func(t *testing.T) {
-		transport := Transport{}
-		opts := Options(WithRoundTripper(
-			api.RoundTripFunc(func(r *http.Request) (*http.Response, error) {
-				t.Logf("request=%v", r)
-				return http.DefaultTransport.RoundTrip(r)
-			})))
-		err := opts.apply(&transport)
-		if err != nil {
-			t.Errorf("expected no error, got %s", err)
-		}
-
-		if transport.next == nil {
-			t.Errorf("transport.next should be non nil")
-		}
-	})
-	t.Run("nil-round-tripper", func(t *testing.T) {
-		opts := Options(WithRoundTripper(nil))
-		if opts != nil {
-			t.Errorf("expected nil options when no round tripper is specified")
+func TestWithPermissionsMap(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		if WithPermissionsMap(nil) != nil {
+			t.Errorf("WithPermissionsMap with empty map must return nil")
 		}
 	})
-}
 
-func TestWithInstallationID(t *testing.T) {
-	t.Run("zero", func(t *testing.T) {
-		transport := Transport{}
-		opts := Options(WithInstallationID(0))
+	tt := []struct {
+		name   string
+		input  map[string]string
+		ok     bool
+		expect map[string]string
+	}{
+		{
+			name:  "with-no-level",
+			input: map[string]string{"issues": ""},
+		},
+		{
+			name:  "invalid-level",
+			input: map[string]string{"issues": "root"},
+		},
+		{
+			name:  "invalid-scope",
+			input: map[string]string{"issues:write": "write"},
+		},
+		{
+			name:  "invalid-and-valid",
+			input: map[string]string{"issues": "write", "contents": "foo"},
+		},
+		{
+			name:  "valid",
+			input: map[string]string{"issues": "write"},
+			ok:    true,
+			expect: map[string]string{
+				"issues": "write",
+			},
+		},
+		{
+			name:  "mixed-case",
+			input: map[string]string{"Issues": "WRITE", "Contents": "read"},
+			ok:    true,
+			expect: map[string]string{
+				"issues":   "write",
+				"contents": "read",
+			},
+		},
+		{
+			name:  "with-scope-none",
+			input: map[string]string{"contents": "none", "issues": "admin"},
+			ok:    true,
+			expect: map[string]string{
+				"contents": "none",
+				"issues":   "admin",
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			transport := Transport{}
+			opts := Options(WithPermissionsMap(tc.input))
+			err := opts.apply(&transport)
+			if tc.ok {
+				if err != nil {
+					t.Errorf("expected no error, got %s", err)
+				}
+
+				if !maps.Equal(transport.scopes, tc.expect) {
+					t.Errorf("expected=%v, got=%v", tc.expect, transport.scopes)
+				}
+			} else {
+				if err == nil {
+					t.Errorf("expected an error, got nil")
+				}
+				if transport.scopes != nil {
+					t.Errorf("transport.scopes should be nil: %v", transport.scopes)
+				}
+			}
+		})
+	}
+
+	t.Run("error-lists-invalid-entries", func(t *testing.T) {
+		transport := Transport{}
+		err := WithPermissionsMap(map[string]string{
+			"issues":   "root",
+			"contents": "foo",
+			"metadata": "read",
+		}).apply(&transport)
+		if err == nil {
+			t.Fatalf("expected an error, got nil")
+		}
+		for _, item := range []string{"issues:root", "contents:foo"} {
+			if !strings.Contains(err.Error(), item) {
+				t.Errorf("expected error to include %q, got %s", item, err)
+			}
+		}
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		transport := Transport{}
+		err := Options(
+			WithPermissions("issues:read", "contents:read"),
+			WithPermissionsMap(map[string]string{"issues": "read", "pull_requests": "write"}),
+		).apply(&transport)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		expect := map[string]string{"issues": "read", "contents": "read", "pull_requests": "write"}
+		if !maps.Equal(transport.scopes, expect) {
+			t.Errorf("expected=%v, got=%v", expect, transport.scopes)
+		}
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		tt := []struct {
+			name string
+			opts []Option
+		}{
+			{
+				name: "with-permissions",
+				opts: []Option{
+					WithPermissions("issues:read"),
+					WithPermissionsMap(map[string]string{"issues": "write"}),
+				},
+			},
+			{
+				name: "with-permissions-map",
+				opts: []Option{
+					WithPermissionsMap(map[string]string{"issues": "write"}),
+					WithPermissions("issues:read"),
+				},
+			},
+			{
+				name: "mixed-case",
+				opts: []Option{
+					WithPermissionsMap(map[string]string{"issues": "write", "Issues": "read"}),
+				},
+			},
+		}
+		for _, tc := range tt {
+			t.Run(tc.name, func(t *testing.T) {
+				transport := Transport{}
+				err := Options(tc.opts...).apply(&transport)
+				if err == nil {
+					t.Errorf("expected an error, got nil")
+				}
+			})
+		}
+	})
+
+	t.Run("same-errors-as-with-permissions", func(t *testing.T) {
+		scopes := map[string]string{"contents": "read", "issues": "read"}
+		a := Transport{scopes: maps.Clone(scopes)}
+		errA := WithPermissions("contents:write", "issues:write").apply(&a)
+
+		b := Transport{scopes: maps.Clone(scopes)}
+		errB := WithPermissionsMap(map[string]string{"issues": "write", "contents": "write"}).apply(&b)
+
+		var joined interface{ Unwrap() []error }
+		if !errors.As(errB, &joined) || len(joined.Unwrap()) != 2 {
+			t.Errorf("expected errors to be joined, got %#v", errB)
+		}
+
+		if errA == nil || errB == nil || strings.TrimPrefix(errA.Error(), "WithPermissions: ") !=
+			strings.TrimPrefix(errB.Error(), "WithPermissionsMap: ") {
+			t.Errorf("expected same errors, got %q and %q", errA, errB)
+		}
+	})
+}
+
+func TestWithRoundTripper(t *testing.T) {
+	t.Run("non-nil", func(t *testing.T) {
+		transport := Transport{}
+		opts := Options(WithRoundTripper(
+			api.RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+				t.Logf("request=%v", r)
+				return http.DefaultTransport.RoundTrip(r)
+			})))
+		err := opts.apply(&transport)
+		if err != nil {
+			t.Errorf("expected no error, got %s", err)
+		}
+
+		if transport.next == nil {
+			t.Errorf("transport.next should be non nil")
+		}
+	})
+	t.Run("nil-round-tripper", func(t *testing.T) {
+		opts := Options(WithRoundTripper(nil))
+		if opts != nil {
+			t.Errorf("expected nil options when no round tripper is specified")
+		}
+	})
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	t.Run("non-nil", func(t *testing.T) {
+		transport := Transport{}
+		client := &http.Client{}
+		err := Options(WithHTTPClient(client)).apply(&transport)
+		if err != nil {
+			t.Errorf("expected no error, got %s", err)
+		}
+
+		if transport.httpClient != client {
+			t.Errorf("transport.httpClient should be the client specified")
+		}
+	})
+	t.Run("nil-client", func(t *testing.T) {
+		if opts := Options(WithHTTPClient(nil)); opts != nil {
+			t.Errorf("expected nil options when no client is specified")
+		}
+	})
+}
+
+func TestWithInstallationID(t *testing.T) {
+	t.Run("zero", func(t *testing.T) {
+		transport := Transport{}
+		opts := Options(WithInstallationID(0))
 		err := opts.apply(&transport)
 		if err == nil {
 			t.Errorf("expected an error, got nil")
@@ -480,3 +1065,727 @@ func TestWithUserAgent(t *testing.T) {
 		}
 	})
 }
+
+func TestWithInstallationInUserAgent(t *testing.T) {
+	transport := Transport{}
+	opts := Options(WithInstallationInUserAgent())
+	err := opts.apply(&transport)
+	if err != nil {
+		t.Errorf("expected no error, got %s", err)
+	}
+
+	if !transport.uaInstallID {
+		t.Errorf("transport.uaInstallID should be true")
+	}
+}
+
+func TestWithUserAgentComment(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		if WithUserAgentComment(" ") != nil {
+			t.Errorf("WithUserAgentComment with empty comment must return nil")
+		}
+	})
+
+	tt := []struct {
+		name    string
+		comment string
+		expect  string
+		ok      bool
+	}{
+		{
+			name:    "plain",
+			comment: "+https://example.com/bot",
+			expect:  "+https://example.com/bot",
+			ok:      true,
+		},
+		{
+			name:    "enclosed",
+			comment: "(+https://example.com/bot)",
+			expect:  "+https://example.com/bot",
+			ok:      true,
+		},
+		{
+			name:    "nested",
+			comment: "bot (contact: bot@example.com)",
+			expect:  "bot (contact: bot@example.com)",
+			ok:      true,
+		},
+		{
+			name:    "enclosed-empty",
+			comment: "( )",
+		},
+		{
+			name:    "unbalanced-open",
+			comment: "+https://example.com/bot (",
+		},
+		{
+			name:    "unbalanced-close",
+			comment: "+https://example.com/bot)",
+		},
+		{
+			name:    "unbalanced-order",
+			comment: "foo) (bar",
+		},
+		{
+			name:    "control-characters",
+			comment: "+https://example.com/bot\r\nX-Injected: true",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			transport := Transport{}
+			err := Options(WithUserAgentComment(tc.comment)).apply(&transport)
+			if tc.ok {
+				if err != nil {
+					t.Errorf("expected no error, got %s", err)
+				}
+				if len(transport.uaComments) != 1 || transport.uaComments[0] != tc.expect {
+					t.Errorf("expected comments=[%s], got=%v", tc.expect, transport.uaComments)
+				}
+			} else {
+				if err == nil {
+					t.Errorf("expected an error, got nil")
+				}
+				if len(transport.uaComments) != 0 {
+					t.Errorf("invalid comments must not be added: %v", transport.uaComments)
+				}
+			}
+		})
+	}
+}
+
+func TestWithUserAgentSuffix(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		if WithUserAgentSuffix(" ") != nil {
+			t.Errorf("WithUserAgentSuffix with empty suffix must return nil")
+		}
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		transport := Transport{}
+		err := Options(WithUserAgentSuffix(" my-app/1.0 ")).apply(&transport)
+		if err != nil {
+			t.Errorf("expected no error, got %s", err)
+		}
+		if transport.uaSuffix != "my-app/1.0" {
+			t.Errorf("expected suffix=%q, got=%q", "my-app/1.0", transport.uaSuffix)
+		}
+	})
+
+	t.Run("control-characters", func(t *testing.T) {
+		transport := Transport{}
+		err := Options(WithUserAgentSuffix("my-app/1.0\r\nX-Injected: true")).apply(&transport)
+		if err == nil {
+			t.Errorf("expected an error, got nil")
+		}
+		if transport.uaSuffix != "" {
+			t.Errorf("invalid suffix must not be set: %q", transport.uaSuffix)
+		}
+	})
+
+	t.Run("with-user-agent", func(t *testing.T) {
+		_, err := newTransport(99, WithUserAgentSuffix("my-app/1.0"), WithUserAgent("foo/1.0"))
+		if err == nil {
+			t.Errorf("expected an error when used with WithUserAgent, got nil")
+		}
+	})
+}
+
+func TestWithExpiryMargin(t *testing.T) {
+	tt := []struct {
+		name   string
+		margin time.Duration
+		ok     bool
+	}{
+		{
+			name:   "zero",
+			margin: 0,
+			ok:     true,
+		},
+		{
+			name:   "valid",
+			margin: 5 * time.Minute,
+			ok:     true,
+		},
+		{
+			name:   "negative",
+			margin: -time.Minute,
+		},
+		{
+			name:   "hour",
+			margin: time.Hour,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			transport := Transport{}
+			err := Options(WithExpiryMargin(tc.margin)).apply(&transport)
+			if tc.ok {
+				if err != nil {
+					t.Errorf("expected no error, got %s", err)
+				}
+				if transport.expiryMargin != tc.margin {
+					t.Errorf("expected expiryMargin=%s, got=%s", tc.margin, transport.expiryMargin)
+				}
+			} else if err == nil {
+				t.Errorf("expected an error, got nil")
+			}
+		})
+	}
+}
+
+func TestWithStaticInstallationToken(t *testing.T) {
+	tt := []struct {
+		name  string
+		token InstallationToken
+		err   error
+		ok    bool
+	}{
+		{
+			name:  "valid",
+			token: InstallationToken{Token: "ghs_static", Exp: time.Now().Add(time.Hour)},
+			ok:    true,
+		},
+		{
+			name:  "valid-without-expiry",
+			token: InstallationToken{Token: "ghs_static"},
+			ok:    true,
+		},
+		{
+			name:  "empty",
+			token: InstallationToken{Exp: time.Now().Add(time.Hour)},
+		},
+		{
+			name:  "expired",
+			token: InstallationToken{Token: "ghs_static", Exp: time.Now().Add(-time.Minute)},
+			err:   ErrInstallationTokenExpired,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			transport := Transport{}
+			err := Options(WithStaticInstallationToken(tc.token)).apply(&transport)
+			if tc.ok {
+				if err != nil {
+					t.Errorf("expected no error, got %s", err)
+				}
+				if transport.static == nil || transport.static.Token != tc.token.Token {
+					t.Errorf("expected static token to be configured")
+				}
+				return
+			}
+
+			if err == nil {
+				t.Errorf("expected an error, got nil")
+			}
+
+			if tc.err != nil && !errors.Is(err, tc.err) {
+				t.Errorf("expected error=%s, got=%s", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestWithJWTExpiry(t *testing.T) {
+	tt := []struct {
+		name   string
+		expiry time.Duration
+		expect time.Duration
+		ok     bool
+	}{
+		{
+			name:   "valid",
+			expiry: 8 * time.Minute,
+			expect: 8 * time.Minute,
+			ok:     true,
+		},
+		{
+			name:   "clamped",
+			expiry: time.Hour,
+			expect: maxJWTExpiry,
+			ok:     true,
+		},
+		{
+			name: "zero",
+		},
+		{
+			name:   "minute",
+			expiry: time.Minute,
+		},
+		{
+			name:   "negative",
+			expiry: -time.Minute,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			transport := Transport{}
+			err := Options(WithJWTExpiry(tc.expiry)).apply(&transport)
+			if tc.ok {
+				if err != nil {
+					t.Errorf("expected no error, got %s", err)
+				}
+				if transport.jwtExpiry != tc.expect {
+					t.Errorf("expected jwtExpiry=%s, got=%s", tc.expect, transport.jwtExpiry)
+				}
+			} else if err == nil {
+				t.Errorf("expected an error, got nil")
+			}
+		})
+	}
+}
+
+func TestWithJWTClockSkew(t *testing.T) {
+	tt := []struct {
+		name string
+		skew time.Duration
+		ok   bool
+	}{
+		{
+			name: "valid",
+			skew: time.Minute,
+			ok:   true,
+		},
+		{
+			name: "max",
+			skew: 5 * time.Minute,
+			ok:   true,
+		},
+		{
+			name: "zero",
+		},
+		{
+			name: "negative",
+			skew: -time.Second,
+		},
+		{
+			name: "too-large",
+			skew: 6 * time.Minute,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			transport := Transport{}
+			err := Options(WithJWTClockSkew(tc.skew)).apply(&transport)
+			if tc.ok {
+				if err != nil {
+					t.Errorf("expected no error, got %s", err)
+				}
+				if transport.jwtSkew != tc.skew {
+					t.Errorf("expected jwtSkew=%s, got=%s", tc.skew, transport.jwtSkew)
+				}
+			} else if err == nil {
+				t.Errorf("expected an error, got nil")
+			}
+		})
+	}
+}
+
+func TestWithStrictPermissionNames(t *testing.T) {
+	tt := []struct {
+		name    string
+		opts    []Option
+		ok      bool
+		message string
+	}{
+		{
+			name: "not-enabled",
+			opts: []Option{WithPermissions("content:read")},
+			ok:   true,
+		},
+		{
+			name: "no-permissions",
+			opts: []Option{WithStrictPermissionNames()},
+			ok:   true,
+		},
+		{
+			name: "valid",
+			opts: []Option{
+				WithStrictPermissionNames(),
+				WithPermissions("contents:read", "pull_requests:write"),
+				WithPermissionsMap(map[string]string{"issues": "write"}),
+			},
+			ok: true,
+		},
+		{
+			name: "typo",
+			opts: []Option{
+				WithPermissions("content:read", "issues:write"),
+				WithStrictPermissionNames(),
+			},
+			message: `unknown permission "content" (did you mean "contents"?)`,
+		},
+		{
+			name: "typo-map",
+			opts: []Option{
+				WithStrictPermissionNames(),
+				WithPermissionsMap(map[string]string{"pull_request": "write"}),
+			},
+			message: `unknown permission "pull_request" (did you mean "pull_requests"?)`,
+		},
+		{
+			name: "unknown",
+			opts: []Option{
+				WithStrictPermissionNames(),
+				WithPermissions("foo:read"),
+			},
+			message: `unknown permission "foo"`,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := newTransport(99, tc.opts...)
+			if tc.ok {
+				if err != nil {
+					t.Errorf("expected no error, got %s", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("expected an error, got nil")
+			}
+
+			if !strings.Contains(err.Error(), tc.message) {
+				t.Errorf("expected error to contain %q, got %q", tc.message, err)
+			}
+		})
+	}
+}
+
+func TestClosestPermissionName(t *testing.T) {
+	tt := []struct {
+		name   string
+		expect string
+	}{
+		{name: "contents", expect: "contents"},
+		{name: "content", expect: "contents"},
+		{name: "isues", expect: "issues"},
+		{name: "pullrequests", expect: "pull_requests"},
+		{name: "workflow", expect: "workflows"},
+		{name: "foo", expect: ""},
+		{name: "something_else", expect: ""},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if v := closestPermissionName(tc.name); v != tc.expect {
+				t.Errorf("expected=%q, got=%q", tc.expect, v)
+			}
+		})
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tt := []struct {
+		a, b   string
+		expect int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"abc", "", 3},
+		{"abc", "abc", 0},
+		{"content", "contents", 1},
+		{"kitten", "sitting", 3},
+	}
+	for _, tc := range tt {
+		if v := editDistance(tc.a, tc.b); v != tc.expect {
+			t.Errorf("editDistance(%q, %q) expected=%d, got=%d", tc.a, tc.b, tc.expect, v)
+		}
+	}
+}
+
+func TestOption_String(t *testing.T) {
+	tt := []struct {
+		option Option
+		expect string
+	}{
+		{WithEndpoint("https://api.go-githubapp.test/"), "WithEndpoint"},
+		{WithRepositories("foo"), "WithRepositories"},
+		{WithPermissions("issues:write"), "WithPermissions"},
+		{WithAdditionalHosts("ghes.go-githubapp.test"), "WithAdditionalHosts"},
+		{WithTokenRefreshCallback(func(context.Context, InstallationToken) {}), "WithTokenRefreshCallback"},
+		{WithMetrics(&testRecorder{}), "WithMetrics"},
+		{Options(WithOwner("foo")), "Options"},
+		{&funcOption{f: func(*Transport) error { return nil }}, "Option"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.expect, func(t *testing.T) {
+			if v := tc.option.String(); v != tc.expect {
+				t.Errorf("expected=%q, got=%q", tc.expect, v)
+			}
+
+			if v := fmt.Sprint(tc.option); v != tc.expect {
+				t.Errorf("expected fmt.Stringer=%q, got=%q", tc.expect, v)
+			}
+		})
+	}
+}
+
+func TestOption_ErrorAttribution(t *testing.T) {
+	tt := []struct {
+		name     string
+		option   Option
+		contains []string
+	}{
+		{
+			name:     "WithRepositories",
+			option:   WithRepositories("foo/bar/baz"),
+			contains: []string{"WithRepositories: "},
+		},
+		{
+			name:     "WithEndpoint",
+			option:   WithEndpoint("ftp://api.go-githubapp.test/"),
+			contains: []string{"WithEndpoint: invalid url scheme"},
+		},
+		{
+			name:     "WithAdditionalHosts",
+			option:   WithAdditionalHosts("https://ghes.go-githubapp.test/foo"),
+			contains: []string{"WithAdditionalHosts: "},
+		},
+		{
+			name: "Options",
+			option: Options(
+				WithEndpoint("https://api.go-githubapp.test/"),
+				nil,
+				WithInstallationID(0),
+			),
+			contains: []string{"Options: option[2]: WithInstallationID: installation id cannot be zero"},
+		},
+		{
+			name: "Options-nested",
+			option: Options(
+				WithOwner("foo"),
+				Options(WithPermissions("issues:yes")),
+			),
+			contains: []string{"Options: option[1]: Options: option[0]: WithPermissions: invalid permissions"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			transport := Transport{}
+			err := tc.option.apply(&transport)
+			if err == nil {
+				t.Fatalf("expected an error, got nil")
+			}
+
+			for _, item := range tc.contains {
+				if !strings.Contains(err.Error(), item) {
+					t.Errorf("expected error to contain %q, got %q", item, err)
+				}
+			}
+		})
+	}
+}
+
+func TestOptions_Errors(t *testing.T) {
+	tt := []struct {
+		name     string
+		options  []Option
+		contains []string
+		count    int
+	}{
+		{
+			name: "single",
+			options: []Option{
+				WithOwner("foo"),
+				WithPermissions("issues:yes"),
+			},
+			contains: []string{
+				"option[1]: WithPermissions: invalid permissions",
+			},
+			count: 1,
+		},
+		{
+			name: "multiple",
+			options: []Option{
+				WithOwner("-invalid-"),
+				WithPermissions("issues:yes"),
+				WithInstallationID(1),
+				WithInstallationID(2),
+			},
+			contains: []string{
+				"option[0]: WithOwner: invalid username",
+				"option[1]: WithPermissions: invalid permissions",
+				"option[3]: WithInstallationID: installation id is already configured(1): 2",
+			},
+			count: 3,
+		},
+		{
+			name: "duplicates",
+			options: []Option{
+				WithPermissions("issues:yes"),
+				WithInstallationID(0),
+				WithPermissions("issues:yes"),
+				WithInstallationID(0),
+			},
+			contains: []string{
+				"option[0]: WithPermissions: invalid permissions",
+				"option[1]: WithInstallationID: installation id cannot be zero",
+			},
+			count: 2,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			transport := Transport{}
+			err := Options(tc.options...).apply(&transport)
+			if err == nil {
+				t.Fatalf("expected an error, got nil")
+			}
+
+			for _, item := range tc.contains {
+				if !strings.Contains(err.Error(), item) {
+					t.Errorf("expected error to contain %q, got %q", item, err)
+				}
+			}
+
+			if v := strings.Count(err.Error(), "option["); v != tc.count {
+				t.Errorf("expected %d errors, got %d: %q", tc.count, v, err)
+			}
+		})
+	}
+}
+
+func TestWithoutBotMetadata(t *testing.T) {
+	transport := Transport{}
+	opts := Options(WithoutBotMetadata())
+	err := opts.apply(&transport)
+	if err != nil {
+		t.Errorf("expected no error, got %s", err)
+	}
+
+	if !transport.skipBot {
+		t.Errorf("transport.skipBot should be true")
+	}
+}
+
+func TestWithEagerBotMetadata(t *testing.T) {
+	transport := Transport{}
+	err := Options(WithEagerBotMetadata()).apply(&transport)
+	if err != nil {
+		t.Errorf("expected no error, got %s", err)
+	}
+
+	if !transport.eagerBot {
+		t.Errorf("transport.eagerBot should be true")
+	}
+}
+
+func TestWithTokenRefreshCallback(t *testing.T) {
+	t.Run("nil", func(t *testing.T) {
+		if WithTokenRefreshCallback(nil) != nil {
+			t.Errorf("WithTokenRefreshCallback with nil callback must return nil")
+		}
+	})
+
+	t.Run("multiple", func(t *testing.T) {
+		transport := Transport{}
+		err := Options(
+			WithTokenRefreshCallback(func(context.Context, InstallationToken) {}),
+			WithTokenRefreshCallback(func(context.Context, InstallationToken) {}),
+		).apply(&transport)
+		if err != nil {
+			t.Errorf("expected no error, got %s", err)
+		}
+
+		if len(transport.onTokenRefresh) != 2 {
+			t.Errorf("expected 2 callbacks, got %d", len(transport.onTokenRefresh))
+		}
+	})
+}
+
+func TestWithRequestEditor(t *testing.T) {
+	t.Run("nil", func(t *testing.T) {
+		if WithRequestEditor(nil) != nil {
+			t.Errorf("WithRequestEditor with nil editor must return nil")
+		}
+	})
+
+	t.Run("multiple", func(t *testing.T) {
+		transport := Transport{}
+		editor := func(*http.Request) error { return nil }
+		err := Options(WithRequestEditor(editor), WithRequestEditor(editor)).apply(&transport)
+		if err != nil {
+			t.Errorf("expected no error, got %s", err)
+		}
+
+		if len(transport.editors) != 2 {
+			t.Errorf("expected 2 editors, got %d", len(transport.editors))
+		}
+	})
+}
+
+func TestWithPreserveAuthorizationHeader(t *testing.T) {
+	transport := Transport{}
+	opts := Options(WithPreserveAuthorizationHeader())
+	err := opts.apply(&transport)
+	if err != nil {
+		t.Errorf("expected no error, got %s", err)
+	}
+
+	if !transport.preserveAuthz {
+		t.Errorf("transport.preserveAuthz should be true")
+	}
+}
+
+func TestWithAcceptHeader(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		if WithAcceptHeader("") != nil {
+			t.Errorf("WithAcceptHeader with empty value must return nil")
+		}
+	})
+
+	t.Run("non-empty", func(t *testing.T) {
+		transport := Transport{}
+		const accept = "application/vnd.github.raw"
+		err := Options(WithAcceptHeader(accept)).apply(&transport)
+		if err != nil {
+			t.Errorf("expected no error, got %s", err)
+		}
+
+		if transport.accept != accept {
+			t.Errorf("transport.accept should be %s, got %s", accept, transport.accept)
+		}
+	})
+}
+
+func TestWithAPIVersion(t *testing.T) {
+	tt := []struct {
+		name  string
+		input string
+		ok    bool
+	}{
+		{name: "invalid-format", input: "2022/11/28"},
+		{name: "invalid-date", input: "2022-13-28"},
+		{name: "invalid-string", input: "v3"},
+		{name: "valid", input: "2022-11-28", ok: true},
+		{name: "valid-future", input: "2026-03-10", ok: true},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			transport := Transport{}
+			err := Options(WithAPIVersion(tc.input)).apply(&transport)
+			if tc.ok {
+				if err != nil {
+					t.Errorf("expected no error, got %s", err)
+				}
+				if transport.apiVersion != tc.input {
+					t.Errorf("expected apiVersion=%s, got=%s", tc.input, transport.apiVersion)
+				}
+			} else {
+				if err == nil {
+					t.Errorf("expected an error, got nil")
+				}
+				if transport.apiVersion != "" {
+					t.Errorf("on error transport.apiVersion must be empty")
+				}
+			}
+		})
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		if WithAPIVersion("") != nil {
+			t.Errorf("WithAPIVersion with empty version must return nil")
+		}
+	})
+}
@@ -0,0 +1,68 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// jwk is JSON web key representation of RSA public key.
+//
+// https://datatracker.ietf.org/doc/html/rfc7517
+type jwk struct {
+	KeyType   string `json:"kty"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	Modulus   string `json:"n"`
+	Exponent  string `json:"e"`
+}
+
+// PublicKeyPEM returns public key of the signer used by the [Transport],
+// PEM encoded in PKIX format. This can be published to allow external parties
+// to verify JWTs minted by the app.
+func (t *Transport) PublicKeyPEM() ([]byte, error) {
+	if t.signer == nil {
+		return nil, errors.New("githubapp: signer is not configured")
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(t.signer.Public())
+	if err != nil {
+		return nil, fmt.Errorf("githubapp: failed to marshal public key: %w", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+// PublicKeyJWK returns public key of the signer used by the [Transport],
+// as JSON web key (JWK). This is similar to [Transport.PublicKeyPEM], but
+// in a format suitable for publishing via JWKS.
+func (t *Transport) PublicKeyJWK() ([]byte, error) {
+	if t.signer == nil {
+		return nil, errors.New("githubapp: signer is not configured")
+	}
+
+	pub, ok := t.signer.Public().(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("githubapp: unsupported key type: %T", t.signer.Public())
+	}
+
+	data, err := json.Marshal(jwk{
+		KeyType:   "RSA",
+		Algorithm: "RS256",
+		Use:       "sig",
+		Modulus:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		Exponent:  base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("githubapp: failed to marshal JWK: %w", err)
+	}
+	return data, nil
+}
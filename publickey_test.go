@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"testing"
+
+	"github.com/tprasadtp/go-githubapp/internal/testkeys"
+)
+
+func TestTransport_PublicKeyPEM(t *testing.T) {
+	t.Run("no-signer", func(t *testing.T) {
+		transport := &Transport{}
+		if _, err := transport.PublicKeyPEM(); err == nil {
+			t.Errorf("expected an error, got nil")
+		}
+	})
+
+	t.Run("rsa", func(t *testing.T) {
+		transport := &Transport{signer: testkeys.RSA2048()}
+		data, err := transport.PublicKeyPEM()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		block, _ := pem.Decode(data)
+		if block == nil || block.Type != "PUBLIC KEY" {
+			t.Fatalf("public key is not PEM encoded")
+		}
+
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			t.Fatalf("failed to parse public key: %s", err)
+		}
+
+		if !testkeys.RSA2048().PublicKey.Equal(pub) {
+			t.Errorf("public key does not match signer's public key")
+		}
+	})
+}
+
+func TestTransport_PublicKeyJWK(t *testing.T) {
+	t.Run("no-signer", func(t *testing.T) {
+		transport := &Transport{}
+		if _, err := transport.PublicKeyJWK(); err == nil {
+			t.Errorf("expected an error, got nil")
+		}
+	})
+
+	t.Run("unsupported-key", func(t *testing.T) {
+		transport := &Transport{signer: testkeys.ECP256()}
+		if _, err := transport.PublicKeyJWK(); err == nil {
+			t.Errorf("expected an error, got nil")
+		}
+	})
+
+	t.Run("rsa", func(t *testing.T) {
+		transport := &Transport{signer: testkeys.RSA2048()}
+		data, err := transport.PublicKeyJWK()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		v := jwk{}
+		err = json.Unmarshal(data, &v)
+		if err != nil {
+			t.Fatalf("JWK is not valid JSON: %s", err)
+		}
+
+		if v.KeyType != "RSA" || v.Algorithm != "RS256" {
+			t.Errorf("unexpected kty=%s, alg=%s", v.KeyType, v.Algorithm)
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(v.Modulus)
+		if err != nil {
+			t.Fatalf("modulus is not base64 url encoded: %s", err)
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(v.Exponent)
+		if err != nil {
+			t.Fatalf("exponent is not base64 url encoded: %s", err)
+		}
+
+		pub := testkeys.RSA2048().PublicKey
+		if new(big.Int).SetBytes(n).Cmp(pub.N) != 0 {
+			t.Errorf("modulus does not match")
+		}
+
+		if new(big.Int).SetBytes(e).Int64() != int64(pub.E) {
+			t.Errorf("exponent does not match")
+		}
+	})
+}
@@ -0,0 +1,282 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/tprasadtp/go-githubapp/internal/api"
+)
+
+// ErrRateLimitNotEnabled is returned by [Transport.RateLimit] when rate limiting
+// is not enabled, which is possible on GitHub Enterprise Server.
+const ErrRateLimitNotEnabled = Error("githubapp: rate limiting is not enabled")
+
+var (
+	_ error = (*RateLimitError)(nil)
+)
+
+// Rate limit headers returned by GitHub API.
+const (
+	retryAfterHeader         = "Retry-After"
+	rateLimitLimitHeader     = "X-RateLimit-Limit"
+	rateLimitRemainingHeader = "X-RateLimit-Remaining"
+	rateLimitUsedHeader      = "X-RateLimit-Used"
+	rateLimitResetHeader     = "X-RateLimit-Reset"
+	rateLimitResourceHeader  = "X-RateLimit-Resource"
+)
+
+// RateLimit is rate limit of a resource as reported by GitHub API
+// via 'X-RateLimit-*' headers.
+//
+// https://docs.github.com/en/rest/using-the-rest-api/rate-limits-for-the-rest-api#checking-the-status-of-your-rate-limit
+type RateLimit struct {
+	// Limit is maximum number of requests allowed per hour. This is zero,
+	// if 'X-RateLimit-Limit' header is missing.
+	Limit int `json:"limit,omitempty" yaml:"limit,omitempty"`
+
+	// Remaining is number of requests remaining in the current window.
+	Remaining int `json:"remaining" yaml:"remaining"`
+
+	// Used is number of requests made in the current window. This is zero,
+	// if 'X-RateLimit-Used' header is missing.
+	Used int `json:"used,omitempty" yaml:"used,omitempty"`
+
+	// Reset is time at which the current window resets.
+	Reset time.Time `json:"reset" yaml:"reset"`
+
+	// Resource is rate limit resource like "core".
+	Resource string `json:"resource,omitempty" yaml:"resource,omitempty"`
+}
+
+// RateLimitError is returned when GitHub API rate limits creating installation
+// access tokens and wait budget configured via [WithRateLimitRetry] is exhausted.
+// Use [errors.As] to extract it from errors returned by this package.
+type RateLimitError struct {
+	// Reset is the time at which rate limit is expected to be reset.
+	// Callers can use it to schedule retries.
+	Reset time.Time
+
+	// Err is the underlying API error.
+	Err *APIError
+}
+
+// Implements Error() interface.
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited until %s: %s", e.Reset.Format(time.RFC3339), e.Err)
+}
+
+// Unwrap returns the underlying [APIError].
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+// WithRateLimitRetry configures [Transport] to wait for rate limits to reset
+// and retry, when GitHub API rate limits creating installation access tokens.
+// Total time spent waiting is bounded by maxWait and context deadline.
+//
+// Both primary and secondary rate limits are handled. If the wait budget
+// is exhausted, [RateLimitError] is returned. When not specified or zero,
+// requests are not retried.
+func WithRateLimitRetry(maxWait time.Duration) Option {
+	return &funcOption{
+		name: "WithRateLimitRetry",
+		f: func(t *Transport) error {
+			if maxWait < 0 {
+				return fmt.Errorf("rate limit max wait cannot be negative: %s", maxWait)
+			}
+			t.rateLimitWait = maxWait
+			return nil
+		},
+	}
+}
+
+// rateLimitReset returns time at which rate limit resets if response
+// indicates request was rate limited.
+//
+// https://docs.github.com/en/rest/using-the-rest-api/rate-limits-for-the-rest-api#exceeding-the-rate-limit
+func rateLimitReset(resp *http.Response, now time.Time) (time.Time, bool) {
+	switch resp.StatusCode {
+	case http.StatusForbidden, http.StatusTooManyRequests:
+	default:
+		return time.Time{}, false
+	}
+
+	// Secondary rate limits include Retry-After header (in seconds).
+	if reset, ok := retryAfter(resp.Header, now); ok {
+		return reset, true
+	}
+
+	// All responses include x-ratelimit-reset header, but rate limit
+	// is only exceeded if x-ratelimit-remaining is zero.
+	if resp.Header.Get(rateLimitRemainingHeader) == "0" {
+		epoch, err := strconv.ParseInt(resp.Header.Get(rateLimitResetHeader), 10, 64)
+		if err == nil {
+			return time.Unix(epoch, 0), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// RateLimits is rate limit status of all resources returned by [Transport.RateLimit].
+type RateLimits struct {
+	// Core is rate limit of REST API.
+	Core RateLimit `json:"core" yaml:"core"`
+
+	// GraphQL is rate limit of GraphQL API.
+	GraphQL RateLimit `json:"graphql" yaml:"graphql"`
+
+	// Resources is rate limit of all resources, including core and graphql,
+	// keyed by resource name, like "search" or "code_scanning_upload".
+	Resources map[string]RateLimit `json:"resources,omitempty" yaml:"resources,omitempty"`
+}
+
+// RateLimit returns current rate limit status of the installation, or of the app
+// if no installation is configured. Querying rate limit status does not count
+// against the rate limit. If rate limiting is not enabled, which is possible on
+// GitHub Enterprise Server, this returns [ErrRateLimitNotEnabled].
+//
+// https://docs.github.com/en/rest/rate-limit/rate-limit?apiVersion=2022-11-28#get-rate-limit-status-for-the-authenticated-user
+func (t *Transport) RateLimit(ctx context.Context) (RateLimits, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	u := t.baseURL.JoinPath("rate_limit")
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return RateLimits{}, fmt.Errorf("githubapp(ratelimit): failed to build request: %w", err)
+	}
+
+	// Installation token requests do not set these headers.
+	r.Header.Set(api.AcceptHeader, api.AcceptHeaderValue)
+	r.Header.Set(api.VersionHeader, t.versionHeaderValue())
+	r.Header.Set(api.UAHeader, t.userAgent())
+
+	client := t.internalClient()
+	resp, err := client.Do(r)
+	if err != nil {
+		return RateLimits{}, fmt.Errorf("githubapp(ratelimit): failed to get rate limit: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return RateLimits{}, fmt.Errorf("githubapp(ratelimit): failed to read response: %w", err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return RateLimits{}, fmt.Errorf("%w: %w", ErrRateLimitNotEnabled, newAPIError(resp, data))
+	default:
+		return RateLimits{}, fmt.Errorf("githubapp(ratelimit): failed to get rate limit: %w",
+			newAPIError(resp, data))
+	}
+
+	rateLimitResp := api.RateLimitResponse{}
+	err = json.Unmarshal(data, &rateLimitResp)
+	if err != nil {
+		return RateLimits{}, fmt.Errorf("githubapp(ratelimit): failed to unmarshal response: %w", err)
+	}
+
+	limits := RateLimits{
+		Resources: make(map[string]RateLimit, len(rateLimitResp.Resources)),
+	}
+	for name, item := range rateLimitResp.Resources {
+		if item == nil {
+			continue
+		}
+		limits.Resources[name] = RateLimit{
+			Limit:     item.Limit,
+			Remaining: item.Remaining,
+			Used:      item.Used,
+			Reset:     time.Unix(item.Reset, 0),
+			Resource:  name,
+		}
+	}
+	limits.Core = limits.Resources["core"]
+	limits.GraphQL = limits.Resources["graphql"]
+	return limits, nil
+}
+
+// parseRateLimit parses rate limit from 'X-RateLimit-*' headers. Returns false
+// if 'X-RateLimit-Remaining' or 'X-RateLimit-Reset' header is missing or invalid.
+// Other headers are optional, and are ignored if invalid.
+func parseRateLimit(h http.Header) (RateLimit, bool) {
+	remaining, err := strconv.Atoi(h.Get(rateLimitRemainingHeader))
+	if err != nil || remaining < 0 {
+		return RateLimit{}, false
+	}
+
+	epoch, err := strconv.ParseInt(h.Get(rateLimitResetHeader), 10, 64)
+	if err != nil {
+		return RateLimit{}, false
+	}
+
+	rl := RateLimit{
+		Remaining: remaining,
+		Reset:     time.Unix(epoch, 0),
+		Resource:  h.Get(rateLimitResourceHeader),
+	}
+
+	if v, err := strconv.Atoi(h.Get(rateLimitLimitHeader)); err == nil && v >= 0 {
+		rl.Limit = v
+	}
+
+	if v, err := strconv.Atoi(h.Get(rateLimitUsedHeader)); err == nil && v >= 0 {
+		rl.Used = v
+	}
+	return rl, true
+}
+
+// saveRateLimit saves rate limit reported by the response, if any.
+func (t *Transport) saveRateLimit(resp *http.Response) {
+	if resp == nil {
+		return
+	}
+
+	if rl, ok := parseRateLimit(resp.Header); ok {
+		t.rateLimit.Store(rl)
+	}
+}
+
+// retryAfter returns time after which request can be retried as indicated
+// by 'Retry-After' header (in seconds).
+func retryAfter(h http.Header, now time.Time) (time.Time, bool) {
+	if v := h.Get(retryAfterHeader); v != "" {
+		seconds, err := strconv.ParseUint(v, 10, 32)
+		if err == nil {
+			return now.Add(time.Duration(seconds) * time.Second), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// waitFor waits for duration d, unless context is cancelled or its deadline
+// occurs before d elapses. Returns true if wait was successful.
+func waitFor(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		return false
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,528 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tprasadtp/go-githubapp/internal/api"
+	"github.com/tprasadtp/go-githubapp/internal/testkeys"
+)
+
+func TestRateLimitReset(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	tt := []struct {
+		name    string
+		status  int
+		headers map[string]string
+		expect  time.Time
+		ok      bool
+	}{
+		{
+			name:    "not-rate-limited-status",
+			status:  http.StatusNotFound,
+			headers: map[string]string{retryAfterHeader: "2"},
+		},
+		{
+			name:   "forbidden-without-headers",
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "forbidden-with-remaining",
+			status: http.StatusForbidden,
+			headers: map[string]string{
+				rateLimitRemainingHeader: "10",
+				rateLimitResetHeader:     strconv.FormatInt(now.Unix(), 10),
+			},
+		},
+		{
+			name:    "retry-after",
+			status:  http.StatusForbidden,
+			headers: map[string]string{retryAfterHeader: "2"},
+			expect:  now.Add(2 * time.Second),
+			ok:      true,
+		},
+		{
+			name:    "retry-after-429",
+			status:  http.StatusTooManyRequests,
+			headers: map[string]string{retryAfterHeader: "60"},
+			expect:  now.Add(time.Minute),
+			ok:      true,
+		},
+		{
+			name:   "ratelimit-reset",
+			status: http.StatusForbidden,
+			headers: map[string]string{
+				rateLimitRemainingHeader: "0",
+				rateLimitResetHeader:     strconv.FormatInt(now.Add(time.Hour).Unix(), 10),
+			},
+			expect: now.Add(time.Hour),
+			ok:     true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tc.status, Header: make(http.Header)}
+			for k, v := range tc.headers {
+				resp.Header.Set(k, v)
+			}
+			reset, ok := rateLimitReset(resp, now)
+			if ok != tc.ok {
+				t.Errorf("expected ok=%t, got=%t", tc.ok, ok)
+			}
+			if !reset.Equal(tc.expect) {
+				t.Errorf("expected reset=%s, got=%s", tc.expect, reset)
+			}
+		})
+	}
+}
+
+func TestParseRateLimit(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	tt := []struct {
+		name    string
+		headers map[string]string
+		expect  RateLimit
+		ok      bool
+	}{
+		{
+			name: "no-headers",
+		},
+		{
+			name:    "missing-reset",
+			headers: map[string]string{rateLimitRemainingHeader: "10"},
+		},
+		{
+			name:    "missing-remaining",
+			headers: map[string]string{rateLimitResetHeader: strconv.FormatInt(now.Unix(), 10)},
+		},
+		{
+			name: "invalid-remaining",
+			headers: map[string]string{
+				rateLimitRemainingHeader: "-1",
+				rateLimitResetHeader:     strconv.FormatInt(now.Unix(), 10),
+			},
+		},
+		{
+			name: "invalid-reset",
+			headers: map[string]string{
+				rateLimitRemainingHeader: "10",
+				rateLimitResetHeader:     "tomorrow",
+			},
+		},
+		{
+			name: "valid",
+			headers: map[string]string{
+				rateLimitRemainingHeader: "10",
+				rateLimitResetHeader:     strconv.FormatInt(now.Unix(), 10),
+			},
+			expect: RateLimit{Remaining: 10, Reset: now},
+			ok:     true,
+		},
+		{
+			name: "all-headers",
+			headers: map[string]string{
+				rateLimitLimitHeader:     "5000",
+				rateLimitRemainingHeader: "4990",
+				rateLimitUsedHeader:      "10",
+				rateLimitResetHeader:     strconv.FormatInt(now.Unix(), 10),
+				rateLimitResourceHeader:  "core",
+			},
+			expect: RateLimit{Limit: 5000, Remaining: 4990, Used: 10, Reset: now, Resource: "core"},
+			ok:     true,
+		},
+		{
+			name: "invalid-optional-headers",
+			headers: map[string]string{
+				rateLimitLimitHeader:     "unlimited",
+				rateLimitRemainingHeader: "10",
+				rateLimitUsedHeader:      "-1",
+				rateLimitResetHeader:     strconv.FormatInt(now.Unix(), 10),
+			},
+			expect: RateLimit{Remaining: 10, Reset: now},
+			ok:     true,
+		},
+		{
+			name: "exhausted",
+			headers: map[string]string{
+				rateLimitRemainingHeader: "0",
+				rateLimitResetHeader:     strconv.FormatInt(now.Unix(), 10),
+			},
+			expect: RateLimit{Remaining: 0, Reset: now},
+			ok:     true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := make(http.Header)
+			for k, v := range tc.headers {
+				h.Set(k, v)
+			}
+			rl, ok := parseRateLimit(h)
+			if ok != tc.ok {
+				t.Errorf("expected ok=%t, got=%t", tc.ok, ok)
+			}
+			if rl.Limit != tc.expect.Limit || rl.Remaining != tc.expect.Remaining ||
+				rl.Used != tc.expect.Used || rl.Resource != tc.expect.Resource ||
+				!rl.Reset.Equal(tc.expect.Reset) {
+				t.Errorf("expected rate limit=%+v, got=%+v", tc.expect, rl)
+			}
+		})
+	}
+}
+
+func TestTransport_TokenCreationQuota(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		remaining := 5000 - calls.Add(1)
+		w.Header().Set(rateLimitLimitHeader, "5000")
+		w.Header().Set(rateLimitRemainingHeader, strconv.FormatInt(remaining, 10))
+		w.Header().Set(rateLimitResetHeader, strconv.FormatInt(reset.Unix(), 10))
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"token":"ghs_token","expires_at":"2099-01-01T00:00:00Z"}`))
+	}))
+	t.Cleanup(server.Close)
+	u, _ := url.Parse(server.URL)
+
+	transport := &Transport{
+		appID:     99,
+		installID: 99,
+		baseURL:   u,
+		minter:    &jwtRS256{internal: testkeys.RSA2048()},
+		next:      http.DefaultTransport,
+	}
+
+	remaining, r := transport.TokenCreationQuota()
+	if remaining != -1 || !r.IsZero() {
+		t.Errorf("expected unknown quota before minting, got remaining=%d, reset=%s", remaining, r)
+	}
+
+	if _, ok := transport.LastRateLimit(); ok {
+		t.Errorf("expected unknown rate limit before minting")
+	}
+
+	for i := 1; i <= 2; i++ {
+		token, err := transport.InstallationToken(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if token.RateLimit == nil || token.RateLimit.Limit != 5000 || token.RateLimit.Remaining != 5000-i {
+			t.Errorf("expected token rate limit remaining=%d, got=%+v", 5000-i, token.RateLimit)
+		}
+
+		rl, ok := transport.LastRateLimit()
+		if !ok || rl.Limit != 5000 || rl.Remaining != 5000-i || !rl.Reset.Equal(reset) {
+			t.Errorf("expected last rate limit remaining=%d, got=%+v", 5000-i, rl)
+		}
+
+		remaining, r = transport.TokenCreationQuota()
+		if remaining != 5000-i {
+			t.Errorf("expected remaining=%d, got=%d", 5000-i, remaining)
+		}
+		if !r.Equal(reset) {
+			t.Errorf("expected reset=%s, got=%s", reset, r)
+		}
+	}
+}
+
+func TestTransport_InstallationToken_RateLimitRetry(t *testing.T) {
+	newServer := func(t *testing.T, calls *atomic.Int64) *url.URL {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			if calls.Add(1) == 1 {
+				w.Header().Set(retryAfterHeader, "2")
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`{"message":"You have exceeded a secondary rate limit."}`))
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"token":"ghs_token","expires_at":"2099-01-01T00:00:00Z"}`))
+		}))
+		t.Cleanup(server.Close)
+		u, _ := url.Parse(server.URL)
+		return u
+	}
+
+	t.Run("retry", func(t *testing.T) {
+		var calls atomic.Int64
+		transport := &Transport{
+			appID:         99,
+			installID:     99,
+			baseURL:       newServer(t, &calls),
+			minter:        &jwtRS256{internal: testkeys.RSA2048()},
+			next:          http.DefaultTransport,
+			rateLimitWait: 5 * time.Second,
+		}
+
+		start := time.Now()
+		token, err := transport.InstallationToken(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if token.Token != "ghs_token" {
+			t.Errorf("expected token to be populated")
+		}
+
+		if elapsed := time.Since(start); elapsed < 2*time.Second {
+			t.Errorf("expected to wait for Retry-After, waited %s", elapsed)
+		}
+
+		if v := calls.Load(); v != 2 {
+			t.Errorf("expected 2 calls, got %d", v)
+		}
+	})
+
+	t.Run("budget-exhausted", func(t *testing.T) {
+		var calls atomic.Int64
+		transport := &Transport{
+			appID:         99,
+			installID:     99,
+			baseURL:       newServer(t, &calls),
+			minter:        &jwtRS256{internal: testkeys.RSA2048()},
+			next:          http.DefaultTransport,
+			rateLimitWait: time.Second,
+		}
+
+		_, err := transport.InstallationToken(context.Background())
+		var rateLimitErr *RateLimitError
+		if !errors.As(err, &rateLimitErr) {
+			t.Fatalf("expected *RateLimitError, got %T(%s)", err, err)
+		}
+
+		if rateLimitErr.Reset.IsZero() {
+			t.Errorf("expected reset time to be populated")
+		}
+
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden {
+			t.Errorf("expected RateLimitError to wrap *APIError with status 403")
+		}
+
+		if v := calls.Load(); v != 1 {
+			t.Errorf("expected 1 call, got %d", v)
+		}
+	})
+
+	t.Run("context-deadline", func(t *testing.T) {
+		var calls atomic.Int64
+		transport := &Transport{
+			appID:         99,
+			installID:     99,
+			baseURL:       newServer(t, &calls),
+			minter:        &jwtRS256{internal: testkeys.RSA2048()},
+			next:          http.DefaultTransport,
+			rateLimitWait: time.Minute,
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		_, err := transport.InstallationToken(ctx)
+		var rateLimitErr *RateLimitError
+		if !errors.As(err, &rateLimitErr) {
+			t.Fatalf("expected *RateLimitError, got %T(%s)", err, err)
+		}
+	})
+}
+
+func TestWithRateLimitRetry(t *testing.T) {
+	t.Run("negative", func(t *testing.T) {
+		transport := Transport{}
+		err := Options(WithRateLimitRetry(-time.Second)).apply(&transport)
+		if err == nil {
+			t.Errorf("expected an error, got nil")
+		}
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		transport := Transport{}
+		err := Options(WithRateLimitRetry(time.Minute)).apply(&transport)
+		if err != nil {
+			t.Errorf("expected no error, got %s", err)
+		}
+		if transport.rateLimitWait != time.Minute {
+			t.Errorf("expected rateLimitWait=%s, got=%s", time.Minute, transport.rateLimitWait)
+		}
+	})
+}
+
+func TestTransport_RateLimit(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/app/installations/99/access_tokens") {
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"token":"ghs_token","expires_at":"2099-01-01T00:00:00Z"}`))
+			return
+		}
+
+		if v := r.Header.Get(api.AuthzHeader); v != "Bearer ghs_token" {
+			t.Errorf("expected installation token, got %q", v)
+		}
+
+		switch r.URL.Path {
+		case "/rate_limit", "/ghes/rate_limit":
+			if strings.HasPrefix(r.URL.Path, "/ghes") {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"message":"Rate limiting is not enabled."}`))
+				return
+			}
+			_, _ = fmt.Fprintf(w, `{"resources":{
+				"core":{"limit":5000,"used":1,"remaining":4999,"reset":%[1]d},
+				"graphql":{"limit":5000,"used":10,"remaining":4990,"reset":%[1]d},
+				"search":{"limit":30,"used":0,"remaining":30,"reset":%[1]d}
+			},"rate":{"limit":5000,"used":1,"remaining":4999,"reset":%[1]d}}`, reset.Unix())
+		case "/error/rate_limit":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			t.Errorf("Unknown/Invalid Request => %s", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	u, _ := url.Parse(server.URL)
+
+	newTransport := func(u *url.URL) *Transport {
+		return &Transport{
+			appID:     99,
+			installID: 99,
+			baseURL:   u,
+			minter:    &jwtRS256{internal: testkeys.RSA2048()},
+			next:      http.DefaultTransport,
+		}
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		limits, err := newTransport(u).RateLimit(context.Background())
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		if limits.Core.Limit != 5000 || limits.Core.Remaining != 4999 || limits.Core.Used != 1 ||
+			!limits.Core.Reset.Equal(reset) || limits.Core.Resource != "core" {
+			t.Errorf("unexpected core rate limit: %+v", limits.Core)
+		}
+
+		if limits.GraphQL.Remaining != 4990 || limits.GraphQL.Resource != "graphql" {
+			t.Errorf("unexpected graphql rate limit: %+v", limits.GraphQL)
+		}
+
+		if v, ok := limits.Resources["search"]; !ok || v.Limit != 30 {
+			t.Errorf("unexpected search rate limit: %+v", v)
+		}
+	})
+
+	t.Run("not-enabled", func(t *testing.T) {
+		limits, err := newTransport(u.JoinPath("ghes")).RateLimit(context.Background())
+		if !errors.Is(err, ErrRateLimitNotEnabled) {
+			t.Errorf("expected error=%s, got=%s", ErrRateLimitNotEnabled, err)
+		}
+
+		if !reflect.DeepEqual(limits, RateLimits{}) {
+			t.Errorf("expected zero value, got %+v", limits)
+		}
+	})
+
+	t.Run("api-error", func(t *testing.T) {
+		_, err := newTransport(u.JoinPath("error")).RateLimit(context.Background())
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || errors.Is(err, ErrRateLimitNotEnabled) {
+			t.Errorf("expected *APIError, got %T(%s)", err, err)
+		}
+	})
+}
+
+func TestTransport_LastRateLimit(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+	var remaining atomic.Int64
+	remaining.Store(100)
+
+	u, _ := url.Parse("https://api.go-githubapp.test/")
+	transport := &Transport{
+		appID:     99,
+		installID: 99,
+		baseURL:   u,
+		minter:    &jwtRS256{internal: testkeys.RSA2048()},
+		next: api.RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+			resp := httptest.NewRecorder()
+			switch {
+			case strings.HasSuffix(r.URL.Path, "/access_tokens"):
+				resp.Header().Set(rateLimitLimitHeader, "5000")
+				resp.Header().Set(rateLimitRemainingHeader, "4999")
+				resp.Header().Set(rateLimitResetHeader, strconv.FormatInt(reset.Unix(), 10))
+				resp.Header().Set(rateLimitResourceHeader, "core")
+				resp.WriteHeader(http.StatusCreated)
+				_, _ = resp.WriteString(`{"token":"ghs_token","expires_at":"2099-01-01T00:00:00Z"}`)
+			case r.URL.Path == "/no-headers":
+				// Rate limiting is disabled.
+				resp.WriteHeader(http.StatusOK)
+			default:
+				resp.Header().Set(rateLimitLimitHeader, "15000")
+				resp.Header().Set(rateLimitRemainingHeader, strconv.FormatInt(remaining.Add(-1), 10))
+				resp.Header().Set(rateLimitUsedHeader, strconv.FormatInt(15000-remaining.Load(), 10))
+				resp.Header().Set(rateLimitResetHeader, strconv.FormatInt(reset.Unix(), 10))
+				resp.Header().Set(rateLimitResourceHeader, "graphql")
+				resp.WriteHeader(http.StatusOK)
+			}
+			return resp.Result(), nil
+		}),
+	}
+
+	do := func(path string) {
+		r, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, u.JoinPath(path).String(), nil)
+		resp, err := transport.RoundTrip(r)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		resp.Body.Close()
+	}
+
+	if _, ok := transport.LastRateLimit(); ok {
+		t.Errorf("expected unknown rate limit before any request")
+	}
+
+	// Installation access token request is made before the request.
+	_, err := transport.InstallationToken(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	rl, ok := transport.LastRateLimit()
+	if !ok || rl.Resource != "core" || rl.Remaining != 4999 {
+		t.Errorf("expected rate limit of installation token request, got %+v", rl)
+	}
+
+	for i := 1; i <= 2; i++ {
+		do("graphql")
+		rl, ok = transport.LastRateLimit()
+		if !ok || rl.Resource != "graphql" || rl.Limit != 15000 || rl.Remaining != 100-i ||
+			rl.Used != 15000-(100-i) || !rl.Reset.Equal(reset) {
+			t.Errorf("expected rate limit remaining=%d, got %+v", 100-i, rl)
+		}
+	}
+
+	// Responses without rate limit headers do not reset the rate limit.
+	do("no-headers")
+	rl, ok = transport.LastRateLimit()
+	if !ok || rl.Resource != "graphql" || rl.Remaining != 98 {
+		t.Errorf("expected previous rate limit to be retained, got %+v", rl)
+	}
+
+	// Token creation quota is not affected by other requests.
+	if v, _ := transport.TokenCreationQuota(); v != 4999 {
+		t.Errorf("expected token creation quota remaining=4999, got %d", v)
+	}
+}
@@ -0,0 +1,281 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Backoff limits for retries.
+const (
+	minRetryBackoff = 100 * time.Millisecond
+	maxRetryBackoff = 2 * time.Second
+)
+
+// idempotentRetries is number of times bootstrap API calls are retried
+// on transient server errors, when retry budget is not configured.
+const idempotentRetries = 2
+
+// WithBootstrapRetryBudget configures [NewTransport] to retry failed bootstrap
+// API calls on network errors and server errors. Budget is shared across all
+// bootstrap API calls, thus retries of all the calls combined never exceed
+// the budget. When budget is exhausted, last error is returned.
+//
+// Budget only bounds retries and not individual requests. Use [WithTimeout] or
+// context passed to [NewTransport] to bound individual requests. Only requests to
+// get app, installation and bot user metadata are retried, as installation access
+// token requests may fail after the token is created. When not specified or zero,
+// these are retried at-most twice, on 500, 502, 503 and 504 responses.
+func WithBootstrapRetryBudget(budget time.Duration) Option {
+	return &funcOption{
+		name: "WithBootstrapRetryBudget",
+		f: func(t *Transport) error {
+			if budget < 0 {
+				return fmt.Errorf("bootstrap retry budget cannot be negative: %s", budget)
+			}
+			t.bootstrapBudget = budget
+			return nil
+		},
+	}
+}
+
+// retryBudget bounds retries across multiple calls by a deadline. If deadline
+// is not set, each call is retried at-most [idempotentRetries] times on
+// transient server errors. If timeout is non zero, each attempt is bounded
+// by the timeout.
+type retryBudget struct {
+	deadline time.Time
+	timeout  time.Duration
+}
+
+// newRetryBudget returns a retry budget which expires after d
+// and bounds each attempt by timeout.
+func newRetryBudget(d time.Duration, timeout time.Duration) *retryBudget {
+	b := &retryBudget{timeout: timeout}
+	if d > 0 {
+		b.deadline = time.Now().Add(d)
+	}
+	return b
+}
+
+// do calls f and retries it with jittered exponential backoff as long as it
+// returns retryable errors and budget is not exhausted. Calls are only made to
+// get app, installation and bot user metadata, which are idempotent.
+func (b *retryBudget) do(ctx context.Context, f func(context.Context) error) error {
+	backoff := minRetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := b.attempt(ctx, f)
+		if err == nil {
+			return nil
+		}
+
+		// Wait for [backoff/2, backoff) to avoid retries in lockstep.
+		//nolint:gosec // jitter does not require a secure random source.
+		wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)))
+		if b.deadline.IsZero() {
+			if attempt >= idempotentRetries || !isTransient(err) {
+				return err
+			}
+		} else {
+			// Attempts which timed out are retried, as long as ctx is not done.
+			timedOut := b.timeout > 0 && errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil
+			if !timedOut && !isRetryable(err) {
+				return err
+			}
+
+			// Budget is exhausted.
+			remaining := time.Until(b.deadline)
+			if remaining <= 0 {
+				return err
+			}
+			wait = min(wait, remaining)
+		}
+
+		if !waitFor(ctx, wait) {
+			return err
+		}
+
+		backoff = min(2*backoff, maxRetryBackoff)
+	}
+}
+
+// attempt calls f with a context bounded by timeout if configured.
+func (b *retryBudget) attempt(ctx context.Context, f func(context.Context) error) error {
+	if b.timeout <= 0 {
+		return f(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+	return f(ctx)
+}
+
+// isRetryable returns true if error is a network error or
+// API error with 5xx or 429 status code.
+func isRetryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= http.StatusInternalServerError ||
+			apiErr.StatusCode == http.StatusTooManyRequests
+	}
+
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return false
+}
+
+// isTransient returns true if error is an API error with
+// 500, 502, 503 or 504 status code.
+func isTransient(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusInternalServerError, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+	}
+	return false
+}
+
+// Defaults for [RetryConfig].
+const (
+	defaultMaxRetries = 3
+	defaultMaxWait    = time.Minute
+)
+
+// RetryConfig configures retries for [NewRetryRoundTripper].
+// Zero value is ready to use, and uses defaults for all fields.
+type RetryConfig struct {
+	// MaxRetries is maximum number of retries for a request.
+	// When zero or negative, requests are retried at-most 3 times.
+	MaxRetries int
+
+	// MinBackoff is the backoff before the first retry. Backoff is doubled
+	// for each subsequent retry. When zero or negative, 100ms is used.
+	MinBackoff time.Duration
+
+	// MaxBackoff is the maximum backoff between retries.
+	// When zero or negative, 2s is used.
+	MaxBackoff time.Duration
+
+	// MaxWait is the maximum time to wait before a retry, when response
+	// indicates when to retry via 'Retry-After' or rate limit headers.
+	// If server asks to wait longer, response is returned as is.
+	// When zero or negative, 1m is used.
+	MaxWait time.Duration
+}
+
+var (
+	_ http.RoundTripper = (*retryRoundTripper)(nil)
+)
+
+// retryRoundTripper retries requests with backoff.
+type retryRoundTripper struct {
+	next http.RoundTripper
+	cfg  RetryConfig
+}
+
+// NewRetryRoundTripper returns a [http.RoundTripper] which retries requests
+// on responses with 429 and 5xx status codes (except 501) and rate limited
+// responses with 403 status code. This can be used with [WithRoundTripper].
+//
+// 'Retry-After' and rate limit headers are respected if present, otherwise
+// exponential backoff is used. Requests with body are only retried if body can
+// be rewound via [http.Request.GetBody]. Errors returned by next are never retried,
+// and when retries are exhausted, last response is returned as is.
+//
+// If next is nil, [http.DefaultTransport] is used.
+func NewRetryRoundTripper(next http.RoundTripper, cfg RetryConfig) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = defaultMaxRetries
+	}
+
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = minRetryBackoff
+	}
+
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = maxRetryBackoff
+	}
+
+	if cfg.MaxWait <= 0 {
+		cfg.MaxWait = defaultMaxWait
+	}
+
+	return &retryRoundTripper{next: next, cfg: cfg}
+}
+
+// RoundTrip implements [http.RoundTripper].
+func (rt *retryRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	backoff := rt.cfg.MinBackoff
+	for attempt := 0; ; attempt++ {
+		resp, err := rt.next.RoundTrip(req)
+		if err != nil || attempt >= rt.cfg.MaxRetries {
+			return resp, err
+		}
+
+		wait, ok := retryDelay(resp, backoff, time.Now())
+		if !ok || wait > rt.cfg.MaxWait {
+			return resp, nil
+		}
+
+		// Requests with body can only be retried if it can be rewound.
+		if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+			return resp, nil
+		}
+
+		if !waitFor(req.Context(), wait) {
+			return resp, nil
+		}
+
+		// RoundTripper should not modify request.
+		clone := req.Clone(req.Context())
+		if req.GetBody != nil {
+			clone.Body, err = req.GetBody()
+			if err != nil {
+				return resp, nil
+			}
+		}
+
+		// Drain and close the body to re-use connections.
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		req = clone
+		backoff = min(2*backoff, rt.cfg.MaxBackoff)
+	}
+}
+
+// retryDelay returns delay before retrying the request, if response is retryable.
+func retryDelay(resp *http.Response, backoff time.Duration, now time.Time) (time.Duration, bool) {
+	if reset, ok := rateLimitReset(resp, now); ok {
+		return max(reset.Sub(now), 0), true
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+	case resp.StatusCode >= http.StatusInternalServerError &&
+		resp.StatusCode != http.StatusNotImplemented:
+		if reset, ok := retryAfter(resp.Header, now); ok {
+			return max(reset.Sub(now), 0), true
+		}
+	default:
+		return 0, false
+	}
+	return backoff, true
+}
@@ -0,0 +1,465 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tprasadtp/go-githubapp/internal/testdata/apitestdata"
+	"github.com/tprasadtp/go-githubapp/internal/testkeys"
+)
+
+func TestWithBootstrapRetryBudget(t *testing.T) {
+	t.Run("negative", func(t *testing.T) {
+		transport := Transport{}
+		err := Options(WithBootstrapRetryBudget(-time.Second)).apply(&transport)
+		if err == nil {
+			t.Errorf("expected an error, got nil")
+		}
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		transport := Transport{}
+		err := Options(WithBootstrapRetryBudget(time.Second)).apply(&transport)
+		if err != nil {
+			t.Errorf("expected no error, got %s", err)
+		}
+		if transport.bootstrapBudget != time.Second {
+			t.Errorf("expected budget=%s, got=%s", time.Second, transport.bootstrapBudget)
+		}
+	})
+}
+
+func TestIsRetryable(t *testing.T) {
+	tt := []struct {
+		name   string
+		err    error
+		expect bool
+	}{
+		{name: "plain-error", err: fmt.Errorf("foo")},
+		{name: "api-error-404", err: fmt.Errorf("wrapped: %w", &APIError{StatusCode: http.StatusNotFound})},
+		{name: "api-error-500", err: fmt.Errorf("wrapped: %w", &APIError{StatusCode: http.StatusInternalServerError}), expect: true},
+		{name: "api-error-429", err: &APIError{StatusCode: http.StatusTooManyRequests}, expect: true},
+		{name: "url-error", err: &url.Error{Op: "Get", URL: "/", Err: fmt.Errorf("connection reset")}, expect: true},
+		{name: "url-error-canceled", err: &url.Error{Op: "Get", URL: "/", Err: context.Canceled}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if v := isRetryable(tc.err); v != tc.expect {
+				t.Errorf("expected=%t, got=%t", tc.expect, v)
+			}
+		})
+	}
+}
+
+func TestNewTransport_BootstrapRetryBudget(t *testing.T) {
+	m := apitestdata.Get(t)
+
+	t.Run("intermittent", func(t *testing.T) {
+		var calls atomic.Int64
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var key string
+			switch r.URL.Path {
+			case "/app":
+				// Fail every other request.
+				if calls.Add(1)%2 == 1 {
+					w.WriteHeader(http.StatusBadGateway)
+					return
+				}
+				key = "get-app"
+			case fmt.Sprintf("/app/installations/%d", apitestdata.InstallationID):
+				if calls.Add(1)%2 == 1 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				key = "get-installation-by-id"
+			case fmt.Sprintf("/app/installations/%d/access_tokens", apitestdata.InstallationID):
+				key = "post-installation-token"
+				w.WriteHeader(http.StatusCreated)
+			case fmt.Sprintf("/users/%s[bot]", apitestdata.AppSlug):
+				key = "get-user-bot"
+			default:
+				t.Errorf("Unknown/Invalid Request => %s", r.URL)
+			}
+			_, _ = w.Write(m[key])
+		}))
+		t.Cleanup(server.Close)
+
+		transport, err := NewTransport(context.Background(), apitestdata.AppID, testkeys.RSA2048(),
+			WithEndpoint(server.URL),
+			WithInstallationID(apitestdata.InstallationID),
+			WithBootstrapRetryBudget(10*time.Second),
+		)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		if transport.AppName() != apitestdata.AppSlug {
+			t.Errorf("expected app name=%s, got=%s", apitestdata.AppSlug, transport.AppName())
+		}
+	})
+
+	t.Run("budget-exhausted", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		t.Cleanup(server.Close)
+
+		const budget = 500 * time.Millisecond
+		start := time.Now()
+		transport, err := NewTransport(context.Background(), apitestdata.AppID, testkeys.RSA2048(),
+			WithEndpoint(server.URL),
+			WithInstallationID(apitestdata.InstallationID),
+			WithBootstrapRetryBudget(budget),
+		)
+		elapsed := time.Since(start)
+
+		if err == nil {
+			t.Errorf("expected an error, got nil")
+		}
+
+		if transport != nil {
+			t.Errorf("expected transport to be nil")
+		}
+
+		if elapsed < budget {
+			t.Errorf("expected bootstrap to be retried for at-least %s, took %s", budget, elapsed)
+		}
+
+		// Allow some slack for the last request.
+		if elapsed > budget+time.Second {
+			t.Errorf("bootstrap took %s, which exceeds budget %s", elapsed, budget)
+		}
+	})
+
+	t.Run("no-budget", func(t *testing.T) {
+		var calls atomic.Int64
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		t.Cleanup(server.Close)
+
+		_, err := NewTransport(context.Background(), apitestdata.AppID, testkeys.RSA2048(),
+			WithEndpoint(server.URL),
+		)
+		if err == nil {
+			t.Errorf("expected an error, got nil")
+		}
+
+		if v := calls.Load(); v != 1+idempotentRetries {
+			t.Errorf("expected only idempotent retries without budget, got %d calls", v)
+		}
+	})
+}
+
+func TestNewTransport_IdempotentRetries(t *testing.T) {
+	m := apitestdata.Get(t)
+
+	t.Run("flaky", func(t *testing.T) {
+		var app, installation, bot atomic.Int64
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var key string
+			switch r.URL.Path {
+			case "/app":
+				if app.Add(1) == 1 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				key = "get-app"
+			case fmt.Sprintf("/app/installations/%d", apitestdata.InstallationID):
+				if installation.Add(1) == 1 {
+					w.WriteHeader(http.StatusBadGateway)
+					return
+				}
+				key = "get-installation-by-id"
+			case fmt.Sprintf("/app/installations/%d/access_tokens", apitestdata.InstallationID):
+				key = "post-installation-token"
+				w.WriteHeader(http.StatusCreated)
+			case fmt.Sprintf("/users/%s[bot]", apitestdata.AppSlug):
+				if bot.Add(1) == 1 {
+					w.WriteHeader(http.StatusGatewayTimeout)
+					return
+				}
+				key = "get-user-bot"
+			default:
+				t.Errorf("Unknown/Invalid Request => %s", r.URL)
+			}
+			_, _ = w.Write(m[key])
+		}))
+		t.Cleanup(server.Close)
+
+		_, err := NewTransport(context.Background(), apitestdata.AppID, testkeys.RSA2048(),
+			WithEndpoint(server.URL),
+			WithInstallationID(apitestdata.InstallationID),
+			WithEagerBotMetadata(),
+		)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		for name, v := range map[string]int64{"app": app.Load(), "installation": installation.Load(), "bot": bot.Load()} {
+			if v != 2 {
+				t.Errorf("expected %s to be fetched twice, got %d", name, v)
+			}
+		}
+	})
+
+	t.Run("access-tokens-not-retried", func(t *testing.T) {
+		var mints atomic.Int64
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/app":
+				_, _ = w.Write(m["get-app"])
+			case fmt.Sprintf("/app/installations/%d", apitestdata.InstallationID):
+				_, _ = w.Write(m["get-installation-by-id"])
+			case fmt.Sprintf("/app/installations/%d/access_tokens", apitestdata.InstallationID):
+				mints.Add(1)
+				w.WriteHeader(http.StatusBadGateway)
+			default:
+				t.Errorf("Unknown/Invalid Request => %s", r.URL)
+			}
+		}))
+		t.Cleanup(server.Close)
+
+		for _, budget := range []time.Duration{0, time.Second} {
+			mints.Store(0)
+			_, err := NewTransport(context.Background(), apitestdata.AppID, testkeys.RSA2048(),
+				WithEndpoint(server.URL),
+				WithInstallationID(apitestdata.InstallationID),
+				WithBootstrapRetryBudget(budget),
+			)
+			if err == nil {
+				t.Errorf("budget=%s: expected an error, got nil", budget)
+			}
+
+			if v := mints.Load(); v != 1 {
+				t.Errorf("budget=%s: expected installation token request not to be retried, got %d calls", budget, v)
+			}
+		}
+	})
+
+	t.Run("context-done", func(t *testing.T) {
+		var calls atomic.Int64
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		t.Cleanup(server.Close)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := NewTransport(ctx, apitestdata.AppID, testkeys.RSA2048(),
+			WithEndpoint(server.URL),
+		)
+		if err == nil {
+			t.Errorf("expected an error, got nil")
+		}
+
+		if v := calls.Load(); v != 1 {
+			t.Errorf("expected no retries when deadline is shorter than backoff, got %d calls", v)
+		}
+	})
+}
+
+func TestNewRetryRoundTripper(t *testing.T) {
+	rt, ok := NewRetryRoundTripper(nil, RetryConfig{}).(*retryRoundTripper)
+	if !ok {
+		t.Fatalf("expected *retryRoundTripper")
+	}
+
+	if rt.next != http.DefaultTransport {
+		t.Errorf("expected next to be http.DefaultTransport")
+	}
+
+	expect := RetryConfig{
+		MaxRetries: defaultMaxRetries,
+		MinBackoff: minRetryBackoff,
+		MaxBackoff: maxRetryBackoff,
+		MaxWait:    defaultMaxWait,
+	}
+	if rt.cfg != expect {
+		t.Errorf("expected=%+v, got=%+v", expect, rt.cfg)
+	}
+}
+
+func TestRetryRoundTripper(t *testing.T) {
+	tt := []struct {
+		name       string
+		cfg        RetryConfig
+		statuses   []int
+		header     http.Header
+		body       string
+		expect     int
+		calls      int64
+		minElapsed time.Duration
+	}{
+		{
+			name:     "ok",
+			statuses: []int{http.StatusOK},
+			expect:   http.StatusOK,
+			calls:    1,
+		},
+		{
+			name:     "not-retryable",
+			statuses: []int{http.StatusNotFound},
+			expect:   http.StatusNotFound,
+			calls:    1,
+		},
+		{
+			name:     "not-implemented",
+			statuses: []int{http.StatusNotImplemented},
+			expect:   http.StatusNotImplemented,
+			calls:    1,
+		},
+		{
+			name:     "forbidden-not-rate-limited",
+			statuses: []int{http.StatusForbidden},
+			expect:   http.StatusForbidden,
+			calls:    1,
+		},
+		{
+			name:     "server-errors-then-ok",
+			cfg:      RetryConfig{MinBackoff: time.Millisecond},
+			statuses: []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK},
+			expect:   http.StatusOK,
+			calls:    3,
+		},
+		{
+			name:     "too-many-requests-then-ok",
+			cfg:      RetryConfig{MinBackoff: time.Millisecond},
+			statuses: []int{http.StatusTooManyRequests, http.StatusOK},
+			expect:   http.StatusOK,
+			calls:    2,
+		},
+		{
+			name:     "body-is-rewound",
+			cfg:      RetryConfig{MinBackoff: time.Millisecond},
+			statuses: []int{http.StatusInternalServerError, http.StatusOK},
+			body:     "payload",
+			expect:   http.StatusOK,
+			calls:    2,
+		},
+		{
+			name:       "give-up-after-max-retries",
+			cfg:        RetryConfig{MaxRetries: 2, MinBackoff: time.Millisecond},
+			statuses:   []int{http.StatusInternalServerError},
+			expect:     http.StatusInternalServerError,
+			calls:      3,
+			minElapsed: 3 * time.Millisecond,
+		},
+		{
+			name:       "backoff",
+			cfg:        RetryConfig{MinBackoff: 50 * time.Millisecond, MaxBackoff: 100 * time.Millisecond},
+			statuses:   []int{http.StatusInternalServerError},
+			expect:     http.StatusInternalServerError,
+			calls:      4,
+			minElapsed: 250 * time.Millisecond,
+		},
+		{
+			name:       "retry-after",
+			cfg:        RetryConfig{MinBackoff: time.Millisecond},
+			statuses:   []int{http.StatusTooManyRequests, http.StatusOK},
+			header:     http.Header{"Retry-After": []string{"1"}},
+			expect:     http.StatusOK,
+			calls:      2,
+			minElapsed: time.Second,
+		},
+		{
+			name:     "retry-after-exceeds-max-wait",
+			cfg:      RetryConfig{MaxWait: time.Second},
+			statuses: []int{http.StatusServiceUnavailable, http.StatusOK},
+			header:   http.Header{"Retry-After": []string{"120"}},
+			expect:   http.StatusServiceUnavailable,
+			calls:    1,
+		},
+		{
+			name:       "forbidden-rate-limited",
+			cfg:        RetryConfig{MinBackoff: time.Millisecond},
+			statuses:   []int{http.StatusForbidden, http.StatusOK},
+			header:     http.Header{"Retry-After": []string{"1"}},
+			expect:     http.StatusOK,
+			calls:      2,
+			minElapsed: time.Second,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls atomic.Int64
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := calls.Add(1)
+				body, _ := io.ReadAll(r.Body)
+				if string(body) != tc.body {
+					t.Errorf("expected body=%q, got=%q", tc.body, body)
+				}
+
+				status := tc.statuses[min(int(n), len(tc.statuses))-1]
+				if status != http.StatusOK {
+					for k, v := range tc.header {
+						w.Header()[k] = v
+					}
+				}
+				w.WriteHeader(status)
+			}))
+			t.Cleanup(server.Close)
+
+			var reqBody io.Reader
+			if tc.body != "" {
+				reqBody = strings.NewReader(tc.body)
+			}
+			r, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, server.URL, reqBody)
+
+			start := time.Now()
+			resp, err := NewRetryRoundTripper(http.DefaultTransport, tc.cfg).RoundTrip(r)
+			elapsed := time.Since(start)
+			if err != nil {
+				t.Fatalf("expected no error, got %s", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tc.expect {
+				t.Errorf("expected status=%d, got=%d", tc.expect, resp.StatusCode)
+			}
+
+			if v := calls.Load(); v != tc.calls {
+				t.Errorf("expected calls=%d, got=%d", tc.calls, v)
+			}
+
+			if elapsed < tc.minElapsed {
+				t.Errorf("expected at-least %s between retries, took %s", tc.minElapsed, elapsed)
+			}
+		})
+	}
+
+	t.Run("body-not-rewindable", func(t *testing.T) {
+		var calls atomic.Int64
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		t.Cleanup(server.Close)
+
+		r, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, server.URL,
+			io.NopCloser(strings.NewReader("payload")))
+		resp, err := NewRetryRoundTripper(nil, RetryConfig{MinBackoff: time.Millisecond}).RoundTrip(r)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+		resp.Body.Close()
+
+		if v := calls.Load(); v != 1 {
+			t.Errorf("expected calls=1, got=%d", v)
+		}
+	})
+}
@@ -11,6 +11,7 @@ import (
 	"log/slog"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 
 	"github.com/tprasadtp/go-githubapp/internal/api"
@@ -47,6 +48,13 @@ type InstallationToken struct {
 	// Installation owner. This is owner of the installation.
 	Owner string `json:"owner,omitempty" yaml:"owner,omitempty"`
 
+	// OwnerID is account id of the installation owner.
+	OwnerID uint64 `json:"owner_id,omitempty" yaml:"ownerID,omitempty"`
+
+	// TargetType is type of the installation owner account, typically
+	// "User" or "Organization".
+	TargetType string `json:"target_type,omitempty" yaml:"targetType,omitempty"`
+
 	// Repositories which can be accessed with the token. This may be empty
 	//  if a scoped token is not requested. In such cases, token will have access to all
 	// repositories accessible by the installation.
@@ -59,9 +67,20 @@ type InstallationToken struct {
 	// BotUsername is app's github username.
 	BotUsername string `json:"bot_username,omitempty" yaml:"bot_username,omitempty"`
 
+	// BotUserID is app's github user id.
+	BotUserID uint64 `json:"bot_user_id,omitempty" yaml:"bot_user_id,omitempty"`
+
 	// BotCommitterEmail is committer email to use to attribute commits to the bot.
 	// This is in the form "<user-id>+<app-name>[bot]@users.noreply.github.com".
 	BotCommitterEmail string `json:"bot_committer_email,omitempty" yaml:"bot_committer_email,omitempty"`
+
+	// BotCommitterName is committer name to use to attribute commits to the bot.
+	// This is in the form "<app-name>[bot]".
+	BotCommitterName string `json:"bot_committer_name,omitempty" yaml:"bot_committer_name,omitempty"`
+
+	// RateLimit is rate limit reported by GitHub API when the token was minted.
+	// This may be nil if response did not include rate limit headers.
+	RateLimit *RateLimit `json:"rate_limit,omitempty" yaml:"rateLimit,omitempty"`
 }
 
 // LogValue implements [log/slog.LogValuer].
@@ -72,28 +91,139 @@ func (t *InstallationToken) LogValue() slog.Value {
 		slog.String("app_name", t.AppName),
 		slog.String("user_agent", t.UserAgent),
 		slog.Uint64("installation_id", t.InstallationID),
+		slog.String("owner", t.Owner),
+		slog.Uint64("owner_id", t.OwnerID),
+		slog.String("target_type", t.TargetType),
 		slog.Any("repositories", t.Repositories),
 		slog.String("token", "REDACTED"),
 		slog.Time("exp", t.Exp),
 		slog.Any("permissions", t.Permissions),
 		slog.String("bot_username", t.BotUsername),
+		slog.Uint64("bot_user_id", t.BotUserID),
 		slog.String("bot_committer_email", t.BotCommitterEmail),
+		slog.String("bot_committer_name", t.BotCommitterName),
 	)
 }
 
 // IsValid checks if [InstallationToken] is valid for at-least 60 seconds.
 func (t *InstallationToken) IsValid() bool {
-	return t.Token != "" && (t.Exp.After(time.Now().Add(time.Minute)) || t.Exp.IsZero())
+	return t.validFor(time.Minute)
+}
+
+// validFor checks if [InstallationToken] is valid for at-least the given duration.
+func (t *InstallationToken) validFor(d time.Duration) bool {
+	return t.Token != "" && (t.Exp.After(time.Now().Add(d)) || t.Exp.IsZero())
 }
 
 // Revoke revokes the installation access token.
 func (t *InstallationToken) Revoke(ctx context.Context) error {
-	return t.revoke(ctx, nil)
+	return t.revoke(ctx, nil, "")
+}
+
+// revokeConcurrency is maximum number of tokens revoked concurrently by [RevokeTokens].
+const revokeConcurrency = 8
+
+// RevokeTokens revokes multiple installation access tokens concurrently, with
+// a bounded number of concurrent requests. Tokens which are already invalid are
+// skipped. Errors are aggregated with [errors.Join] and identify the index of the
+// token. If context is cancelled, tokens not yet revoked are skipped and context
+// error is included in the returned error. Successfully revoked tokens are marked
+// as invalid, like [InstallationToken.Revoke].
+func RevokeTokens(ctx context.Context, tokens []InstallationToken) error {
+	return revokeTokens(ctx, tokens, nil)
+}
+
+// revokeTokens is an internal version of RevokeTokens, which supports custom
+// round tripper for testing and customization.
+func revokeTokens(ctx context.Context, tokens []InstallationToken, rt http.RoundTripper) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var wg sync.WaitGroup
+	var ctxErr error
+	errs := make([]error, len(tokens))
+	sem := make(chan struct{}, revokeConcurrency)
+
+loop:
+	for i := range tokens {
+		if !tokens[i].IsValid() {
+			continue
+		}
+
+		// Select does not prioritize cases, thus check context first.
+		if ctx.Err() != nil {
+			ctxErr = fmt.Errorf("githubapp: failed to revoke tokens: %w", ctx.Err())
+			break loop
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			ctxErr = fmt.Errorf("githubapp: failed to revoke tokens: %w", ctx.Err())
+			break loop
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			if err := tokens[i].revoke(ctx, rt, ""); err != nil {
+				errs[i] = fmt.Errorf("token[%d]: %w", i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	return errors.Join(append(errs, ctxErr)...)
+}
+
+// Refresh mints a new installation access token using metadata of the token,
+// with the same repositories and permissions. This is useful when token is
+// persisted and [Transport] which minted it is no longer available. Signer MUST
+// be the private key of the app which minted the token.
+//
+// Token itself is not modified or revoked. Additional options, if any, are
+// applied after the options derived from the token.
+func (t *InstallationToken) Refresh(ctx context.Context, signer crypto.Signer, opts ...Option) (InstallationToken, error) {
+	var err error
+	if t.InstallationID == 0 {
+		err = errors.Join(err, errors.New("installation id is missing"))
+	}
+
+	if t.Server == "" {
+		err = errors.Join(err, errors.New("server is missing"))
+	}
+
+	if err != nil {
+		return InstallationToken{}, fmt.Errorf("githubapp: cannot refresh token: %w", err)
+	}
+
+	options := []Option{
+		WithEndpoint(t.Server),
+		WithInstallationID(t.InstallationID),
+		WithUserAgent(t.UserAgent),
+		WithPermissionsMap(t.Permissions),
+	}
+
+	if t.Owner != "" {
+		options = append(options, WithOwner(t.Owner))
+	}
+
+	if len(t.Repositories) > 0 {
+		options = append(options, WithRepositories(t.Repositories...))
+	}
+
+	return NewInstallationToken(ctx, t.AppID, signer, append(options, opts...)...)
 }
 
 // revoke is an internal version of Revoke, which supports custom round tripper
-// for testing and customization.
-func (t *InstallationToken) revoke(ctx context.Context, rt http.RoundTripper) error {
+// and API version for testing and customization. If version is empty, default
+// API version is used.
+func (t *InstallationToken) revoke(ctx context.Context, rt http.RoundTripper, version string) error {
 	if ctx == nil {
 		ctx = context.Background()
 	}
@@ -131,7 +261,10 @@ func (t *InstallationToken) revoke(ctx context.Context, rt http.RoundTripper) er
 	}
 
 	// Add Headers.
-	r.Header.Set(api.VersionHeader, api.VersionHeaderValue)
+	if version == "" {
+		version = api.VersionHeaderValue
+	}
+	r.Header.Set(api.VersionHeader, version)
 	r.Header.Set(api.AuthzHeader, api.AuthzHeaderValue(t.Token))
 	r.Header.Add(api.AcceptHeader, api.AcceptHeaderValue)
 	if t.UserAgent == "" {
@@ -140,12 +273,8 @@ func (t *InstallationToken) revoke(ctx context.Context, rt http.RoundTripper) er
 		r.Header.Add(api.UAHeader, t.UserAgent)
 	}
 
-	client := &http.Client{}
-
-	// Uses custom round tripper specified.
-	if rt != nil {
-		client.Transport = rt
-	}
+	// Uses custom round tripper specified if any.
+	client := newInternalClient(rt)
 
 	resp, err := client.Do(r)
 	if err != nil {
@@ -164,7 +293,8 @@ func (t *InstallationToken) revoke(ctx context.Context, rt http.RoundTripper) er
 }
 
 // NewInstallationToken returns new installation access token.
-// This takes same options as [Transport].
+// This takes same options as [Transport]. Bot user metadata of the token is
+// populated on a best-effort basis, see [Transport.InstallationToken] for more info.
 func NewInstallationToken(ctx context.Context, appid uint64, signer crypto.Signer, opts ...Option) (InstallationToken, error) {
 	t, err := NewTransport(ctx, appid, signer, opts...)
 	if err != nil {
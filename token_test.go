@@ -6,6 +6,8 @@ package githubapp
 import (
 	"context"
 	"crypto"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"maps"
@@ -14,6 +16,7 @@ import (
 	"reflect"
 	"slices"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -290,7 +293,7 @@ func TestInstallationToken_Revoke(t *testing.T) {
 	}
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			err := tc.token.revoke(tc.ctx, tc.rt)
+			err := tc.token.revoke(tc.ctx, tc.rt, "")
 			if tc.ok {
 				if err != nil {
 					t.Errorf("unexpected error: %s", err)
@@ -307,6 +310,99 @@ func TestInstallationToken_Revoke(t *testing.T) {
 	}
 }
 
+func TestRevokeTokens(t *testing.T) {
+	var inflight, peak, calls atomic.Int64
+	rt := api.RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+		calls.Add(1)
+		n := inflight.Add(1)
+		defer inflight.Add(-1)
+		for {
+			v := peak.Load()
+			if n <= v || peak.CompareAndSwap(v, n) {
+				break
+			}
+		}
+
+		// Slow down requests, so that they overlap.
+		time.Sleep(10 * time.Millisecond)
+
+		resp := httptest.NewRecorder()
+		if r.Header.Get(api.AuthzHeader) == "Bearer ghs_fail" {
+			resp.WriteHeader(http.StatusInternalServerError)
+		} else {
+			resp.WriteHeader(http.StatusNoContent)
+		}
+		return resp.Result(), nil
+	})
+
+	t.Run("revoke", func(t *testing.T) {
+		calls.Store(0)
+		peak.Store(0)
+
+		tokens := make([]InstallationToken, 0, 4*revokeConcurrency)
+		for i := 0; i < cap(tokens); i++ {
+			tokens = append(tokens, InstallationToken{
+				Token: fmt.Sprintf("ghs_%d", i),
+				Exp:   time.Now().Add(time.Hour),
+			})
+		}
+
+		// Invalid tokens must be skipped.
+		tokens[1].Exp = time.Now().Add(-time.Hour)
+		tokens[2].Token = ""
+
+		// Errors must identify the token.
+		tokens[3].Token = "ghs_fail"
+
+		err := revokeTokens(context.Background(), tokens, rt)
+		if err == nil {
+			t.Fatalf("expected an error, got nil")
+		}
+
+		if !strings.Contains(err.Error(), "token[3]") || strings.Contains(err.Error(), "token[1]") {
+			t.Errorf("expected only token[3] to fail, got %s", err)
+		}
+
+		if v := calls.Load(); v != int64(len(tokens)-2) {
+			t.Errorf("expected %d revoke requests, got %d", len(tokens)-2, v)
+		}
+
+		if v := peak.Load(); v > revokeConcurrency || v < 2 {
+			t.Errorf("expected concurrent requests to be bounded by %d, got %d", revokeConcurrency, v)
+		}
+
+		for i, token := range tokens {
+			if i != 3 && token.IsValid() {
+				t.Errorf("expected token[%d] to be invalid after revoking", i)
+			}
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		calls.Store(0)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		tokens := []InstallationToken{{Token: "ghs_1"}, {Token: "ghs_2"}}
+		err := revokeTokens(ctx, tokens, rt)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected error=%s, got=%s", context.Canceled, err)
+		}
+
+		if v := calls.Load(); v != 0 {
+			t.Errorf("expected no revoke requests, got %d", v)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		//nolint:staticcheck // test nil context.
+		err := RevokeTokens(nil, nil)
+		if err != nil {
+			t.Errorf("expected no error, got %s", err)
+		}
+	})
+}
+
 func TestNewInstallationToken_TransportErr(t *testing.T) {
 	type testCase struct {
 		name    string
@@ -645,6 +741,7 @@ func TestNewInstallationToken_MockServer(t *testing.T) {
 			name: "GetBotUser-NotFound",
 			options: []Option{
 				WithInstallationID(apitestdata.InstallationID),
+				WithEagerBotMetadata(),
 			},
 			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				var key string
@@ -679,6 +776,7 @@ func TestNewInstallationToken_MockServer(t *testing.T) {
 			name: "GetBotUser-ServerError",
 			options: []Option{
 				WithInstallationID(apitestdata.InstallationID),
+				WithEagerBotMetadata(),
 			},
 			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				var key string
@@ -754,10 +852,21 @@ func TestNewInstallationToken_MockServer(t *testing.T) {
 					t.Errorf("expected BotCommitterEmail to be non empty")
 				}
 
+				if token.BotUserID != apitestdata.BotUserID {
+					t.Errorf("expected BotUserID=%d, got=%d", apitestdata.BotUserID, token.BotUserID)
+				}
+
 				if token.InstallationID == 0 {
 					t.Errorf("expected InstallationID to be non zero")
 				}
 
+				if !strings.EqualFold(token.Owner, apitestdata.InstallationOwner) ||
+					token.OwnerID != apitestdata.InstallationOwnerID {
+					t.Errorf("expected Owner=%s, OwnerID=%d, got Owner=%s, OwnerID=%d",
+						apitestdata.InstallationOwner, apitestdata.InstallationOwnerID,
+						token.Owner, token.OwnerID)
+				}
+
 				if token.AppID == 0 {
 					t.Errorf("expected AppID to be non zero")
 				}
@@ -775,6 +884,71 @@ func TestNewInstallationToken_MockServer(t *testing.T) {
 					t.Errorf("expected scopes=%v, got=%v",
 						tc.scopes, token.Permissions)
 				}
+
+				// App metadata is populated from get-app response.
+				transport, err := NewTransport(ctx, apitestdata.AppID, testkeys.RSA2048(), options...)
+				if err != nil {
+					t.Fatalf("expected no error, got %s", err)
+				}
+
+				app := transport.App()
+				if app.ID != apitestdata.AppID || app.Slug != apitestdata.AppSlug ||
+					app.Name != apitestdata.AppSlug || app.Owner != apitestdata.AppOwner {
+					t.Errorf("expected app metadata to be populated, got %#v", app)
+				}
+
+				appScopes := map[string]string{"contents": "read", "issues": "read", "metadata": "read"}
+				if v := transport.AppPermissions(); !maps.Equal(v, appScopes) {
+					t.Errorf("expected app permissions=%v, got=%v", appScopes, v)
+				}
+
+				if v := transport.AppEvents(); len(v) != 0 {
+					t.Errorf("expected no app events, got %v", v)
+				}
+
+				// Installation account is populated from get-installation response,
+				// even if owner is discovered via installation id.
+				account := transport.InstallationAccount()
+				if !strings.EqualFold(account.Login, apitestdata.InstallationOwner) ||
+					account.ID != apitestdata.InstallationOwnerID || account.Type != "Organization" {
+					t.Errorf("expected installation account to be populated, got %#v", account)
+				}
+
+				if v := transport.Owner(); !strings.EqualFold(v, apitestdata.InstallationOwner) {
+					t.Errorf("expected owner=%s, got=%s", apitestdata.InstallationOwner, v)
+				}
+
+				// Configured repositories do not include the owner.
+				repos := transport.ConfiguredRepositories()
+				for _, repo := range repos {
+					if strings.Contains(repo, "/") {
+						t.Errorf("expected configured repository without owner, got=%s", repo)
+					}
+				}
+
+				if len(repos) > 0 {
+					repos[0] = "modified"
+					if v := transport.ConfiguredRepositories(); v[0] == "modified" {
+						t.Errorf("expected configured repositories to be immutable")
+					}
+				}
+
+				// Installation permissions are populated from get-installation response,
+				// irrespective of scoped permissions.
+				if v := transport.InstallationPermissions(); !maps.Equal(v, appScopes) {
+					t.Errorf("expected installation permissions=%v, got=%v", appScopes, v)
+				}
+
+				// Accessors must return clones.
+				transport.AppPermissions()["contents"] = "write"
+				if v := transport.App().Permissions["contents"]; v != "read" {
+					t.Errorf("expected app permissions to be immutable, got contents=%s", v)
+				}
+
+				transport.InstallationPermissions()["contents"] = "write"
+				if v := transport.InstallationPermissions()["contents"]; v != "read" {
+					t.Errorf("expected installation permissions to be immutable, got contents=%s", v)
+				}
 			} else {
 				if err == nil {
 					t.Errorf("expected an error, got nil")
@@ -787,3 +961,88 @@ func TestNewInstallationToken_MockServer(t *testing.T) {
 		})
 	}
 }
+
+func TestInstallationToken_Refresh(t *testing.T) {
+	t.Run("missing-metadata", func(t *testing.T) {
+		tt := []struct {
+			name  string
+			token InstallationToken
+		}{
+			{name: "empty"},
+			{name: "no-server", token: InstallationToken{AppID: 99, InstallationID: 99}},
+			{name: "no-installation-id", token: InstallationToken{AppID: 99, Server: api.DefaultEndpoint}},
+		}
+		for _, tc := range tt {
+			t.Run(tc.name, func(t *testing.T) {
+				_, err := tc.token.Refresh(context.Background(), testkeys.RSA2048())
+				if err == nil {
+					t.Errorf("expected an error, got nil")
+				}
+			})
+		}
+	})
+
+	t.Run("mock-server", func(t *testing.T) {
+		m := apitestdata.Get(t)
+		var tokenReq api.InstallationTokenRequest
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var key string
+			switch r.URL.Path {
+			case "/app":
+				key = "get-app"
+			case fmt.Sprintf("/app/installations/%d", apitestdata.InstallationID):
+				key = "get-installation-by-id"
+			case fmt.Sprintf("/app/installations/%d/access_tokens", apitestdata.InstallationID):
+				_ = json.NewDecoder(r.Body).Decode(&tokenReq)
+				key = "post-installation-token-with-scopes"
+				w.WriteHeader(http.StatusCreated)
+			case fmt.Sprintf("/users/%s[bot]", apitestdata.AppSlug):
+				key = "get-user-bot"
+			default:
+				t.Errorf("Unknown/Invalid Request => %s", r.URL)
+			}
+			_, _ = w.Write(m[key])
+		}))
+		t.Cleanup(server.Close)
+
+		token := InstallationToken{
+			Token:          "ghs_expired",
+			AppID:          apitestdata.AppID,
+			InstallationID: apitestdata.InstallationID,
+			Server:         server.URL,
+			Exp:            time.Now().Add(-time.Hour),
+			Owner:          apitestdata.InstallationOwner,
+			Repositories:   []string{apitestdata.InstallationRepository},
+			Permissions:    map[string]string{"metadata": "read"},
+		}
+
+		refreshed, err := token.Refresh(context.Background(), testkeys.RSA2048())
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		if refreshed.Token == "" || refreshed.Token == token.Token {
+			t.Errorf("expected a new token, got %q", refreshed.Token)
+		}
+
+		if refreshed.InstallationID != token.InstallationID || refreshed.AppID != token.AppID {
+			t.Errorf("refreshed token must be for the same app and installation: %#v", refreshed)
+		}
+
+		if refreshed.Server != server.URL && refreshed.Server != server.URL+"/" {
+			t.Errorf("expected server=%s, got=%s", server.URL, refreshed.Server)
+		}
+
+		if !slices.Equal(tokenReq.Repositories, token.Repositories) {
+			t.Errorf("expected repositories=%v, got=%v", token.Repositories, tokenReq.Repositories)
+		}
+
+		if !maps.Equal(tokenReq.Permissions, token.Permissions) {
+			t.Errorf("expected permissions=%v, got=%v", token.Permissions, tokenReq.Permissions)
+		}
+
+		if token.Token != "ghs_expired" {
+			t.Errorf("original token must not be modified")
+		}
+	})
+}
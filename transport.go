@@ -14,9 +14,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"maps"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"sync/atomic"
@@ -51,24 +53,75 @@ func ctxHasJWTKey(ctx context.Context) bool {
 // GitHub App or as an GitHub app installation.
 //
 // 'Authorization' header is automatically populated with a suitable installation
-// token or JWT token for all requests. If it already exists, it is ignored.
+// token or JWT token for all requests. If it already exists, it is ignored, unless
+// request context is configured via [WithExistingAuthorization] or
+// [WithPreserveAuthorizationHeader] is specified.
+// Requests to hosts other than the host of the endpoint are rejected,
+// unless configured via [WithAdditionalHosts].
 // Token renewal requests will always override 'Accept' and "X-GitHub-Api-Version"
-// headers.
+// headers. Requests made by the Transport itself, never follow redirects to a
+// different host, to avoid leaking credentials.
 type Transport struct {
-	appID       uint64            // app ID
-	appSlug     string            // app slug/name
-	installID   uint64            // installation id
-	owner       string            // owner of repositories
-	repos       []string          // repository names
-	ua          string            // user agent
-	next        http.RoundTripper // next round tripper
-	baseURL     *url.URL          // REST API v3 base URL
-	minter      jwtMinter         // jwt minter
-	jwt         atomic.Value      // jwt token
-	token       atomic.Value      // installation token
-	botUsername string            // bot user.name
-	botEmail    string            // bot user.email
-	scopes      map[string]string // scoped permissions
+	appID            uint64                      // app ID
+	appSlug          string                      // app slug/name
+	installID        uint64                      // installation id
+	owner            string                      // owner of repositories
+	repos            []string                    // repository names
+	ua               string                      // user agent
+	uaInstallID      bool                        // include installation id in user agent
+	next             http.RoundTripper           // next round tripper
+	baseURL          *url.URL                    // REST API v3 base URL
+	signer           crypto.Signer               // jwt signer
+	minter           jwtMinter                   // jwt minter
+	jwt              atomic.Value                // jwt token
+	token            atomic.Value                // installation token
+	jwtFlight        flight[JWT]                 // in-flight JWT mint
+	tokenFlight      flight[InstallationToken]   // in-flight installation token mint
+	renewFlight      flight[InstallationToken]   // in-flight installation token renewal
+	bot              *botUserLoader              // bot user metadata
+	scopes           map[string]string           // scoped permissions
+	health           atomic.Value                // last known health state
+	cache            Cache                       // shared installation token cache
+	bootstrapBudget  time.Duration               // retry budget for bootstrap
+	rateLimitWait    time.Duration               // max wait for rate limits
+	accept           string                      // default accept header
+	logger           *slog.Logger                // logger for token lifecycle events
+	apiVersion       string                      // X-GitHub-Api-Version header value
+	bootstrapTimeout time.Duration               // timeout for bootstrap
+	metrics          Recorder                    // metrics recorder
+	uaComments       []string                    // user agent comments
+	uaSuffix         string                      // user agent suffix
+	hosts            []string                    // additional hosts
+	tokenURL         string                      // canonical access tokens URL
+	app              *Transport                  // app transport sharing the JWT
+	skipBot          bool                        // skip fetching bot metadata
+	eagerBot         bool                        // fetch bot metadata during bootstrap
+	requestTimeout   time.Duration               // timeout for bootstrap requests
+	graphqlURL       *url.URL                    // GraphQL API endpoint
+	uploadURL        *url.URL                    // upload endpoint
+	onTokenRefresh   []tokenRefreshCallback      // installation token mint callbacks
+	preserveAuthz    bool                        // preserve pre-set authorization header
+	quota            atomic.Value                // token creation rate limit
+	expiryMargin     time.Duration               // installation token expiry margin
+	installs         transportCache[uint64]      // installation transports for context overrides
+	installFlights   flightGroup[*Transport]     // in-flight installation transport bootstraps
+	scoped           transportCache[string]      // scoped transports for context permissions
+	targetType       string                      // installation target type
+	ownerID          uint64                      // installation owner account id
+	editors          []func(*http.Request) error // request editors
+	strictScopes     bool                        // validate permission names
+	static           *InstallationToken          // static installation token
+	jwtExpiry        time.Duration               // JWT lifetime
+	jwtSkew          time.Duration               // JWT iat backdate
+	validateOnly     bool                        // skip minting token during bootstrap
+	appMeta          AppMetadata                 // app metadata
+	httpClient       *http.Client                // client for internal requests
+	repoIDs          []int64                     // repository ids
+	singleFile       string                      // single file path
+	installCache     InstallationCache           // shared installation lookup cache
+	installPerms     map[string]string           // installation permissions
+	closed           atomic.Bool                 // transport is closed
+	rateLimit        atomic.Value                // rate limit from the last response
 }
 
 // NewTransport creates a new [Transport] for authenticating as an app/installation.
@@ -98,8 +151,20 @@ type Transport struct {
 //
 // If only installation access token or JWT is required but not the round tripper,
 // use [NewInstallationToken] or [NewJWT] respectively.
+//
+// If [WithStaticInstallationToken] is specified, app id and signer are optional and
+// are not verified, as the [Transport] never mints any tokens.
 func NewTransport(ctx context.Context, appid uint64, signer crypto.Signer, opts ...Option) (*Transport, error) {
-	var err error
+	t, err := newTransport(appid, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Static installation tokens do not require a signer or bootstrapping.
+	if t.static != nil {
+		return t, nil
+	}
+
 	if signer == nil {
 		err = errors.Join(err, errors.New("no signer provided"))
 	}
@@ -112,6 +177,69 @@ func NewTransport(ctx context.Context, appid uint64, signer crypto.Signer, opts
 		return nil, fmt.Errorf("githubapp: invalid options: %w", err)
 	}
 
+	// If context is nil, assign a default context.
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	// Select JWT signer based on the public key of the signer.
+	switch v := signer.Public().(type) {
+	case *rsa.PublicKey:
+		if v.N.BitLen() < 2048 {
+			return nil,
+				fmt.Errorf("githubapp: rsa keys size(%d) < 2048 bits", v.N.BitLen())
+		}
+		t.signer = signer
+		t.minter = &jwtRS256{internal: signer, expiry: t.jwtExpiry, skew: t.jwtSkew}
+	case *ecdsa.PublicKey:
+		return nil, errors.New("githubapp: ECDSA keys are not supported")
+	case *ed25519.PublicKey, ed25519.PublicKey:
+		return nil, errors.New("githubapp: ED-25519 keys are not supported")
+	default:
+		return nil, fmt.Errorf("githubapp: unknown key type: %T", v)
+	}
+
+	// Bound bootstrap phase, as default http transport has no overall deadline.
+	ctx, cancel := t.bootstrapContext(ctx)
+	defer cancel()
+
+	// Verify signer can sign before making any network calls.
+	err = checkSigner(ctx, signer)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, bootstrapError(ctx, "verify signer", err)
+		}
+		return nil, err
+	}
+
+	// Shared client for init operations.
+	client := t.internalClient()
+
+	// Retry budget shared across all bootstrap API calls.
+	budget := newRetryBudget(t.bootstrapBudget, t.requestTimeout)
+
+	// Verify app id and signer are both valid.
+	err = budget.do(ctx, func(ctx context.Context) error {
+		return t.checkApp(ctx, client)
+	})
+	if err != nil {
+		return nil, bootstrapError(ctx, "verify app", err)
+	}
+
+	// Verify installation if installation options are specified.
+	err = t.bootstrapInstallation(ctx, client, budget)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// newTransport returns a new [Transport] with options applied and defaults
+// populated. This does not configure the signer or make any API calls.
+func newTransport(appid uint64, opts ...Option) (*Transport, error) {
+	var err error
+
 	// Apply all options.
 	t := &Transport{
 		appID: appid,
@@ -123,11 +251,49 @@ func NewTransport(ctx context.Context, appid uint64, signer crypto.Signer, opts
 		}
 	}
 
-	// If only repository names are given, but not the owner.
-	if len(t.repos) > 0 && t.owner == "" {
+	// If only repository names are given, but not the owner. If installation id
+	// is specified, owner is populated from the installation during bootstrap.
+	if len(t.repos) > 0 && t.owner == "" && t.installID == 0 {
 		err = errors.Join(err, errors.New("owner not specified"))
 	}
 
+	// Repository ids do not identify the owner, thus installation id or owner
+	// is required. Options can be specified in any order, thus conflicts with
+	// repository names are checked here.
+	if len(t.repoIDs) > 0 {
+		if t.owner == "" && t.installID == 0 {
+			err = errors.Join(err, errors.New("owner or installation id not specified"))
+		}
+
+		if len(t.repos) > 0 {
+			err = errors.Join(err, errors.New("WithRepositories and WithRepositoryIDs cannot be used together"))
+		}
+	}
+
+	// Request read access to the single file, unless specified explicitly.
+	// This is done after applying all options, to avoid conflicts with
+	// permissions specified explicitly.
+	t.useSingleFileScope()
+
+	// Validate permission names if configured. This is done after applying
+	// all options, as options can be specified in any order.
+	if t.strictScopes {
+		err = errors.Join(err, checkPermissionNames(t.scopes))
+	}
+
+	// User agent suffix is only applicable to default user agent.
+	if t.ua != "" && t.uaSuffix != "" {
+		err = errors.Join(err, errors.New("WithUserAgent and WithUserAgentSuffix cannot be used together"))
+	}
+
+	// Static installation tokens cannot be re-scoped or renewed.
+	if t.static != nil {
+		if t.installID != 0 || t.owner != "" || len(t.repos) > 0 || len(t.repoIDs) > 0 ||
+			len(t.scopes) > 0 || t.cache != nil {
+			err = errors.Join(err, errors.New("WithStaticInstallationToken cannot be used with installation options"))
+		}
+	}
+
 	if err != nil {
 		return nil, fmt.Errorf("githubapp: invalid options: %w", err)
 	}
@@ -142,61 +308,97 @@ func NewTransport(ctx context.Context, appid uint64, signer crypto.Signer, opts
 		t.ua = api.UAHeaderValue
 	}
 
+	// If endpoint is not configured, use server of the static token if any.
+	if t.baseURL == nil && t.static != nil && t.static.Server != "" {
+		err = WithEndpoint(t.static.Server).apply(t)
+		if err != nil {
+			return nil, fmt.Errorf("githubapp: invalid options: server of static token: %w", err)
+		}
+	}
+
 	// If endpoint is not configured, use default endpoint.
 	if t.baseURL == nil {
 		t.baseURL, _ = url.Parse(api.DefaultEndpoint)
 	}
 
-	// If context is nil, assign a default context.
-	if ctx == nil {
-		ctx = context.Background()
+	// Populate installation metadata from the static installation token.
+	if t.static != nil {
+		t.useStaticToken()
 	}
 
-	// Select JWT signer based on the public key of the signer.
-	switch v := signer.Public().(type) {
-	case *rsa.PublicKey:
-		if v.N.BitLen() < 2048 {
-			return nil,
-				fmt.Errorf("githubapp: rsa keys size(%d) < 2048 bits", v.N.BitLen())
-		}
-		t.minter = &jwtRS256{internal: signer}
-	case *ecdsa.PublicKey:
-		return nil, errors.New("githubapp: ECDSA keys are not supported")
-	case *ed25519.PublicKey, ed25519.PublicKey:
-		return nil, errors.New("githubapp: ED-25519 keys are not supported")
-	default:
-		return nil, fmt.Errorf("githubapp: unknown key type: %T", v)
+	// GraphQL and upload endpoints are only known for github.com,
+	// or if configured via WithEnterpriseHost.
+	if t.baseURL.String() == api.DefaultEndpoint && t.graphqlURL == nil {
+		t.graphqlURL, _ = url.Parse(api.DefaultGraphQLEndpoint)
+		t.uploadURL, _ = url.Parse(api.DefaultUploadEndpoint)
 	}
 
-	// Shared client for init operations.
-	client := &http.Client{
-		Transport: t,
-	}
+	return t, nil
+}
 
-	// Verify app id and signer are both valid.
-	err = t.checkApp(ctx, client)
-	if err != nil {
-		return nil, fmt.Errorf("githubapp: failed to verify app: %w", err)
+// useStaticToken populates installation metadata from the static installation
+// token configured via [WithStaticInstallationToken].
+func (t *Transport) useStaticToken() {
+	if t.appID == 0 {
+		t.appID = t.static.AppID
 	}
+	t.appSlug = t.static.AppName
+	t.installID = t.static.InstallationID
+	t.owner = t.static.Owner
+	t.targetType = t.static.TargetType
+	t.ownerID = t.static.OwnerID
+	t.repos = t.static.Repositories
+	t.bot = newLoadedBotUser(t.static.BotUsername, t.static.BotCommitterEmail, t.static.BotUserID)
+	t.token.Store(*t.static)
+}
 
+// bootstrapInstallation verifies installation and fetches bot user metadata,
+// if installation options are specified.
+func (t *Transport) bootstrapInstallation(ctx context.Context, client *http.Client, budget *retryBudget) error {
 	// t.owner is only populated if WithOrganization or WithRepositories
 	// is provided as an option. t.install is only populated if installation
 	// id is specified.
-	if t.owner != "" || t.installID != 0 {
-		// Check installation.
-		err = t.checkInstallation(ctx, client)
-		if err != nil {
-			return nil, fmt.Errorf("githubapp: failed to verify installation: %w", err)
-		}
+	if t.owner == "" && t.installID == 0 {
+		return nil
+	}
 
-		// Fetch bot user metadata.
-		err = t.fetchBotUserID(ctx, client)
-		if err != nil {
-			return nil, fmt.Errorf("githubapp: failed to fetch bot user metadata: %w", err)
-		}
+	// Check installation.
+	err := budget.do(ctx, func(ctx context.Context) error {
+		return t.checkInstallation(ctx, client)
+	})
+	if err != nil {
+		return bootstrapError(ctx, "verify installation", err)
 	}
 
-	return t, nil
+	// Installation token requests are not retried, as they may fail
+	// after the token is created.
+	err = budget.attempt(ctx, t.checkInstallationToken)
+	if err != nil {
+		return bootstrapError(ctx, "verify installation", err)
+	}
+
+	// Bot user metadata is fetched on first use, unless disabled.
+	if t.skipBot {
+		return nil
+	}
+
+	if t.bot == nil {
+		t.bot = &botUserLoader{}
+	}
+
+	if !t.eagerBot || t.validateOnly {
+		return nil
+	}
+
+	v := &botUser{}
+	err = budget.do(ctx, func(ctx context.Context) error {
+		return t.fetchBotUserID(ctx, client, v)
+	})
+	if err != nil {
+		return bootstrapError(ctx, "fetch bot user metadata", err)
+	}
+	t.bot.loaded.Store(v)
+	return nil
 }
 
 // AppID returns the GitHub app id.
@@ -209,14 +411,72 @@ func (t *Transport) AppName() string {
 	return t.appSlug
 }
 
-// BotUsername returns the GitHub app's username.
+// App returns metadata of the GitHub app, like its permissions and events it
+// is subscribed to. This is useful for diagnostics, as installation permissions
+// cannot exceed the app's permissions. This is empty if app was not verified,
+// for example, when using [WithStaticInstallationToken].
+func (t *Transport) App() AppMetadata {
+	meta := t.appMeta
+	meta.Permissions = maps.Clone(t.appMeta.Permissions)
+	meta.Events = slices.Clone(t.appMeta.Events)
+	return meta
+}
+
+// AppPermissions returns permissions requested by the GitHub app.
+// See [Transport.App] for more info.
+func (t *Transport) AppPermissions() map[string]string {
+	return maps.Clone(t.appMeta.Permissions)
+}
+
+// AppEvents returns events the GitHub app is subscribed to.
+// See [Transport.App] for more info.
+func (t *Transport) AppEvents() []string {
+	return slices.Clone(t.appMeta.Events)
+}
+
+// BotUsername returns the GitHub app's username. Bot user metadata is fetched
+// on first use, unless [WithEagerBotMetadata] is specified. This is empty if
+// [WithoutBotMetadata] is specified or if bot user metadata cannot be fetched.
+// Use [Transport.BotUserMetadata] to bound the fetch with a context and handle
+// errors fetching the metadata.
 func (t *Transport) BotUsername() string {
-	return t.botUsername
+	bot, _ := t.botUserMetadata(context.Background())
+	if bot == nil {
+		return ""
+	}
+	return bot.username
 }
 
 // BotCommitterEmail returns the GitHub app's no-reply email to use for git metadata.
+// This is empty if [WithoutBotMetadata] is specified or if bot user metadata
+// cannot be fetched. See [Transport.BotUsername] for more info.
 func (t *Transport) BotCommitterEmail() string {
-	return t.botEmail
+	bot, _ := t.botUserMetadata(context.Background())
+	if bot == nil {
+		return ""
+	}
+	return bot.email
+}
+
+// BotUserID returns the GitHub app's bot user id. This can be used to match
+// webhook sender ids. This is zero if [WithoutBotMetadata] is specified or if
+// bot user metadata cannot be fetched. See [Transport.BotUsername] for more info.
+func (t *Transport) BotUserID() uint64 {
+	bot, _ := t.botUserMetadata(context.Background())
+	if bot == nil {
+		return 0
+	}
+	return bot.id
+}
+
+// BotCommitterName returns the GitHub app's name to use for git metadata,
+// which is in the form "<app-name>[bot]". Unlike [Transport.BotUsername],
+// this does not require bot metadata. This is empty if app name is not known.
+func (t *Transport) BotCommitterName() string {
+	if t.appSlug == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s[bot]", t.appSlug)
 }
 
 // InstallationID returns the GitHub installation id. If not repositories
@@ -225,6 +485,182 @@ func (t *Transport) InstallationID() uint64 {
 	return t.installID
 }
 
+// Owner returns the owner of the installation. If only [WithInstallationID]
+// is specified, this is populated from the installation account. This is empty
+// if [Transport] is not configured with installation options.
+func (t *Transport) Owner() string {
+	return t.owner
+}
+
+// ConfiguredRepositories returns names of the repositories, without the owner,
+// specified via [WithRepositories]. This is empty if repositories are not
+// configured, in which case installation access tokens can access all
+// repositories available to the installation. Unlike [Transport.Repositories],
+// this does not make any API calls.
+func (t *Transport) ConfiguredRepositories() []string {
+	return slices.Clone(t.repos)
+}
+
+// InstallationAccountType returns type of the account the app is installed on,
+// typically "User" or "Organization". This is empty if [Transport] is not
+// configured with installation options.
+func (t *Transport) InstallationAccountType() string {
+	return t.targetType
+}
+
+// InstallationAccount returns the account the app is installed on. This is
+// populated even if only installation id is configured, and owner is discovered.
+// This is zero value if [Transport] is not configured with installation options.
+func (t *Transport) InstallationAccount() InstallationAccount {
+	return InstallationAccount{
+		Login: t.owner,
+		ID:    t.ownerID,
+		Type:  t.targetType,
+	}
+}
+
+// AccessTokensURL returns URL used for creating installation access tokens.
+// This is the canonical URL returned by the API for the installation if
+// available, otherwise it is built from the endpoint. If installation id
+// is not configured, This will return empty string.
+func (t *Transport) AccessTokensURL() string {
+	if t.installID == 0 {
+		return ""
+	}
+
+	if t.tokenURL != "" {
+		return t.tokenURL
+	}
+
+	return t.baseURL.JoinPath(
+		"app", "installations",
+		strconv.FormatUint(t.installID, 10),
+		"access_tokens").String()
+}
+
+// Endpoint returns REST API endpoint used by the [Transport], which defaults to
+// "https://api.github.com/" unless configured via [WithEndpoint]. Requests made
+// via the [Transport] MUST target this host or hosts configured via
+// [WithAdditionalHosts], otherwise they are rejected. Returned URL is a copy,
+// thus it is safe to modify.
+func (t *Transport) Endpoint() *url.URL {
+	if t.baseURL == nil {
+		return nil
+	}
+
+	u := *t.baseURL
+	if t.baseURL.User != nil {
+		user := *t.baseURL.User
+		u.User = &user
+	}
+	return &u
+}
+
+// GraphQLEndpoint returns GraphQL API endpoint. This is only available
+// for github.com or if configured via [WithEnterpriseHost], otherwise
+// this returns empty string.
+func (t *Transport) GraphQLEndpoint() string {
+	if t.graphqlURL == nil {
+		return ""
+	}
+	return t.graphqlURL.String()
+}
+
+// UploadEndpoint returns endpoint for uploading release assets. This is
+// only available for github.com or if configured via [WithEnterpriseHost],
+// otherwise this returns empty string.
+func (t *Transport) UploadEndpoint() string {
+	if t.uploadURL == nil {
+		return ""
+	}
+	return t.uploadURL.String()
+}
+
+// Unwrap returns the underlying [http.RoundTripper] wrapped by the [Transport].
+// This allows middleware to walk a chain of round trippers, similar to [errors.Unwrap].
+// If no round tripper was configured via [WithRoundTripper], this returns
+// [http.DefaultTransport].
+//
+// Returned round tripper is used by the [Transport] concurrently, thus
+// modifying it is unsafe.
+func (t *Transport) Unwrap() http.RoundTripper {
+	return t.next
+}
+
+// userAgent returns user agent to use for token related API requests.
+// If configured via [WithInstallationInUserAgent], this includes installation id
+// as a comment, followed by comments configured via [WithUserAgentComment].
+func (t *Transport) userAgent() string {
+	ua := t.ua
+	if t.uaInstallID && t.installID != 0 {
+		if ua == "" || ua == api.UAHeaderValue {
+			ua = fmt.Sprintf("%s (installation:%d)", api.UAHeaderValue, t.installID)
+		} else {
+			ua = fmt.Sprintf("%s (installation:%d) %s", ua, t.installID, api.UAHeaderValue)
+		}
+	}
+
+	for _, comment := range t.uaComments {
+		ua += " (" + comment + ")"
+	}
+
+	if t.uaSuffix != "" {
+		ua += " " + t.uaSuffix
+	}
+	return ua
+}
+
+// versionHeaderValue returns X-GitHub-Api-Version header value to use for
+// requests made by the transport itself.
+func (t *Transport) versionHeaderValue() string {
+	if t.apiVersion != "" {
+		return t.apiVersion
+	}
+	return api.VersionHeaderValue
+}
+
+// TokenExpiry returns expiry of the cached installation access token
+// used by the [Transport]. This is useful when handing the token to external
+// processes, like git credential helpers. Returns false if no installation access
+// token is cached yet or if the cached token has expired. This does not make any
+// API requests. See [Transport.CredentialExpiry] to also get expiry of the JWT.
+func (t *Transport) TokenExpiry() (time.Time, bool) {
+	token, ok := t.token.Load().(InstallationToken)
+	if !ok || !token.validFor(0) {
+		return time.Time{}, false
+	}
+	return token.Exp, true
+}
+
+// TokenCreationQuota returns remaining installation access token creation quota
+// and time at which it resets, as reported by GitHub API when the last
+// installation access token was requested. This can be used by high volume
+// apps to back off before hitting the rate limit. If no installation access
+// token has been requested yet, or response did not include rate limit headers,
+// remaining is -1 and reset is zero.
+func (t *Transport) TokenCreationQuota() (remaining int, reset time.Time) {
+	if v, ok := t.quota.Load().(RateLimit); ok {
+		return v.Remaining, v.Reset
+	}
+	return -1, time.Time{}
+}
+
+// LastRateLimit returns rate limit reported by GitHub API in the most recent
+// response to a request made via the [Transport], including requests for
+// installation access tokens, even if the request failed. Requests authenticated
+// with installation access token reflect the rate limit of the installation,
+// while requests authenticated with JWT reflect the rate limit of the app.
+// Use [RateLimit.Resource] to distinguish between different rate limits.
+//
+// Returns false if no request has been made yet, or responses did not include
+// rate limit headers, for example, if rate limiting is disabled on GitHub
+// Enterprise Server. Responses without rate limit headers do not reset
+// previously reported rate limit.
+func (t *Transport) LastRateLimit() (RateLimit, bool) {
+	v, ok := t.rateLimit.Load().(RateLimit)
+	return v, ok
+}
+
 // ScopedPermissions returns permissions configured for the transport.
 // This is not the same as app permissions. This will return nil if
 // no scoped permissions are set.
@@ -232,6 +668,14 @@ func (t *Transport) ScopedPermissions() map[string]string {
 	return maps.Clone(t.scopes)
 }
 
+// InstallationPermissions returns permissions granted to the installation, as
+// reported by the API when [Transport] was created. Unlike [Transport.ScopedPermissions],
+// this is not limited to scoped permissions. This will return nil if [Transport]
+// is not configured with installation options or uses a static installation token.
+func (t *Transport) InstallationPermissions() map[string]string {
+	return maps.Clone(t.installPerms)
+}
+
 // checkApp verifies app id and signer both are valid. This also populates the app's name.
 func (t *Transport) checkApp(ctx context.Context, client *http.Client) error {
 	u := t.baseURL.JoinPath("app")
@@ -241,26 +685,28 @@ func (t *Transport) checkApp(ctx context.Context, client *http.Client) error {
 
 	// Verify the key is valid by making a request to /app.
 	// See - https://docs.github.com/en/rest/apps/apps?apiVersion=2022-11-28
-	resp, err := client.Do(r)
+	resp, data, err := t.doMetadataRequest(client, r)
 	if err != nil {
 		return fmt.Errorf("failed to verify key for app id %d: %w", t.appID, err)
 	}
-	defer resp.Body.Close()
 
 	switch resp.StatusCode {
 	case http.StatusOK:
 	case http.StatusForbidden, http.StatusUnauthorized:
-		return fmt.Errorf("invalid app id or credentials: %s", resp.Status)
+		apiErr := newAPIError(resp, data)
+
+		// Suspended apps cannot authenticate as app.
+		if apiErr.StatusCode == http.StatusForbidden &&
+			strings.Contains(strings.ToLower(apiErr.Message), "suspended") {
+			return fmt.Errorf("%w: %w", ErrAppSuspended, apiErr)
+		}
+		return fmt.Errorf("%w (app id %d): %w", ErrInvalidAppCredentials, t.appID, apiErr)
 	default:
-		return fmt.Errorf("failed to verify key for app id %d - %s", t.appID, resp.Status)
+		return fmt.Errorf("failed to verify key for app id %d - %w", t.appID, newAPIError(resp, data))
 	}
 
-	// Populate app's slug.
+	// Populate app's slug and metadata.
 	appResp := api.App{}
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read response: %w", err)
-	}
 
 	err = json.Unmarshal(data, &appResp)
 	if err != nil {
@@ -268,6 +714,18 @@ func (t *Transport) checkApp(ctx context.Context, client *http.Client) error {
 	}
 
 	t.appSlug = *appResp.Slug
+	t.appMeta = AppMetadata{
+		ID:          t.appID,
+		Slug:        *appResp.Slug,
+		Permissions: appResp.Permissions,
+		Events:      appResp.Events,
+	}
+	if appResp.Name != nil {
+		t.appMeta.Name = *appResp.Name
+	}
+	if appResp.Owner != nil && appResp.Owner.Login != nil {
+		t.appMeta.Owner = *appResp.Owner.Login
+	}
 	return nil
 }
 
@@ -277,46 +735,62 @@ func (t *Transport) checkApp(ctx context.Context, client *http.Client) error {
 //
 // https://docs.github.com/en/rest/apps/apps?apiVersion=2022-11-28#get-a-repository-installation-for-the-authenticated-app--parameters
 func (t *Transport) checkInstallation(ctx context.Context, client *http.Client) error {
-	var u *url.URL
+	var candidates []*url.URL
 	if t.installID != 0 {
-		u = t.baseURL.JoinPath("app", "installations", strconv.FormatUint(t.installID, 10))
+		candidates = append(candidates,
+			t.baseURL.JoinPath("app", "installations", strconv.FormatUint(t.installID, 10)))
 	} else {
-		u = t.baseURL.JoinPath("users", t.owner, "installation")
+		// Owner might be a user or an organization. If installation is not found
+		// via users endpoint, try organizations endpoint before giving up.
+		candidates = append(candidates,
+			t.baseURL.JoinPath("users", t.owner, "installation"),
+			t.baseURL.JoinPath("orgs", t.owner, "installation"))
 	}
 
-	// Set context to use JWT.
-	r, _ := http.NewRequestWithContext(ctxWithJWTKey(ctx), http.MethodGet, u.String(), nil)
-	resp, err := client.Do(r)
-	if err != nil {
-		return fmt.Errorf("error fetching installation for %s: %w", t.owner, err)
+	// If owner was previously resolved via organizations endpoint,
+	// try it first to avoid a lookup which is known to fail.
+	if len(candidates) == 2 && t.installCache != nil {
+		if _, ok := t.installCache.Get(ctx, t.metadataCacheKey(candidates[1])); ok {
+			candidates[0], candidates[1] = candidates[1], candidates[0]
+		}
 	}
-	defer resp.Body.Close()
 
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read response body: %w", err)
-	}
+	var data []byte
+	for i, u := range candidates {
+		// Set context to use JWT.
+		r, _ := http.NewRequestWithContext(ctxWithJWTKey(ctx), http.MethodGet, u.String(), nil)
+		resp, body, err := t.doMetadataRequest(client, r)
+		if err != nil {
+			return fmt.Errorf("error fetching installation for %s: %w", t.owner, err)
+		}
+
+		data = body
+
+		if resp.StatusCode == http.StatusOK {
+			break
+		}
 
-	if resp.StatusCode != http.StatusOK {
-		errResp := &api.ErrorResponse{}
-		err = json.Unmarshal(data, errResp)
-		if err == nil && errResp.Message != "" {
-			return fmt.Errorf("%s(%s)", errResp.Message, resp.Status)
+		if resp.StatusCode != http.StatusNotFound || i == len(candidates)-1 {
+			return newAPIError(resp, data)
 		}
-		return fmt.Errorf("%s", resp.Status)
 	}
 
 	getInstallationResp := api.Installation{}
-	err = json.Unmarshal(data, &getInstallationResp)
+	err := json.Unmarshal(data, &getInstallationResp)
 	if err != nil {
 		return fmt.Errorf("failed to unmarshal response body: %w", err)
 	}
 
-	// Check if installation is suspended.
-	if getInstallationResp.SuspendedAt != nil {
-		if getInstallationResp.SuspendedAt.Time.Before(time.Now()) {
-			return fmt.Errorf("installation id %d is not active", *getInstallationResp.ID)
+	// Check if installation is suspended. Suspensions scheduled in the future
+	// are also reported, as installation would be unusable soon.
+	if getInstallationResp.SuspendedAt != nil && !getInstallationResp.SuspendedAt.Time.IsZero() {
+		suspendedAt := getInstallationResp.SuspendedAt.Time
+		if suspendedAt.After(time.Now()) {
+			return fmt.Errorf("%w: installation id %d is scheduled to be suspended at %s",
+				ErrInstallationSuspended, *getInstallationResp.ID, suspendedAt.Format(time.RFC3339))
 		}
+		return fmt.Errorf("%w: installation id %d is suspended since %s",
+			ErrInstallationSuspended, *getInstallationResp.ID, suspendedAt.Format(time.RFC3339))
 	}
 
 	// Checks is scoped permissions are supported by the app's installation.
@@ -327,6 +801,16 @@ func (t *Transport) checkInstallation(ctx context.Context, client *http.Client)
 		return err
 	}
 
+	// Check if single file is configured for the installation.
+	err = t.checkInstallationSingleFile(getInstallationResp)
+	if err != nil {
+		return err
+	}
+
+	// Save installation permissions. These are also used to verify scoped
+	// permissions of transports created via CloneWith.
+	t.installPerms = getInstallationResp.Permissions
+
 	// Save installation ID.
 	if t.installID == 0 {
 		t.installID = uint64(*getInstallationResp.ID)
@@ -335,62 +819,149 @@ func (t *Transport) checkInstallation(ctx context.Context, client *http.Client)
 			t.installID, *getInstallationResp.ID)
 	}
 
-	// Save owner if not specified. This is the case where only installation id is given.
-	if t.owner == "" {
-		t.owner = *getInstallationResp.Account.Login
+	// Save owner if not specified. This is the case where installation id is given,
+	// optionally with repository names without the owner. Otherwise, ensure owner
+	// matches the installation account, as repositories are looked up by name.
+	if getInstallationResp.Account != nil && getInstallationResp.Account.Login != nil {
+		login := *getInstallationResp.Account.Login
+		if t.owner == "" {
+			t.owner = login
+		} else if !strings.EqualFold(t.owner, login) {
+			return fmt.Errorf("installation id %d belongs to %s, not %s",
+				*getInstallationResp.ID, login, t.owner)
+		}
+
+		if getInstallationResp.Account.ID != nil && *getInstallationResp.Account.ID > 0 {
+			t.ownerID = uint64(*getInstallationResp.Account.ID)
+		}
+	}
+
+	// Save installation target type.
+	if getInstallationResp.TargetType != nil {
+		t.targetType = *getInstallationResp.TargetType
+	}
+
+	// Save canonical access tokens URL, if it can be used by the transport.
+	if getInstallationResp.AccessTokensURL != nil {
+		u, err := url.Parse(*getInstallationResp.AccessTokensURL)
+		if err == nil && u.Scheme == t.baseURL.Scheme && t.isAllowedHost(u) {
+			t.tokenURL = u.String()
+		}
+	}
+	return nil
+}
+
+// checkInstallationToken verifies an installation access token can be created
+// for scopes and repositories configured.
+func (t *Transport) checkInstallationToken(ctx context.Context) error {
+	// Installation token is minted on first use, if only validating.
+	if t.validateOnly {
+		return nil
 	}
 
 	// Try to create a new installation token for scopes and repository specified.
-	// This is immediately used to fetch bot metadata.
-	_, err = t.installationAuthzHeaderValue(ctx)
+	_, err := t.installationAuthzHeaderValue(ctx)
 	if err != nil {
+		// If repositories are not accessible, API only returns a generic error,
+		// check which of the configured repositories are not accessible.
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnprocessableEntity &&
+			(len(t.repos) > 0 || len(t.repoIDs) > 0) {
+			if repoErr := t.checkRepositories(ctx, apiErr); repoErr != nil {
+				return repoErr
+			}
+		}
 		return err
 	}
 
 	return nil
 }
 
-// fetchBotUserID fetches bot's GitHub user id.
-func (t *Transport) fetchBotUserID(ctx context.Context, client *http.Client) error {
-	u := t.baseURL.JoinPath("users", fmt.Sprintf("%s[bot]", t.appSlug))
-	r, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+// unscopedTransport returns [Transport] for the same installation as t, whose
+// installation access tokens are not scoped to repositories. Tokens are only
+// granted "metadata" permission, and are neither cached nor reported via
+// [WithTokenRefreshCallback].
+func (t *Transport) unscopedTransport() *Transport {
+	it := t.derive()
+	it.installID = t.installID
+	it.owner = t.owner
+	it.targetType = t.targetType
+	it.ownerID = t.ownerID
+	it.tokenURL = t.tokenURL
+	it.installPerms = t.installPerms
+	it.scopes = map[string]string{"metadata": "read"}
+	it.cache = nil
+	it.onTokenRefresh = nil
+	return it
+}
+
+// checkRepositories returns [*RepositoryAccessError] if any of the configured
+// repositories are not accessible to the installation. Repositories accessible
+// to the installation are listed with an installation access token, which is not
+// scoped to repositories. If they cannot be listed, this returns nil.
+func (t *Transport) checkRepositories(ctx context.Context, apiErr *APIError) error {
+	repos, err := t.Repositories(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to build request: %w", err)
+		t.debug(ctx, "githubapp: failed to list installation repositories", slog.Any("err", err))
+		return nil
 	}
 
-	resp, err := client.Do(r)
-	if err != nil {
-		return fmt.Errorf("request failed - %w", err)
+	names := make(map[string]struct{}, len(repos))
+	ids := make(map[int64]struct{}, len(repos))
+	for _, repo := range repos {
+		names[strings.ToLower(repo.Name)] = struct{}{}
+		ids[int64(repo.ID)] = struct{}{}
 	}
-	defer resp.Body.Close()
 
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read response: %w", err)
+	repoErr := &RepositoryAccessError{Err: apiErr}
+	for _, name := range t.repos {
+		if _, ok := names[strings.ToLower(name)]; !ok {
+			repoErr.Repositories = append(repoErr.Repositories, name)
+		}
 	}
 
-	// If the API responds with non 200 status, try to read the error message in the response.
-	if resp.StatusCode != http.StatusOK {
-		errResp := &api.ErrorResponse{}
-		err = json.Unmarshal(data, errResp)
-		if err == nil && errResp.Message != "" {
-			return fmt.Errorf("%s(%s)", errResp.Message, resp.Status)
+	for _, id := range t.repoIDs {
+		if _, ok := ids[id]; !ok {
+			repoErr.RepositoryIDs = append(repoErr.RepositoryIDs, id)
 		}
-		return fmt.Errorf("%s", resp.Status)
 	}
 
-	user := api.User{}
-	err = json.Unmarshal(data, &user)
-	if err != nil {
-		return fmt.Errorf("failed to unmarshal response: %w", err)
+	if len(repoErr.Repositories) == 0 && len(repoErr.RepositoryIDs) == 0 {
+		return nil
+	}
+	return repoErr
+}
+
+// useSingleFileScope adds "single_file:read" to scoped permissions, if
+// single file is configured but "single_file" permission is not.
+func (t *Transport) useSingleFileScope() {
+	if t.singleFile == "" {
+		return
 	}
 
-	if user.ID == nil || user.Login == nil {
-		return errors.New("missing user id or login in API response")
+	if _, ok := t.scopes["single_file"]; !ok {
+		if t.scopes == nil {
+			t.scopes = make(map[string]string)
+		}
+		t.scopes["single_file"] = api.PermissionLevelRead
+	}
+}
+
+// checkInstallationSingleFile checks if single file configured via [WithSingleFile]
+// is one of the single files configured for the installation.
+func (t *Transport) checkInstallationSingleFile(installation api.Installation) error {
+	if t.singleFile == "" {
+		return nil
+	}
+
+	paths := slices.Clone(installation.SingleFilePaths)
+	if installation.SingleFileName != nil && *installation.SingleFileName != "" {
+		paths = append(paths, *installation.SingleFileName)
 	}
 
-	t.botUsername = *user.Login
-	t.botEmail = fmt.Sprintf("%d+%s@users.noreply.github.com", *user.ID, *user.Login)
+	if !slices.Contains(paths, t.singleFile) {
+		return fmt.Errorf("single file %q is not configured for the installation: %v", t.singleFile, paths)
+	}
 	return nil
 }
 
@@ -406,6 +977,11 @@ func (t *Transport) checkInstallationPermissions(permissions map[string]string)
 
 	missing := make([]string, 0, len(t.scopes))
 	for scopeName, scopeLevel := range t.scopes {
+		// Explicitly dropping a permission is always possible.
+		if scopeLevel == api.PermissionLevelNone {
+			continue
+		}
+
 		// Lookup if installation permission has that scope.
 		installLevel, ok := permissions[scopeName]
 		if !ok {
@@ -447,83 +1023,204 @@ func (t *Transport) checkInstallationPermissions(permissions map[string]string)
 
 // JWT returns already existing JWT bearer token or mints a new one.
 func (t *Transport) JWT(ctx context.Context) (JWT, error) {
+	if t.closed.Load() {
+		return JWT{}, ErrTransportClosed
+	}
+
+	// Installation transports created via App share the JWT.
+	if t.app != nil {
+		return t.app.JWT(ctx)
+	}
+
+	if t.static != nil {
+		return JWT{}, errors.New("githubapp: JWT is not available with static installation token")
+	}
+
 	v := t.jwt.Load()
 	if v != nil {
-		if bearer, _ := v.(JWT); bearer.IsValid() {
+		bearer, _ := v.(JWT)
+		if bearer.IsValid() {
 			return bearer, nil
 		}
+		t.debug(ctx, "githubapp: renewing expired JWT", slog.Any("jwt", bearer))
 	}
 
+	// Concurrent callers share the JWT minted by one of them.
+	return t.jwtFlight.do(ctx, func(ctx context.Context) (JWT, error) {
+		if v := t.jwt.Load(); v != nil {
+			if bearer, _ := v.(JWT); bearer.IsValid() {
+				return bearer, nil
+			}
+		}
+		return t.mintJWT(ctx)
+	})
+}
+
+// mintJWT mints a new JWT and saves it.
+func (t *Transport) mintJWT(ctx context.Context) (_ JWT, err error) {
+	start := time.Now()
+	defer func() {
+		t.observeTokenMint(TokenKindJWT, start, err)
+	}()
+
 	bearer, err := t.minter.MintJWT(ctx, t.appID, time.Now())
 	if err != nil {
-		return JWT{}, fmt.Errorf("githubapp: failed to mint JWT: %w", err)
+		err = fmt.Errorf("githubapp: failed to mint JWT: %w", err)
+		t.setHealth(err)
+		return JWT{}, err
 	}
 
-	// Sign returns BearerToken without the app slug, add it.
+	// Cache relies on exp reported by the minter, ensure it is accurate.
+	err = verifyJWTClaims(bearer)
+	if err != nil {
+		err = fmt.Errorf("githubapp: minted JWT is inconsistent: %w", err)
+		t.setHealth(err)
+		return JWT{}, err
+	}
+
+	// Sign returns BearerToken without the app slug, add it.
 	bearer.AppName = t.appSlug
 	t.jwt.Store(bearer)
+	t.debug(ctx, "githubapp: minted JWT", slog.Any("jwt", bearer))
 	return bearer, nil
 }
 
 // InstallationToken returns a new installation access token. This always returns
 // a new token, thus callers can safely revoke the token whenever required.
+//
+// If [WithStaticInstallationToken] is specified, this returns the static token
+// until it expires, and [ErrInstallationTokenExpired] afterwards. Revoking it
+// makes the [Transport] unusable.
+//
+// Bot user metadata is fetched after minting the token, if it was not already
+// fetched. Failing to fetch it does not fail minting the token, but bot user
+// metadata of the returned token is not populated. Failures are cached for a
+// minute, thus they do not delay every call. Use [Transport.BotUserMetadata]
+// to handle such errors or [WithEagerBotMetadata] to fetch it in [NewTransport].
 func (t *Transport) InstallationToken(ctx context.Context) (InstallationToken, error) {
-	if t.installID == 0 {
-		return InstallationToken{}, errors.New("githubapp: installation id is not configured")
+	token, err := t.mintInstallationToken(ctx)
+	if err != nil || t.static != nil || token.BotUserID != 0 {
+		return token, err
 	}
 
-	buf, err := json.Marshal(api.InstallationTokenRequest{
-		Repositories: t.repos,
-		Permissions:  t.scopes,
-	})
+	bot, err := t.botUserMetadata(ctx)
 	if err != nil {
-		return InstallationToken{},
-			fmt.Errorf("githubapp(token): failed to marshal token request: %w", err)
+		t.debug(ctx, "githubapp: failed to fetch bot user metadata", slog.Any("err", err))
+		return token, nil
 	}
 
-	tokenURL := t.baseURL.JoinPath(
-		"app", "installations",
-		strconv.FormatUint(t.installID, 10),
-		"access_tokens")
+	if bot != nil {
+		token.BotUsername = bot.username
+		token.BotUserID = bot.id
+		token.BotCommitterEmail = bot.email
+	}
+	return token, nil
+}
 
-	// Force using JWT via ctxWithJWTKey.
-	r, err := http.NewRequestWithContext(
-		ctxWithJWTKey(ctx), http.MethodPost, tokenURL.String(), bytes.NewBuffer(buf))
-	if err != nil {
-		return InstallationToken{},
-			fmt.Errorf("githubapp(token): failed to build token request: %w", err)
+// mintInstallationToken returns a new installation access token. Unlike
+// [Transport.InstallationToken], this does not fetch bot user metadata, as
+// fetching it requires an installation access token.
+func (t *Transport) mintInstallationToken(ctx context.Context) (_ InstallationToken, err error) {
+	if t.closed.Load() {
+		return InstallationToken{}, ErrTransportClosed
 	}
 
-	client := http.Client{
-		Transport: t,
+	if t.static != nil {
+		if !t.static.validFor(0) {
+			return InstallationToken{}, fmt.Errorf("%w (expired at %s)",
+				ErrInstallationTokenExpired, t.static.Exp.Format(time.RFC3339))
+		}
+
+		token := *t.static
+		token.Repositories = slices.Clone(t.static.Repositories)
+		token.Permissions = maps.Clone(t.static.Permissions)
+		return token, nil
 	}
 
-	resp, err := client.Do(r)
-	if err != nil {
-		return InstallationToken{},
-			fmt.Errorf("githubapp(token): failed to get installation token: %w", err)
+	if t.installID == 0 {
+		return InstallationToken{}, errors.New("githubapp: installation id is not configured")
 	}
-	defer resp.Body.Close()
 
-	data, err := io.ReadAll(resp.Body)
+	start := time.Now()
+	defer func() {
+		t.observeTokenMint(TokenKindInstallation, start, err)
+	}()
+
+	buf, err := json.Marshal(api.InstallationTokenRequest{
+		Repositories:  t.repos,
+		RepositoryIDs: t.repoIDs,
+		Permissions:   t.scopes,
+	})
 	if err != nil {
 		return InstallationToken{},
-			fmt.Errorf("githubapp(token): failed to read response: %w", err)
+			fmt.Errorf("githubapp(token): failed to marshal token request: %w", err)
 	}
 
-	if resp.StatusCode != http.StatusCreated {
-		// Try to decode error message if possible.
-		// GitHub API error response JSON is inconsistent.
-		errResp := &api.ErrorResponse{}
-		err = json.Unmarshal(data, errResp)
-		if err == nil && errResp.Message != "" {
-			// Error string MUST include response code or response status
-			// for integration tests to verify.
+	tokenURL := t.AccessTokensURL()
+
+	client := t.internalClient()
+
+	var data []byte
+	var waited time.Duration
+	var rateLimit *RateLimit
+	for {
+		// Force using JWT via ctxWithJWTKey.
+		r, err := http.NewRequestWithContext(
+			ctxWithJWTKey(ctx), http.MethodPost, tokenURL, bytes.NewBuffer(buf))
+		if err != nil {
+			return InstallationToken{},
+				fmt.Errorf("githubapp(token): failed to build token request: %w", err)
+		}
+
+		resp, err := client.Do(r)
+		if err != nil {
+			return InstallationToken{},
+				fmt.Errorf("githubapp(token): failed to get installation token: %w", err)
+		}
+
+		data, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return InstallationToken{},
+				fmt.Errorf("githubapp(token): failed to read response: %w", err)
+		}
+
+		// Save rate limit, even if request failed.
+		rateLimit = nil
+		if rl, ok := parseRateLimit(resp.Header); ok {
+			t.quota.Store(rl)
+			rateLimit = &rl
+		}
+
+		if resp.StatusCode == http.StatusCreated {
+			break
+		}
+
+		// Error string MUST include response code or response status
+		// for integration tests to verify. APIError includes both.
+		apiErr := newAPIError(resp, data)
+
+		// Wait for rate limits to reset if configured.
+		if reset, ok := rateLimitReset(resp, time.Now()); ok {
+			// Wait at-least a second to avoid retrying in a tight loop.
+			wait := max(time.Until(reset), time.Second)
+			if t.rateLimitWait == 0 || waited+wait > t.rateLimitWait || !waitFor(ctx, wait) {
+				return InstallationToken{},
+					fmt.Errorf("githubapp(token): failed to get installation token: %w",
+						&RateLimitError{Reset: reset, Err: apiErr})
+			}
+			waited += wait
+			continue
+		}
+
+		// Check if app or installation is suspended.
+		if err = apiErr.suspendedError(); err != nil {
 			return InstallationToken{},
-				fmt.Errorf("githubapp(token): %s(%s)", errResp.Message, resp.Status)
+				fmt.Errorf("githubapp(token): failed to get installation token: %w", err)
 		}
+
 		return InstallationToken{},
-			fmt.Errorf("githubapp(token): failed to get installation token %s", resp.Status)
+			fmt.Errorf("githubapp(token): failed to get installation token: %w", apiErr)
 	}
 
 	tokenResp := api.InstallationTokenResponse{}
@@ -539,10 +1236,13 @@ func (t *Transport) InstallationToken(ctx context.Context) (InstallationToken, e
 		AppID:          t.appID,
 		AppName:        t.appSlug,
 		InstallationID: t.installID,
-		UserAgent:      t.ua,
+		UserAgent:      t.userAgent(),
 		Token:          tokenResp.Token,
 		Exp:            tokenResp.Exp.Time,
 		Owner:          t.owner,
+		OwnerID:        t.ownerID,
+		TargetType:     t.targetType,
+		RateLimit:      rateLimit,
 	}
 
 	if tokenResp.Repositories != nil {
@@ -554,40 +1254,377 @@ func (t *Transport) InstallationToken(ctx context.Context) (InstallationToken, e
 		}
 	}
 
-	token.BotCommitterEmail = t.botEmail
-	token.BotUsername = t.botUsername
+	// Bot user metadata is only included if it was already fetched, as it
+	// is fetched using the installation token.
+	token.BotCommitterName = t.BotCommitterName()
+	if bot := t.loadedBotUser(); bot != nil {
+		token.BotCommitterEmail = bot.email
+		token.BotUsername = bot.username
+		token.BotUserID = bot.id
+	}
 	if tokenResp.Permissions != nil {
 		token.Permissions = tokenResp.Permissions
 	}
 
+	t.debug(ctx, "githubapp: minted installation token", slog.Any("token", &token))
+
+	t.notifyTokenRefresh(ctx, token)
 	return token, nil
 }
 
+// tokenRefreshCallback is invoked after minting an installation access token.
+type tokenRefreshCallback func(context.Context, InstallationToken)
+
+// notifyTokenRefresh invokes token refresh callbacks, if any. Panics in callbacks
+// are recovered and logged, as token was already minted successfully.
+func (t *Transport) notifyTokenRefresh(ctx context.Context, token InstallationToken) {
+	for _, fn := range t.onTokenRefresh {
+		// Callbacks get their own copy, so they cannot modify the returned token.
+		refreshed := token
+		refreshed.Repositories = slices.Clone(token.Repositories)
+		refreshed.Permissions = maps.Clone(token.Permissions)
+		if token.RateLimit != nil {
+			rl := *token.RateLimit
+			refreshed.RateLimit = &rl
+		}
+
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.warn(ctx, "githubapp: token refresh callback panicked",
+						slog.Any("panic", r), slog.Any("token", &refreshed))
+				}
+			}()
+			fn(ctx, refreshed)
+		}()
+	}
+}
+
+// tokenExpiryMargin returns duration for which cached installation tokens
+// must be valid to be re-used.
+func (t *Transport) tokenExpiryMargin() time.Duration {
+	if t.expiryMargin > 0 {
+		return t.expiryMargin
+	}
+	return time.Minute
+}
+
 // installationAuthzHeaderValue returns Authorization header value to be used
 // for accessing API as installation. The token is automatically refreshed
 // whenever required. This already includes prefix Bearer and can be directly
 // used with [net/http.Header.Set]. If error occurs during creating a new token,
 // header string value is empty.
+//
+// If a [Cache] is configured via [WithTokenCache], it is consulted before
+// minting a new token and newly minted tokens are saved to it.
 func (t *Transport) installationAuthzHeaderValue(ctx context.Context) (string, error) {
 	v := t.token.Load()
 	if v != nil {
-		if token, _ := v.(InstallationToken); token.IsValid() {
+		token, _ := v.(InstallationToken)
+		if token.validFor(t.tokenExpiryMargin()) {
 			return "Bearer " + token.Token, nil
 		}
+		t.debug(ctx, "githubapp: renewing expired installation token", slog.Any("token", &token))
 	}
-	token, err := t.InstallationToken(ctx)
+
+	// Concurrent callers share the token minted by one of them.
+	token, err := t.tokenFlight.do(ctx, func(ctx context.Context) (InstallationToken, error) {
+		if v := t.token.Load(); v != nil {
+			if token, _ := v.(InstallationToken); token.validFor(t.tokenExpiryMargin()) {
+				return token, nil
+			}
+		}
+
+		var key string
+		if t.cache != nil {
+			key = t.tokenCacheKey()
+			if token, ok := t.cache.Get(ctx, key); ok && token.validFor(t.tokenExpiryMargin()) {
+				t.debug(ctx, "githubapp: installation token cache hit", slog.Any("token", &token))
+				t.token.Store(token)
+				return token, nil
+			}
+		}
+
+		token, err := t.mintInstallationToken(ctx)
+		if err != nil {
+			return InstallationToken{}, err
+		}
+		t.token.Store(token)
+
+		if t.cache != nil {
+			// Token is valid even if it cannot be saved to the cache.
+			// Thus, errors from the cache are not propagated.
+			_ = t.cache.Set(ctx, key, token)
+		}
+		return token, nil
+	})
 	if err != nil {
 		return "", err
 	}
 	return "Bearer " + token.Token, nil
 }
 
+// CloneWith returns a new [Transport] for the same app and installation as t,
+// with additional options applied. Verified app, installation and bot metadata
+// are re-used, thus no API calls are made. Scoped permissions specified via
+// [WithPermissions] or [WithPermissionsMap] replace those of t, and are verified
+// against the permissions of the installation. Cloned [Transport] shares JWT
+// with t, but mints its own installation access tokens.
+//
+// Options which change the app, endpoint or installation like [WithEndpoint],
+// [WithInstallationID], [WithOwner], [WithRepositories], [WithSingleFile] and
+// [WithStaticInstallationToken] are rejected, as they require [NewTransport].
+func (t *Transport) CloneWith(opts ...Option) (*Transport, error) {
+	if t.static != nil {
+		return nil, errors.New("githubapp: transport using static installation token cannot be cloned")
+	}
+
+	ct := t.derive()
+
+	// Options may modify these in-place, thus clone them. Scopes are
+	// populated after applying options, as they are replaced if specified.
+	ct.scopes = nil
+	ct.hosts = slices.Clone(t.hosts)
+	ct.uaComments = slices.Clone(t.uaComments)
+	ct.editors = slices.Clone(t.editors)
+	ct.onTokenRefresh = slices.Clone(t.onTokenRefresh)
+	ct.repos = slices.Clone(t.repos)
+	ct.repoIDs = slices.Clone(t.repoIDs)
+
+	// Options not shared by derived transports.
+	ct.accept = t.accept
+	ct.graphqlURL = t.graphqlURL
+	ct.uploadURL = t.uploadURL
+	ct.preserveAuthz = t.preserveAuthz
+	ct.strictScopes = t.strictScopes
+	ct.jwtExpiry = t.jwtExpiry
+	ct.jwtSkew = t.jwtSkew
+	ct.bootstrapTimeout = t.bootstrapTimeout
+
+	// Installation was already verified by t.
+	ct.installID = t.installID
+	ct.singleFile = t.singleFile
+	ct.owner = t.owner
+	ct.targetType = t.targetType
+	ct.ownerID = t.ownerID
+	ct.tokenURL = t.tokenURL
+	ct.bot = t.bot
+	ct.installPerms = t.installPerms
+
+	var err error
+	for i := range opts {
+		if opts[i] != nil {
+			err = errors.Join(err, opts[i].apply(ct))
+		}
+	}
+
+	if ct.scopes == nil {
+		ct.scopes = maps.Clone(t.scopes)
+	} else {
+		ct.useSingleFileScope()
+	}
+
+	if ct.appID != t.appID {
+		err = errors.Join(err, errors.New("app id cannot be changed"))
+	}
+
+	if ct.baseURL.String() != t.baseURL.String() {
+		err = errors.Join(err, errors.New("endpoint cannot be changed"))
+	}
+
+	if ct.static != nil {
+		err = errors.Join(err, errors.New("WithStaticInstallationToken cannot be used"))
+	}
+
+	if ct.installID != t.installID || !strings.EqualFold(ct.owner, t.owner) ||
+		!slices.Equal(ct.repos, t.repos) || !slices.Equal(ct.repoIDs, t.repoIDs) ||
+		ct.singleFile != t.singleFile {
+		err = errors.Join(err, errors.New("installation cannot be changed"))
+	}
+
+	if ct.strictScopes {
+		err = errors.Join(err, checkPermissionNames(ct.scopes))
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("githubapp: invalid options: %w", err)
+	}
+
+	// Scoped permissions are only verified against the known installation permissions.
+	if len(ct.scopes) > 0 {
+		if ct.installID == 0 {
+			return nil, errors.New("githubapp: scoped permissions require an installation")
+		}
+
+		err = ct.checkInstallationPermissions(ct.installPerms)
+		if err != nil {
+			return nil, fmt.Errorf("githubapp: %w", err)
+		}
+	}
+	return ct, nil
+}
+
+// Refresh discards cached JWT and installation access token, and mints new ones.
+// This is useful when credentials are known to be revoked externally, for example,
+// when all installation access tokens are revoked. Installation access tokens
+// for installations and permissions specified via the context are discarded, and
+// are minted on next use. If a [Cache] is configured via [WithTokenCache], newly
+// minted token is saved to it. Errors from minting JWT and installation access
+// token are combined.
+//
+// Refresh is safe for concurrent use. Requests in-flight use either the
+// discarded credentials or the new ones.
+func (t *Transport) Refresh(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if t.closed.Load() {
+		return ErrTransportClosed
+	}
+
+	if t.static != nil {
+		return errors.New("githubapp: cannot refresh static installation token")
+	}
+
+	// Installation transports created via App share the JWT.
+	app := t
+	if t.app != nil {
+		app = t.app
+	}
+	app.jwt.Store(JWT{})
+	t.token.Store(InstallationToken{})
+
+	// Discard transports for installations and permissions from the context.
+	t.installs.drain()
+	t.scoped.drain()
+
+	_, jwtErr := t.JWT(ctx)
+	if t.installID == 0 {
+		return jwtErr
+	}
+
+	token, err := t.mintInstallationToken(ctx)
+	if err != nil {
+		return errors.Join(jwtErr, err)
+	}
+	t.token.Store(token)
+
+	if t.cache != nil {
+		// Token is valid even if it cannot be saved to the cache.
+		_ = t.cache.Set(ctx, t.tokenCacheKey(), token)
+	}
+	return jwtErr
+}
+
+// RevokeToken revokes the installation access token cached by the [Transport],
+// and discards it, so that next request mints a new installation access token.
+// If a [Cache] is configured via [WithTokenCache], revoked token is deleted from it,
+// so that other transports sharing the cache do not use it. If no installation access token
+// is cached, this is a no-op. If revocation fails, token is not discarded.
+// Use [Transport.Refresh] to discard tokens which were revoked externally.
+//
+// Installation access tokens for installations and permissions specified via
+// the context are not revoked.
+func (t *Transport) RevokeToken(ctx context.Context) error {
+	if t.static != nil {
+		return errors.New("githubapp: cannot revoke static installation token")
+	}
+
+	v := t.token.Load()
+	if v == nil {
+		return nil
+	}
+
+	token, _ := v.(InstallationToken)
+	if !token.IsValid() {
+		return nil
+	}
+
+	// Revoke using transport's endpoint and round tripper.
+	token.Server = t.baseURL.String()
+	if err := token.revoke(ctx, t.internalNext(), t.versionHeaderValue()); err != nil {
+		return err
+	}
+	t.token.Store(InstallationToken{})
+
+	if t.cache != nil {
+		// Revoked token is no longer valid, even if deleting it fails.
+		_ = t.cache.Delete(ctx, t.tokenCacheKey())
+	}
+	return nil
+}
+
+// Close revokes the installation access token cached by the [Transport], including
+// those for installations and permissions specified via the context, and discards
+// the JWT. Requests made after Close, including those in-flight which are yet to
+// be authenticated, fail with [ErrTransportClosed]. Static installation tokens
+// configured via [WithStaticInstallationToken] are not revoked, as they are not
+// minted by the [Transport].
+//
+// Close is idempotent and is safe for concurrent use. Only the first call
+// revokes the tokens, subsequent calls return nil.
+func (t *Transport) Close(ctx context.Context) error {
+	if !t.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var err error
+	if t.static == nil {
+		err = t.RevokeToken(ctx)
+
+		// Close transports for installations and permissions from the context.
+		transports := append(t.installs.drain(), t.scoped.drain()...)
+
+		for _, it := range transports {
+			if it.closed.CompareAndSwap(false, true) {
+				err = errors.Join(err, it.RevokeToken(ctx))
+				it.token.Store(InstallationToken{})
+			}
+		}
+	}
+
+	// Installation transports created via App share the JWT with it,
+	// which is not discarded.
+	if t.app == nil {
+		t.jwt.Store(JWT{})
+	}
+	t.token.Store(InstallationToken{})
+
+	if err != nil {
+		return fmt.Errorf("githubapp: failed to revoke installation token: %w", err)
+	}
+	return nil
+}
+
+// RoundTrip implements [http.RoundTripper]. It authenticates the request with
+// JWT or installation access token, renewing them as required.
+//
+// If installation access token is rejected with 401 (Unauthorized), for example,
+// because it was revoked, a new installation access token is minted and request
+// is retried once, if request can be replayed. Requests with a body can only be
+// replayed if [http.Request.GetBody] is set. If a new token cannot be minted,
+// original response is returned.
 func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.roundTrip(req, t.next)
+}
+
+// roundTrip implements [Transport.RoundTrip], but uses next as the
+// underlying round tripper.
+func (t *Transport) roundTrip(req *http.Request, next http.RoundTripper) (*http.Response, error) {
 	if req == nil {
 		return nil, errors.New("githubapp(RoundTrip): request is nil")
 	}
 
-	if !strings.EqualFold(t.baseURL.Host, req.URL.Host) {
+	if t.closed.Load() {
+		return nil, ErrTransportClosed
+	}
+
+	if !t.isAllowedHost(req.URL) {
 		return nil,
 			fmt.Errorf("githubapp(RoundTrip): Host for round tripper(%s) does not match host for request(%s)",
 				t.baseURL.Host, req.URL.Host)
@@ -596,39 +1633,294 @@ func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 	ctx := req.Context()
 	clone := cloneRequest(req) // RoundTripper should not modify request
 
+	// Transport which provided installation access token for the request, if any.
+	var renew *Transport
+
 	// ctxHasKeyJWT is only set for token renewals.
 	if ctxHasJWTKey(ctx) {
 		// Always ignore 'Accept' and 'X-GitHub-Api-Version' headers if
 		// any and always use library defaults.
 		clone.Header.Set(api.AcceptHeader, api.AcceptHeaderValue)
-		clone.Header.Set(api.VersionHeader, api.VersionHeaderValue)
+		clone.Header.Set(api.VersionHeader, t.versionHeaderValue())
 
 		// Use fallback User Agent header if it is missing.
 		if clone.Header.Get(api.UAHeader) == "" {
-			clone.Header.Set(api.UAHeader, t.ua)
+			clone.Header.Set(api.UAHeader, t.userAgent())
+		}
+	} else {
+		// Use default Accept header if it is missing.
+		if t.accept != "" && clone.Header.Get(api.AcceptHeader) == "" {
+			clone.Header.Set(api.AcceptHeader, t.accept)
 		}
+
+		// Use configured API version if it is missing.
+		if t.apiVersion != "" && clone.Header.Get(api.VersionHeader) == "" {
+			clone.Header.Set(api.VersionHeader, t.apiVersion)
+		}
+	}
+
+	// Pass through pre-set Authorization header if configured via
+	// WithPreserveAuthorizationHeader or WithExistingAuthorization.
+	// Token renewals always use JWT.
+	if (t.preserveAuthz || ctxExistingAuthz(ctx)) && !ctxHasJWTKey(ctx) && clone.Header.Get(api.AuthzHeader) != "" {
+		// Health is not updated as the response does not reflect
+		// credentials of the transport.
+		if err := t.editRequest(clone); err != nil {
+			return nil, err
+		}
+
+		//nolint:wrapcheck // don't wrap errors returned by underlying round-tripper.
+		return next.RoundTrip(clone)
 	}
 
 	// Installation id is populated when WithRepositories or WithOrganization
 	// or WithInstallationID etc are used. ctxHasKeyJWT returns true when context
 	// value is set. if ctx is set or no installation-id is specified, transport will
 	// use JWT for authentication. Otherwise, it uses installation access token.
-	if t.installID == 0 || ctxHasJWTKey(ctx) {
+	//
+	// Installation id and permissions specified via the context are handled
+	// by requestTransport.
+	if ctxHasJWTKey(ctx) || (t.installID == 0 && t.static == nil && ctxInstallationID(ctx) == 0) {
 		jwt, err := t.JWT(ctx)
 		if err != nil {
 			return nil, err
 		}
 		clone.Header.Set(api.AuthzHeader, api.AuthzHeaderValue(jwt.Token))
 	} else {
-		authzHeaderValue, err := t.installationAuthzHeaderValue(ctx)
+		var authzHeaderValue string
+		it, err := t.requestTransport(ctx)
+		if err == nil {
+			authzHeaderValue, err = it.installationAuthzHeaderValue(ctx)
+		}
 		if err != nil {
+			if ctx.Err() == nil {
+				t.setHealth(err)
+			}
 			return nil, err
 		}
 		clone.Header.Set(api.AuthzHeader, authzHeaderValue)
+		renew = it
+	}
+
+	if err := t.editRequest(clone); err != nil {
+		return nil, err
+	}
+
+	resp, err := next.RoundTrip(clone)
+	t.setHealthFromResponse(ctx, resp, err)
+	t.saveRateLimit(resp)
+
+	// Installation access token might have been revoked, retry with a new one.
+	// Static installation tokens cannot be renewed.
+	if err == nil && resp.StatusCode == http.StatusUnauthorized && renew != nil && renew.static == nil {
+		return t.retryUnauthorized(renew, req, clone, resp, next)
+	}
+
+	//nolint:wrapcheck // don't wrap errors returned by underlying round-tripper.
+	return resp, err
+}
+
+// retryUnauthorized retries the request rejected with 401 (Unauthorized) once,
+// with a new installation access token minted by it. If request cannot be
+// replayed or a new token cannot be minted, resp is returned as is.
+func (t *Transport) retryUnauthorized(
+	it *Transport, req, clone *http.Request, resp *http.Response, next http.RoundTripper,
+) (*http.Response, error) {
+	ctx := req.Context()
+	if ctx.Err() != nil || !isReplayable(req) {
+		return resp, nil
+	}
+
+	stale := strings.TrimPrefix(clone.Header.Get(api.AuthzHeader), "Bearer ")
+	authzHeaderValue, err := it.renewInstallationToken(ctx, stale)
+	if err != nil {
+		t.debug(ctx, "githubapp: failed to renew rejected installation token", slog.Any("err", err))
+		return resp, nil
+	}
+
+	retry := clone.Clone(ctx)
+	if req.GetBody != nil {
+		retry.Body, err = req.GetBody()
+		if err != nil {
+			return resp, nil
+		}
 	}
+	retry.Header.Set(api.AuthzHeader, authzHeaderValue)
+
+	// Drain the body, so that connection can be re-used.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+	resp.Body.Close()
+
+	t.debug(ctx, "githubapp: retrying request with renewed installation token")
+	resp, err = next.RoundTrip(retry)
+	t.setHealthFromResponse(ctx, resp, err)
+	t.saveRateLimit(resp)
 
 	//nolint:wrapcheck // don't wrap errors returned by underlying round-tripper.
-	return t.next.RoundTrip(clone)
+	return resp, err
+}
+
+// isReplayable reports whether request can be sent again. Requests with body
+// are only replayable if GetBody is set.
+func isReplayable(req *http.Request) bool {
+	if req.GetBody != nil {
+		return true
+	}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		return false
+	}
+
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace,
+		http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// renewInstallationToken mints a new installation access token, as stale token
+// was rejected. If token was already renewed, for example, by a concurrent
+// request, it is re-used. Unlike [Transport.installationAuthzHeaderValue],
+// cache is not consulted, as it might have the stale token.
+func (t *Transport) renewInstallationToken(ctx context.Context, stale string) (string, error) {
+	if v := t.token.Load(); v != nil {
+		token, _ := v.(InstallationToken)
+		if token.Token != stale && token.validFor(t.tokenExpiryMargin()) {
+			return "Bearer " + token.Token, nil
+		}
+	}
+
+	// Concurrent callers rejected with the same token share the renewed token.
+	// This does not share the in-flight calls of installationAuthzHeaderValue,
+	// as they might return the stale token.
+	token, err := t.renewFlight.do(ctx, func(ctx context.Context) (InstallationToken, error) {
+		if v := t.token.Load(); v != nil {
+			if token, _ := v.(InstallationToken); token.Token != stale && token.validFor(t.tokenExpiryMargin()) {
+				return token, nil
+			}
+		}
+
+		token, err := t.mintInstallationToken(ctx)
+		if err != nil {
+			return InstallationToken{}, err
+		}
+		t.token.Store(token)
+
+		if t.cache != nil {
+			// Token is valid even if it cannot be saved to the cache.
+			_ = t.cache.Set(ctx, t.tokenCacheKey(), token)
+		}
+		return token, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return "Bearer " + token.Token, nil
+}
+
+// editRequest applies request editors configured via [WithRequestEditor], if any.
+func (t *Transport) editRequest(r *http.Request) error {
+	for _, editor := range t.editors {
+		if err := editor(r); err != nil {
+			return fmt.Errorf("githubapp(RoundTrip): request editor failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// internalRoundTripper authenticates requests like [Transport], but uses next
+// as the underlying round tripper. This is used by [WithHTTPClient].
+type internalRoundTripper struct {
+	t    *Transport
+	next http.RoundTripper
+}
+
+// RoundTrip implements [http.RoundTripper].
+func (rt *internalRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return rt.t.roundTrip(req, rt.next)
+}
+
+// internalNext returns round tripper used for internal requests.
+func (t *Transport) internalNext() http.RoundTripper {
+	if t.httpClient == nil {
+		return t.next
+	}
+
+	if t.httpClient.Transport == nil {
+		return http.DefaultTransport
+	}
+	return t.httpClient.Transport
+}
+
+// internalClient returns [http.Client] for requests made by the [Transport]
+// itself, which are authenticated by the [Transport]. If a client is configured
+// via [WithHTTPClient], its Transport and Timeout are used.
+func (t *Transport) internalClient() *http.Client {
+	if t.httpClient == nil {
+		return newInternalClient(t)
+	}
+
+	client := newInternalClient(&internalRoundTripper{t: t, next: t.internalNext()})
+	client.Timeout = t.httpClient.Timeout
+	return client
+}
+
+// newInternalClient returns [http.Client] used for requests made by this
+// package, like bootstrapping and token renewals. If rt is nil,
+// [http.DefaultTransport] is used.
+//
+// Such requests carry 'Authorization' header, thus redirects to a different
+// host or from https to http are never followed to avoid leaking credentials.
+func newInternalClient(rt http.RoundTripper) *http.Client {
+	return &http.Client{
+		Transport:     rt,
+		CheckRedirect: checkRedirect,
+	}
+}
+
+// checkRedirect refuses cross-host redirects and redirects which downgrade
+// scheme from https to http. Otherwise, it follows default policy of
+// stopping after 10 redirects.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+
+	prev := via[len(via)-1]
+	if !strings.EqualFold(req.URL.Host, prev.URL.Host) {
+		return fmt.Errorf("refusing to follow cross-host redirect from %s to %s",
+			prev.URL.Host, req.URL.Host)
+	}
+
+	if strings.EqualFold(prev.URL.Scheme, "https") && !strings.EqualFold(req.URL.Scheme, "https") {
+		return fmt.Errorf("refusing to follow redirect from https to %s", req.URL.Scheme)
+	}
+	return nil
+}
+
+// isAllowedHost checks if requests to URL can be authenticated.
+// Hosts are compared case-insensitively, ignoring default ports.
+func (t *Transport) isAllowedHost(u *url.URL) bool {
+	host := canonicalHost(u.Scheme, u.Host)
+	if host == canonicalHost(t.baseURL.Scheme, t.baseURL.Host) {
+		return true
+	}
+	return slices.Contains(t.hosts, host)
+}
+
+// canonicalHost returns lower cased host without the default port for the scheme.
+// If scheme is empty, both http and https default ports are removed.
+func canonicalHost(scheme, host string) string {
+	host = strings.ToLower(host)
+	port := (&url.URL{Host: host}).Port()
+	switch {
+	case port == "443" && scheme != "http",
+		port == "80" && scheme != "https":
+		return strings.TrimSuffix(host, ":"+port)
+	}
+	return host
 }
 
 // cloneRequest returns a clone of the provided *http.Request.
@@ -641,5 +1933,8 @@ func cloneRequest(r *http.Request) *http.Request {
 
 	// shallow copy of the Headers.
 	clone.Header = maps.Clone(r.Header)
+	if clone.Header == nil {
+		clone.Header = make(http.Header)
+	}
 	return clone
 }
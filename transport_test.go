@@ -4,16 +4,84 @@
 package githubapp
 
 import (
+	"bytes"
 	"context"
 	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
 	"reflect"
 	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/tprasadtp/go-githubapp/githubapptest"
+	"github.com/tprasadtp/go-githubapp/internal/api"
+	"github.com/tprasadtp/go-githubapp/internal/testdata/apitestdata"
 	"github.com/tprasadtp/go-githubapp/internal/testkeys"
 )
 
+// requestRecorder is a [http.RoundTripper] which records requests made to
+// mock servers, like [githubapptest.NewServer], before sending them via
+// [http.DefaultTransport].
+type requestRecorder struct {
+	mu       sync.Mutex
+	requests []*http.Request
+}
+
+// RoundTrip implements [http.RoundTripper].
+func (rec *requestRecorder) RoundTrip(r *http.Request) (*http.Response, error) {
+	rec.mu.Lock()
+	rec.requests = append(rec.requests, r.Clone(r.Context()))
+	rec.mu.Unlock()
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+// paths returns paths of the recorded requests.
+func (rec *requestRecorder) paths() []string {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	paths := make([]string, 0, len(rec.requests))
+	for _, r := range rec.requests {
+		paths = append(paths, r.URL.Path)
+	}
+	return paths
+}
+
+// count returns number of recorded requests with the given path.
+func (rec *requestRecorder) count(path string) int {
+	n := 0
+	for _, v := range rec.paths() {
+		if v == path {
+			n++
+		}
+	}
+	return n
+}
+
+// headers returns values of the header for the recorded requests.
+func (rec *requestRecorder) headers(name string) []string {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	values := make([]string, 0, len(rec.requests))
+	for _, r := range rec.requests {
+		values = append(values, r.Header.Get(name))
+	}
+	return values
+}
+
 // transportCmp compares two transports. But ignores some fields.
 func transportCmp(t *testing.T, a, b *Transport) bool {
 	t.Helper()
@@ -304,6 +372,28 @@ func TestTransport_checkInstallationPermissions(t *testing.T) {
 				"contents": "read",
 			},
 		},
+		{
+			name: "valid-scope-none",
+			permissions: map[string]string{
+				"contents": "read",
+				"issues":   "write",
+			},
+			scopes: map[string]string{
+				"contents": "none",
+				"issues":   "write",
+			},
+			ok: true,
+		},
+		{
+			name: "valid-scope-none-missing-from-install",
+			permissions: map[string]string{
+				"issues": "write",
+			},
+			scopes: map[string]string{
+				"contents": "none",
+			},
+			ok: true,
+		},
 		{
 			name: "valid-empty-scope",
 			permissions: map[string]string{
@@ -420,3 +510,2455 @@ func TestTransport_JWT(t *testing.T) {
 		}
 	})
 }
+
+func TestTransport_UserAgent(t *testing.T) {
+	tt := []struct {
+		name        string
+		ua          string
+		uaInstallID bool
+		uaComments  []string
+		uaSuffix    string
+		expect      string
+	}{
+		{
+			name:   "default",
+			ua:     api.UAHeaderValue,
+			expect: api.UAHeaderValue,
+		},
+		{
+			name:   "custom",
+			ua:     "my-app/1.0",
+			expect: "my-app/1.0",
+		},
+		{
+			name:        "default-with-installation",
+			ua:          api.UAHeaderValue,
+			uaInstallID: true,
+			expect:      api.UAHeaderValue + " (installation:12345)",
+		},
+		{
+			name:        "custom-with-installation",
+			ua:          "my-app/1.0",
+			uaInstallID: true,
+			expect:      "my-app/1.0 (installation:12345) " + api.UAHeaderValue,
+		},
+		{
+			name:       "default-with-comments",
+			ua:         api.UAHeaderValue,
+			uaComments: []string{"+https://example.com/bot", "linux"},
+			expect:     api.UAHeaderValue + " (+https://example.com/bot) (linux)",
+		},
+		{
+			name:        "custom-with-installation-and-comment",
+			ua:          "my-app/1.0",
+			uaInstallID: true,
+			uaComments:  []string{"+https://example.com/bot"},
+			expect:      "my-app/1.0 (installation:12345) " + api.UAHeaderValue + " (+https://example.com/bot)",
+		},
+		{
+			name:     "default-with-suffix",
+			ua:       api.UAHeaderValue,
+			uaSuffix: "my-app/1.0",
+			expect:   api.UAHeaderValue + " my-app/1.0",
+		},
+		{
+			name:        "default-with-installation-comment-and-suffix",
+			ua:          api.UAHeaderValue,
+			uaInstallID: true,
+			uaComments:  []string{"+https://example.com/bot"},
+			uaSuffix:    "my-app/1.0",
+			expect:      api.UAHeaderValue + " (installation:12345) (+https://example.com/bot) my-app/1.0",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var got string
+			u, _ := url.Parse("https://api.go-githubapp.test/")
+			transport := &Transport{
+				appID:       99,
+				installID:   12345,
+				ua:          tc.ua,
+				uaInstallID: tc.uaInstallID,
+				uaComments:  tc.uaComments,
+				uaSuffix:    tc.uaSuffix,
+				baseURL:     u,
+				minter:      &jwtRS256{internal: testkeys.RSA2048()},
+				next: api.RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+					got = r.Header.Get(api.UAHeader)
+					resp := httptest.NewRecorder()
+					resp.WriteHeader(http.StatusCreated)
+					_, _ = resp.WriteString(`{"token":"ghs_token","expires_at":"2099-01-01T00:00:00Z"}`)
+					return resp.Result(), nil
+				}),
+			}
+
+			token, err := transport.InstallationToken(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if got != tc.expect {
+				t.Errorf("expected user agent=%q, got=%q", tc.expect, got)
+			}
+
+			if token.UserAgent != tc.expect {
+				t.Errorf("expected token.UserAgent=%q, got=%q", tc.expect, token.UserAgent)
+			}
+		})
+	}
+}
+
+func TestNewTransport_SignerUnusable(t *testing.T) {
+	transport, err := NewTransport(
+		context.Background(),
+		99,
+		&errSigner{signer: testkeys.RSA2048()},
+		WithInstallationID(99),
+		WithRoundTripper(api.RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+			t.Errorf("no network calls must be made with unusable signer: %s", r.URL)
+			return nil, os.ErrInvalid
+		})),
+	)
+
+	if transport != nil {
+		t.Errorf("expected transport to be nil")
+	}
+
+	if !errors.Is(err, ErrSignerUnusable) {
+		t.Errorf("expected error to be %s, got %s", ErrSignerUnusable, err)
+	}
+
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected error to wrap signer error, got %s", err)
+	}
+}
+
+func TestTransport_RoundTrip_AcceptHeader(t *testing.T) {
+	const accept = "application/vnd.github.raw"
+	tt := []struct {
+		name    string
+		ctx     context.Context
+		request string
+		expect  string
+	}{
+		{
+			name:   "default-applied",
+			ctx:    context.Background(),
+			expect: accept,
+		},
+		{
+			name:    "request-not-overwritten",
+			ctx:     context.Background(),
+			request: "application/vnd.github.diff",
+			expect:  "application/vnd.github.diff",
+		},
+		{
+			name:    "internal-requests-use-library-default",
+			ctx:     ctxWithJWTKey(context.Background()),
+			request: "application/vnd.github.diff",
+			expect:  api.AcceptHeaderValue,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var got string
+			u, _ := url.Parse("https://api.go-githubapp.test/")
+			transport := &Transport{
+				appID:   99,
+				baseURL: u,
+				accept:  accept,
+				minter:  &jwtRS256{internal: testkeys.RSA2048()},
+				next: api.RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+					got = r.Header.Get(api.AcceptHeader)
+					resp := httptest.NewRecorder()
+					resp.WriteHeader(http.StatusOK)
+					return resp.Result(), nil
+				}),
+			}
+
+			r, _ := http.NewRequestWithContext(tc.ctx, http.MethodGet, u.JoinPath("app").String(), nil)
+			if tc.request != "" {
+				r.Header.Set(api.AcceptHeader, tc.request)
+			}
+
+			resp, err := transport.RoundTrip(r)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			resp.Body.Close()
+
+			if got != tc.expect {
+				t.Errorf("expected Accept=%q, got=%q", tc.expect, got)
+			}
+
+			if r.Header.Get(api.AcceptHeader) != tc.request {
+				t.Errorf("original request must not be modified")
+			}
+		})
+	}
+}
+
+func TestNewTransport_APIVersion(t *testing.T) {
+	const version = "2026-03-10"
+	server := githubapptest.NewServer()
+	t.Cleanup(server.Close)
+
+	rec := &requestRecorder{}
+	transport, err := NewTransport(context.Background(), githubapptest.AppID, testkeys.RSA2048(),
+		WithEndpoint(server.URL),
+		WithAPIVersion(version),
+		WithRoundTripper(rec),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	client := &http.Client{Transport: transport}
+	for _, item := range []string{"/user-request", "/user-request-with-version"} {
+		r, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL+item, nil)
+		if item == "/user-request-with-version" {
+			r.Header.Set(api.VersionHeader, "2022-11-28")
+		}
+		resp, err := client.Do(r)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		resp.Body.Close()
+	}
+
+	paths := rec.paths()
+	if !slices.Equal(paths, []string{"/app", "/user-request", "/user-request-with-version"}) {
+		t.Fatalf("unexpected requests: %v", paths)
+	}
+
+	for i, v := range rec.headers(api.VersionHeader) {
+		expect := version
+		if paths[i] == "/user-request-with-version" {
+			expect = "2022-11-28"
+		}
+
+		if v != expect {
+			t.Errorf("%s: expected %s=%s, got=%s", paths[i], api.VersionHeader, expect, v)
+		}
+	}
+}
+
+func TestCheckRedirect(t *testing.T) {
+	mustParse := func(s string) *http.Request {
+		r, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, s, nil)
+		return r
+	}
+	tt := []struct {
+		name string
+		req  *http.Request
+		via  []*http.Request
+		ok   bool
+	}{
+		{
+			name: "same-host",
+			req:  mustParse("https://api.github.com/bar"),
+			via:  []*http.Request{mustParse("https://api.github.com/foo")},
+			ok:   true,
+		},
+		{
+			name: "same-host-different-case",
+			req:  mustParse("https://API.github.com/bar"),
+			via:  []*http.Request{mustParse("https://api.github.com/foo")},
+			ok:   true,
+		},
+		{
+			name: "cross-host",
+			req:  mustParse("https://attacker.go-githubapp.test/bar"),
+			via:  []*http.Request{mustParse("https://api.github.com/foo")},
+		},
+		{
+			name: "cross-port",
+			req:  mustParse("https://api.github.com:8443/bar"),
+			via:  []*http.Request{mustParse("https://api.github.com/foo")},
+		},
+		{
+			name: "scheme-downgrade",
+			req:  mustParse("http://api.github.com/bar"),
+			via:  []*http.Request{mustParse("https://api.github.com/foo")},
+		},
+		{
+			name: "too-many-redirects",
+			req:  mustParse("https://api.github.com/bar"),
+			via: func() []*http.Request {
+				v := make([]*http.Request, 0, 10)
+				for i := 0; i < 10; i++ {
+					v = append(v, mustParse("https://api.github.com/foo"))
+				}
+				return v
+			}(),
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkRedirect(tc.req, tc.via)
+			if tc.ok && err != nil {
+				t.Errorf("expected no error, got %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Errorf("expected an error, got nil")
+			}
+		})
+	}
+}
+
+func TestInternalClient_CrossHostRedirect(t *testing.T) {
+	var leaked atomic.Bool
+	attacker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(api.AuthzHeader) != "" {
+			leaked.Store(true)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(attacker.Close)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, attacker.URL+r.URL.Path, http.StatusTemporaryRedirect)
+	}))
+	t.Cleanup(server.Close)
+
+	t.Run("revoke", func(t *testing.T) {
+		token := InstallationToken{
+			Token:  "ghs_token",
+			Server: server.URL,
+			Exp:    time.Now().Add(time.Hour),
+		}
+		err := token.Revoke(context.Background())
+		if err == nil {
+			t.Errorf("expected an error, got nil")
+		}
+	})
+
+	t.Run("installations", func(t *testing.T) {
+		u, _ := url.Parse(server.URL)
+		transport := &Transport{
+			appID:   99,
+			baseURL: u,
+			minter:  &jwtRS256{internal: testkeys.RSA2048()},
+			next:    http.DefaultTransport,
+		}
+		_, err := transport.Installations(context.Background())
+		if err == nil {
+			t.Errorf("expected an error, got nil")
+		}
+	})
+
+	if leaked.Load() {
+		t.Errorf("credentials leaked via cross-host redirect")
+	}
+}
+
+func TestTransport_RoundTrip_AdditionalHosts(t *testing.T) {
+	u, _ := url.Parse("https://api.go-githubapp.test/")
+	transport := &Transport{
+		appID:     99,
+		installID: 99,
+		baseURL:   u,
+		hosts:     []string{"graphql.go-githubapp.test"},
+		minter:    &jwtRS256{internal: testkeys.RSA2048()},
+		next: api.RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+			resp := httptest.NewRecorder()
+			if strings.HasSuffix(r.URL.Path, "/access_tokens") {
+				resp.WriteHeader(http.StatusCreated)
+				_, _ = resp.WriteString(`{"token":"ghs_token","expires_at":"2099-01-01T00:00:00Z"}`)
+				return resp.Result(), nil
+			}
+			if v := r.Header.Get(api.AuthzHeader); v != "Bearer ghs_token" {
+				t.Errorf("expected installation token for %s, got %q", r.URL.Host, v)
+			}
+			resp.WriteHeader(http.StatusOK)
+			return resp.Result(), nil
+		}),
+	}
+
+	tt := []struct {
+		name string
+		url  string
+		ok   bool
+	}{
+		{name: "endpoint", url: "https://api.go-githubapp.test/repos", ok: true},
+		{name: "additional-host", url: "https://graphql.go-githubapp.test/graphql", ok: true},
+		{name: "additional-host-case", url: "https://GraphQL.go-githubapp.test/graphql", ok: true},
+		{name: "unknown-host", url: "https://attacker.go-githubapp.test/graphql"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, tc.url, nil)
+			resp, err := transport.RoundTrip(r)
+			if tc.ok {
+				if err != nil {
+					t.Fatalf("expected no error, got %s", err)
+				}
+				resp.Body.Close()
+			} else if err == nil {
+				resp.Body.Close()
+				t.Errorf("expected an error, got nil")
+			}
+		})
+	}
+}
+
+func TestTransport_AccessTokensURL(t *testing.T) {
+	m := apitestdata.Get(t)
+
+	t.Run("no-installation", func(t *testing.T) {
+		transport := &Transport{appID: 99}
+		if v := transport.AccessTokensURL(); v != "" {
+			t.Errorf("expected empty access tokens url, got %s", v)
+		}
+	})
+
+	tt := []struct {
+		name      string
+		canonical func(server string) string
+		expect    func(server string) string
+	}{
+		{
+			name: "canonical",
+			canonical: func(server string) string {
+				return server + "/canonical/access_tokens"
+			},
+			expect: func(server string) string {
+				return server + "/canonical/access_tokens"
+			},
+		},
+		{
+			name: "canonical-different-host",
+			canonical: func(_ string) string {
+				return "https://attacker.go-githubapp.test/access_tokens"
+			},
+			expect: func(server string) string {
+				return fmt.Sprintf("%s/app/installations/%d/access_tokens", server, apitestdata.InstallationID)
+			},
+		},
+		{
+			name: "missing",
+			expect: func(server string) string {
+				return fmt.Sprintf("%s/app/installations/%d/access_tokens", server, apitestdata.InstallationID)
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var tokenPath atomic.Value
+			var server *httptest.Server
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.URL.Path == "/app":
+					_, _ = w.Write(m["get-app"])
+				case r.URL.Path == fmt.Sprintf("/app/installations/%d", apitestdata.InstallationID):
+					installation := map[string]any{}
+					_ = json.Unmarshal(m["get-installation-by-id"], &installation)
+					delete(installation, "access_tokens_url")
+					if tc.canonical != nil {
+						installation["access_tokens_url"] = tc.canonical(server.URL)
+					}
+					_ = json.NewEncoder(w).Encode(installation)
+				case strings.HasSuffix(r.URL.Path, "/access_tokens"):
+					tokenPath.Store(r.URL.Path)
+					w.WriteHeader(http.StatusCreated)
+					_, _ = w.Write(m["post-installation-token"])
+				case r.URL.Path == fmt.Sprintf("/users/%s[bot]", apitestdata.AppSlug):
+					_, _ = w.Write(m["get-user-bot"])
+				default:
+					t.Errorf("Unknown/Invalid Request => %s", r.URL)
+				}
+			}))
+			t.Cleanup(server.Close)
+
+			transport, err := NewTransport(context.Background(), apitestdata.AppID, testkeys.RSA2048(),
+				WithEndpoint(server.URL),
+				WithInstallationID(apitestdata.InstallationID),
+			)
+			if err != nil {
+				t.Fatalf("expected no error, got %s", err)
+			}
+
+			expect := tc.expect(server.URL)
+			if v := transport.AccessTokensURL(); v != expect {
+				t.Errorf("expected access tokens url=%s, got=%s", expect, v)
+			}
+
+			u, _ := url.Parse(expect)
+			if v, _ := tokenPath.Load().(string); v != u.Path {
+				t.Errorf("expected token to be minted via %s, got %s", u.Path, v)
+			}
+		})
+	}
+}
+
+func TestCanonicalHost(t *testing.T) {
+	tt := []struct {
+		scheme string
+		host   string
+		expect string
+	}{
+		{scheme: "https", host: "UPLOADS.github.com", expect: "uploads.github.com"},
+		{scheme: "https", host: "uploads.github.com:443", expect: "uploads.github.com"},
+		{scheme: "https", host: "uploads.github.com:80", expect: "uploads.github.com:80"},
+		{scheme: "http", host: "uploads.github.com:80", expect: "uploads.github.com"},
+		{scheme: "http", host: "uploads.github.com:443", expect: "uploads.github.com:443"},
+		{scheme: "https", host: "uploads.github.com:8443", expect: "uploads.github.com:8443"},
+		{scheme: "https", host: "[::1]:443", expect: "[::1]"},
+		{host: "uploads.github.com:443", expect: "uploads.github.com"},
+		{host: "uploads.github.com:80", expect: "uploads.github.com"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.scheme+"-"+tc.host, func(t *testing.T) {
+			if v := canonicalHost(tc.scheme, tc.host); v != tc.expect {
+				t.Errorf("expected=%s, got=%s", tc.expect, v)
+			}
+		})
+	}
+}
+
+func TestTransport_RoundTrip_HostAllowlist(t *testing.T) {
+	newTransport := func(opts ...Option) *Transport {
+		u, _ := url.Parse(api.DefaultEndpoint)
+		transport := &Transport{
+			appID:     99,
+			installID: 99,
+			baseURL:   u,
+			minter:    &jwtRS256{internal: testkeys.RSA2048()},
+			next: api.RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+				resp := httptest.NewRecorder()
+				if strings.HasSuffix(r.URL.Path, "/access_tokens") {
+					resp.WriteHeader(http.StatusCreated)
+					_, _ = resp.WriteString(`{"token":"ghs_token","expires_at":"2099-01-01T00:00:00Z"}`)
+					return resp.Result(), nil
+				}
+				if v := r.Header.Get(api.AuthzHeader); v != "Bearer ghs_token" {
+					t.Errorf("expected installation token for %s, got %q", r.URL.Host, v)
+				}
+				resp.WriteHeader(http.StatusCreated)
+				return resp.Result(), nil
+			}),
+		}
+		for _, opt := range opts {
+			if err := opt.apply(transport); err != nil {
+				t.Fatalf("expected no error, got %s", err)
+			}
+		}
+		return transport
+	}
+
+	tt := []struct {
+		name      string
+		transport *Transport
+		url       string
+		ok        bool
+	}{
+		{
+			name:      "allowlisted",
+			transport: newTransport(WithAdditionalHosts("uploads.github.com")),
+			url:       "https://uploads.github.com/repos/foo/bar/releases/1/assets?name=foo.zip",
+			ok:        true,
+		},
+		{
+			name:      "allowlisted-default-port",
+			transport: newTransport(WithAdditionalHosts("Uploads.GitHub.com:443")),
+			url:       "https://uploads.github.com:443/repos/foo/bar/releases/1/assets?name=foo.zip",
+			ok:        true,
+		},
+		{
+			name:      "endpoint-default-port",
+			transport: newTransport(),
+			url:       "https://api.github.com:443/repos/foo/bar",
+			ok:        true,
+		},
+		{
+			name:      "not-allowlisted",
+			transport: newTransport(),
+			url:       "https://uploads.github.com/repos/foo/bar/releases/1/assets?name=foo.zip",
+		},
+		{
+			name:      "allowlisted-non-default-port",
+			transport: newTransport(WithAdditionalHosts("uploads.github.com")),
+			url:       "https://uploads.github.com:8443/repos/foo/bar/releases/1/assets?name=foo.zip",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, tc.url, nil)
+			resp, err := tc.transport.RoundTrip(r)
+			if tc.ok {
+				if err != nil {
+					t.Fatalf("expected no error, got %s", err)
+				}
+				resp.Body.Close()
+			} else if err == nil {
+				resp.Body.Close()
+				t.Errorf("expected an error, got nil")
+			}
+		})
+	}
+}
+
+func TestNewTransport_WithoutBotMetadata(t *testing.T) {
+	server := githubapptest.NewServer()
+	t.Cleanup(server.Close)
+
+	rec := &requestRecorder{}
+	transport, err := NewTransport(context.Background(), githubapptest.AppID, testkeys.RSA2048(),
+		WithEndpoint(server.URL),
+		WithInstallationID(githubapptest.InstallationID),
+		WithoutBotMetadata(),
+		WithRoundTripper(rec),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	if transport.BotUsername() != "" || transport.BotCommitterEmail() != "" {
+		t.Errorf("expected bot metadata to be empty, got username=%q, email=%q",
+			transport.BotUsername(), transport.BotCommitterEmail())
+	}
+
+	if v := rec.count(fmt.Sprintf("/users/%s[bot]", githubapptest.AppSlug)); v != 0 {
+		t.Errorf("expected bot user not to be fetched, got %d requests", v)
+	}
+}
+
+func TestNewTransport_UserAgentSuffix(t *testing.T) {
+	const expect = api.UAHeaderValue + " my-app/1.0"
+	server := githubapptest.NewServer()
+	t.Cleanup(server.Close)
+
+	rec := &requestRecorder{}
+	transport, err := NewTransport(context.Background(), githubapptest.AppID, testkeys.RSA2048(),
+		WithEndpoint(server.URL),
+		WithInstallationID(githubapptest.InstallationID),
+		WithUserAgentSuffix("my-app/1.0"),
+		WithRoundTripper(rec),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	token, err := transport.InstallationToken(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	if token.UserAgent != expect {
+		t.Errorf("expected token.UserAgent=%q, got=%q", expect, token.UserAgent)
+	}
+
+	paths := rec.paths()
+	for i, v := range rec.headers(api.UAHeader) {
+		if v != expect {
+			t.Errorf("%s: expected user agent=%q, got=%q", paths[i], expect, v)
+		}
+	}
+}
+
+func TestNewTransport_DefaultEndpoints(t *testing.T) {
+	t.Run("github.com", func(t *testing.T) {
+		transport, err := newTransport(99)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		if v := transport.Endpoint(); v.String() != api.DefaultEndpoint {
+			t.Errorf("expected endpoint=%s, got=%s", api.DefaultEndpoint, v)
+		}
+
+		if v := transport.GraphQLEndpoint(); v != api.DefaultGraphQLEndpoint {
+			t.Errorf("expected graphql endpoint=%s, got=%s", api.DefaultGraphQLEndpoint, v)
+		}
+
+		if v := transport.UploadEndpoint(); v != api.DefaultUploadEndpoint {
+			t.Errorf("expected upload endpoint=%s, got=%s", api.DefaultUploadEndpoint, v)
+		}
+	})
+
+	t.Run("custom-endpoint", func(t *testing.T) {
+		transport, err := newTransport(99,
+			WithEnterpriseHost("ghes.go-githubapp.test"),
+			WithEndpoint("https://api.go-githubapp.test/"))
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		if v := transport.GraphQLEndpoint(); v != "" {
+			t.Errorf("expected graphql endpoint to be empty, got=%s", v)
+		}
+
+		// Endpoint must be a copy.
+		endpoint := transport.Endpoint()
+		if endpoint.String() != "https://api.go-githubapp.test/" {
+			t.Errorf("expected endpoint=https://api.go-githubapp.test/, got=%s", endpoint)
+		}
+
+		endpoint.Host = "evil.go-githubapp.test"
+		endpoint.Path = "/modified"
+		if v := transport.Endpoint(); v.String() != "https://api.go-githubapp.test/" {
+			t.Errorf("expected endpoint to be immutable, got=%s", v)
+		}
+
+		if v := transport.UploadEndpoint(); v != "" {
+			t.Errorf("expected upload endpoint to be empty, got=%s", v)
+		}
+	})
+}
+
+func TestTransport_OnTokenRefresh(t *testing.T) {
+	var refreshed []InstallationToken
+	u, _ := url.Parse("https://api.go-githubapp.test/")
+	transport := &Transport{
+		appID:     99,
+		appSlug:   "gh-integration-tests-app",
+		installID: 99,
+		owner:     "gh-integration-tests",
+		bot:       newLoadedBotUser("gh-integration-tests-app[bot]", "", 0),
+		ua:        api.UAHeaderValue,
+		baseURL:   u,
+		minter:    &jwtRS256{internal: testkeys.RSA2048()},
+		onTokenRefresh: []tokenRefreshCallback{
+			func(_ context.Context, token InstallationToken) {
+				refreshed = append(refreshed, token)
+			},
+		},
+		next: api.RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+			resp := httptest.NewRecorder()
+			if strings.HasSuffix(r.URL.Path, "/access_tokens") {
+				resp.WriteHeader(http.StatusCreated)
+				_, _ = resp.WriteString(`{"token":"ghs_token","expires_at":"2099-01-01T00:00:00Z",` +
+					`"permissions":{"issues":"write"},"repositories":[{"name":"foo"}]}`)
+				return resp.Result(), nil
+			}
+			resp.WriteHeader(http.StatusOK)
+			return resp.Result(), nil
+		}),
+	}
+
+	// Cached tokens must not trigger the callback.
+	for i := 0; i < 2; i++ {
+		r, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, u.JoinPath("repos").String(), nil)
+		resp, err := transport.RoundTrip(r)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		resp.Body.Close()
+	}
+
+	if len(refreshed) != 1 {
+		t.Fatalf("expected callback to be invoked once, got %d", len(refreshed))
+	}
+
+	token := refreshed[0]
+	if token.Token != "ghs_token" {
+		t.Errorf("expected token=ghs_token, got=%s", token.Token)
+	}
+
+	if token.InstallationID != 99 || token.AppID != 99 || token.AppName != "gh-integration-tests-app" {
+		t.Errorf("expected app and installation to be populated, got %#v", token)
+	}
+
+	if token.Owner != "gh-integration-tests" || token.BotUsername != "gh-integration-tests-app[bot]" {
+		t.Errorf("expected owner and bot metadata to be populated, got %#v", token)
+	}
+
+	if token.BotCommitterName != "gh-integration-tests-app[bot]" {
+		t.Errorf("expected bot committer name to be populated, got %q", token.BotCommitterName)
+	}
+
+	if token.Exp.Year() != 2099 {
+		t.Errorf("expected expiry to be populated, got %s", token.Exp)
+	}
+
+	if token.Server != u.String() {
+		t.Errorf("expected server=%s, got=%s", u, token.Server)
+	}
+
+	if token.Permissions["issues"] != "write" || !slices.Equal(token.Repositories, []string{"foo"}) {
+		t.Errorf("expected permissions and repositories to be populated, got %#v", token)
+	}
+}
+
+func TestTransport_ExpiryMargin(t *testing.T) {
+	tt := []struct {
+		name   string
+		margin time.Duration
+		mint   bool
+	}{
+		{
+			name: "default",
+		},
+		{
+			name:   "less-than-remaining-validity",
+			margin: 2 * time.Minute,
+		},
+		{
+			name:   "more-than-remaining-validity",
+			margin: 5 * time.Minute,
+			mint:   true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var minted bool
+			u, _ := url.Parse("https://api.go-githubapp.test/")
+			transport := &Transport{
+				appID:        99,
+				installID:    99,
+				ua:           api.UAHeaderValue,
+				baseURL:      u,
+				expiryMargin: tc.margin,
+				minter:       &jwtRS256{internal: testkeys.RSA2048()},
+				next: api.RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+					resp := httptest.NewRecorder()
+					if strings.HasSuffix(r.URL.Path, "/access_tokens") {
+						minted = true
+						resp.WriteHeader(http.StatusCreated)
+						_, _ = resp.WriteString(`{"token":"ghs_new","expires_at":"2099-01-01T00:00:00Z"}`)
+						return resp.Result(), nil
+					}
+					resp.WriteHeader(http.StatusOK)
+					return resp.Result(), nil
+				}),
+			}
+			transport.token.Store(InstallationToken{Token: "ghs_cached", Exp: time.Now().Add(3 * time.Minute)})
+
+			v, err := transport.installationAuthzHeaderValue(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if minted != tc.mint {
+				t.Errorf("expected minted=%t, got=%t", tc.mint, minted)
+			}
+
+			expect := "Bearer ghs_cached"
+			if tc.mint {
+				expect = "Bearer ghs_new"
+			}
+			if v != expect {
+				t.Errorf("expected authorization=%q, got=%q", expect, v)
+			}
+		})
+	}
+}
+
+func TestTransport_TokenRefreshCallback(t *testing.T) {
+	type ctxKey struct{}
+	var calls atomic.Int64
+	var refreshed []InstallationToken
+	var buf bytes.Buffer
+	u, _ := url.Parse("https://api.go-githubapp.test/")
+	transport := &Transport{
+		appID:     99,
+		installID: 99,
+		ua:        api.UAHeaderValue,
+		baseURL:   u,
+		minter:    &jwtRS256{internal: testkeys.RSA2048()},
+		logger:    slog.New(slog.NewTextHandler(&buf, nil)),
+		onTokenRefresh: []tokenRefreshCallback{
+			func(context.Context, InstallationToken) {
+				panic("callback panicked")
+			},
+			func(ctx context.Context, token InstallationToken) {
+				if ctx.Value(ctxKey{}) == nil {
+					t.Errorf("expected context of the request to be passed to callback")
+				}
+				refreshed = append(refreshed, token)
+			},
+		},
+		next: api.RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+			resp := httptest.NewRecorder()
+			if strings.HasSuffix(r.URL.Path, "/access_tokens") {
+				n := calls.Add(1)
+				resp.WriteHeader(http.StatusCreated)
+				// First token expires immediately, thus must be renewed.
+				exp := time.Now().Add(30 * time.Second)
+				if n > 1 {
+					exp = time.Now().Add(time.Hour)
+				}
+				_, _ = fmt.Fprintf(resp, `{"token":"ghs_token_%d","expires_at":"%s"}`,
+					n, exp.UTC().Format(time.RFC3339))
+				return resp.Result(), nil
+			}
+			resp.WriteHeader(http.StatusOK)
+			return resp.Result(), nil
+		}),
+	}
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, struct{}{})
+	for i := 0; i < 3; i++ {
+		r, _ := http.NewRequestWithContext(ctx, http.MethodGet, u.JoinPath("repos").String(), nil)
+		resp, err := transport.RoundTrip(r)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		resp.Body.Close()
+	}
+
+	if len(refreshed) != 2 {
+		t.Fatalf("expected callback to be invoked twice, got %d", len(refreshed))
+	}
+
+	for i, token := range refreshed {
+		if expect := fmt.Sprintf("ghs_token_%d", i+1); token.Token != expect {
+			t.Errorf("expected token=%s, got=%s", expect, token.Token)
+		}
+	}
+
+	if !strings.Contains(buf.String(), "callback panicked") {
+		t.Errorf("expected panic to be logged, got %q", buf.String())
+	}
+}
+
+func TestNewTransport_RequestEditor(t *testing.T) {
+	const correlationHeader = "X-Correlation-ID"
+	server := githubapptest.NewServer()
+	t.Cleanup(server.Close)
+
+	rec := &requestRecorder{}
+	_, err := NewTransport(context.Background(), githubapptest.AppID, testkeys.RSA2048(),
+		WithEndpoint(server.URL),
+		WithInstallationID(githubapptest.InstallationID),
+		WithEagerBotMetadata(),
+		WithRoundTripper(rec),
+		WithRequestEditor(func(r *http.Request) error {
+			r.Header.Set(correlationHeader, "go-githubapp")
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	// App, installation, token and bot user requests.
+	paths := rec.paths()
+	if len(paths) < 4 {
+		t.Errorf("expected at-least 4 requests, got %v", paths)
+	}
+
+	for i, v := range rec.headers(correlationHeader) {
+		if v != "go-githubapp" {
+			t.Errorf("%s: expected %s header, got=%q", paths[i], correlationHeader, v)
+		}
+	}
+}
+
+func TestTransport_RoundTrip_RequestEditor(t *testing.T) {
+	tt := []struct {
+		name    string
+		editors []func(*http.Request) error
+		accept  string
+		err     bool
+	}{
+		{
+			name: "headers-already-set",
+			editors: []func(*http.Request) error{
+				func(r *http.Request) error {
+					if r.Header.Get(api.AuthzHeader) != "Bearer ghs_token" {
+						return fmt.Errorf("authorization header is not set: %q", r.Header.Get(api.AuthzHeader))
+					}
+					if r.Header.Get(api.AcceptHeader) != "application/vnd.github.raw" {
+						return fmt.Errorf("accept header is not set: %q", r.Header.Get(api.AcceptHeader))
+					}
+					return nil
+				},
+			},
+			accept: "application/vnd.github.raw",
+		},
+		{
+			name: "override-in-order",
+			editors: []func(*http.Request) error{
+				func(r *http.Request) error {
+					r.Header.Set(api.AcceptHeader, "application/vnd.github.diff")
+					return nil
+				},
+				func(r *http.Request) error {
+					if r.Header.Get(api.AcceptHeader) != "application/vnd.github.diff" {
+						return errors.New("editors are not invoked in order")
+					}
+					r.Header.Set(api.AcceptHeader, "application/vnd.github.patch")
+					return nil
+				},
+			},
+			accept: "application/vnd.github.patch",
+		},
+		{
+			name: "error",
+			editors: []func(*http.Request) error{
+				func(*http.Request) error {
+					return errors.New("editor failed")
+				},
+			},
+			err: true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls int
+			var accept string
+			u, _ := url.Parse("https://api.go-githubapp.test/")
+			transport := &Transport{
+				appID:     99,
+				installID: 99,
+				ua:        api.UAHeaderValue,
+				accept:    "application/vnd.github.raw",
+				baseURL:   u,
+				editors:   tc.editors,
+				minter:    &jwtRS256{internal: testkeys.RSA2048()},
+				next: api.RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+					resp := httptest.NewRecorder()
+					if strings.HasSuffix(r.URL.Path, "/access_tokens") {
+						resp.WriteHeader(http.StatusCreated)
+						_, _ = resp.WriteString(`{"token":"ghs_token","expires_at":"2099-01-01T00:00:00Z"}`)
+						return resp.Result(), nil
+					}
+					calls++
+					accept = r.Header.Get(api.AcceptHeader)
+					resp.WriteHeader(http.StatusOK)
+					return resp.Result(), nil
+				}),
+			}
+			transport.token.Store(InstallationToken{Token: "ghs_token", Exp: time.Now().Add(time.Hour)})
+
+			r, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, u.JoinPath("repos").String(), nil)
+			resp, err := transport.RoundTrip(r)
+			if tc.err {
+				if err == nil {
+					t.Errorf("expected an error, got nil")
+				}
+				if calls != 0 {
+					t.Errorf("request must not be sent when editor fails")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error, got %s", err)
+			}
+			resp.Body.Close()
+
+			if accept != tc.accept {
+				t.Errorf("expected accept=%q, got=%q", tc.accept, accept)
+			}
+
+			if r.Header.Get(api.AcceptHeader) != "" {
+				t.Errorf("original request must not be modified")
+			}
+		})
+	}
+}
+
+func TestTransport_Unwrap(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		transport, err := newTransport(99)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		if transport.Unwrap() != http.DefaultTransport {
+			t.Errorf("expected http.DefaultTransport, got %T", transport.Unwrap())
+		}
+	})
+
+	t.Run("custom", func(t *testing.T) {
+		next := &http.Transport{}
+		transport, err := newTransport(99, WithRoundTripper(next))
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		if rt, ok := transport.Unwrap().(*http.Transport); !ok || rt != next {
+			t.Errorf("expected configured round tripper, got %T", transport.Unwrap())
+		}
+	})
+}
+
+func TestNewTransport_InstallationAccountType(t *testing.T) {
+	m := apitestdata.Get(t)
+
+	// Installation on a user account.
+	user := map[string]any{}
+	if err := json.Unmarshal(m["get-installation-by-user"], &user); err != nil {
+		t.Fatalf("failed to unmarshal test data: %s", err)
+	}
+	user["target_type"] = "User"
+	userData, _ := json.Marshal(user)
+
+	tt := []struct {
+		name   string
+		users  []byte
+		orgs   []byte
+		expect string
+		paths  []string
+		ok     bool
+	}{
+		{
+			name:   "user",
+			users:  userData,
+			expect: "User",
+			paths:  []string{"users"},
+			ok:     true,
+		},
+		{
+			name:   "organization-via-users",
+			users:  m["get-installation-by-user"],
+			expect: "Organization",
+			paths:  []string{"users"},
+			ok:     true,
+		},
+		{
+			name:   "organization-via-orgs",
+			orgs:   m["get-installation-by-user"],
+			expect: "Organization",
+			paths:  []string{"users", "orgs"},
+			ok:     true,
+		},
+		{
+			name:  "not-found",
+			paths: []string{"users", "orgs"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var paths []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/app":
+					_, _ = w.Write(m["get-app"])
+				case fmt.Sprintf("/users/%s/installation", apitestdata.InstallationOwner):
+					paths = append(paths, "users")
+					if tc.users == nil {
+						w.WriteHeader(http.StatusNotFound)
+						_, _ = w.Write(m["error-not-found"])
+						return
+					}
+					_, _ = w.Write(tc.users)
+				case fmt.Sprintf("/orgs/%s/installation", apitestdata.InstallationOwner):
+					paths = append(paths, "orgs")
+					if tc.orgs == nil {
+						w.WriteHeader(http.StatusNotFound)
+						_, _ = w.Write(m["error-not-found"])
+						return
+					}
+					_, _ = w.Write(tc.orgs)
+				case fmt.Sprintf("/app/installations/%d/access_tokens", apitestdata.InstallationID):
+					w.WriteHeader(http.StatusCreated)
+					_, _ = w.Write([]byte(`{"token":"ghs_token","expires_at":"2099-01-01T00:00:00Z"}`))
+				default:
+					t.Errorf("Unknown/Invalid Request => %s", r.URL)
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			}))
+			t.Cleanup(server.Close)
+
+			transport, err := NewTransport(context.Background(), apitestdata.AppID, testkeys.RSA2048(),
+				WithEndpoint(server.URL),
+				WithOwner(apitestdata.InstallationOwner),
+				WithoutBotMetadata(),
+			)
+
+			if !slices.Equal(paths, tc.paths) {
+				t.Errorf("expected lookups=%v, got=%v", tc.paths, paths)
+			}
+
+			if !tc.ok {
+				var apiErr *APIError
+				if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+					t.Errorf("expected APIError with status 404, got %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error, got %s", err)
+			}
+
+			if v := transport.InstallationAccountType(); v != tc.expect {
+				t.Errorf("expected account type=%q, got=%q", tc.expect, v)
+			}
+
+			token, err := transport.InstallationToken(context.Background())
+			if err != nil {
+				t.Fatalf("expected no error, got %s", err)
+			}
+
+			if token.TargetType != tc.expect {
+				t.Errorf("expected token.TargetType=%q, got=%q", tc.expect, token.TargetType)
+			}
+		})
+	}
+}
+
+func TestTransport_RoundTrip_PreserveAuthorizationHeader(t *testing.T) {
+	const userToken = "Bearer ghu_user_token"
+	tt := []struct {
+		name     string
+		preserve bool
+		existing bool
+		header   string
+		jwt      bool
+		expect   string
+	}{
+		{
+			name:   "default-overwrites",
+			header: userToken,
+			expect: "Bearer ghs_token",
+		},
+		{
+			name:     "context",
+			existing: true,
+			header:   userToken,
+			expect:   userToken,
+		},
+		{
+			name:     "context-no-header",
+			existing: true,
+			expect:   "Bearer ghs_token",
+		},
+		{
+			name:     "context-token-renewal-uses-jwt",
+			existing: true,
+			header:   userToken,
+			jwt:      true,
+		},
+		{
+			name:     "preserve",
+			preserve: true,
+			header:   userToken,
+			expect:   userToken,
+		},
+		{
+			name:     "preserve-no-header",
+			preserve: true,
+			expect:   "Bearer ghs_token",
+		},
+		{
+			name:     "preserve-token-renewal-uses-jwt",
+			preserve: true,
+			header:   userToken,
+			jwt:      true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var got string
+			u, _ := url.Parse("https://api.go-githubapp.test/")
+			transport := &Transport{
+				appID:         99,
+				installID:     99,
+				baseURL:       u,
+				preserveAuthz: tc.preserve,
+				minter:        &jwtRS256{internal: testkeys.RSA2048()},
+				next: api.RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+					resp := httptest.NewRecorder()
+					if strings.HasSuffix(r.URL.Path, "/access_tokens") {
+						resp.WriteHeader(http.StatusCreated)
+						_, _ = resp.WriteString(`{"token":"ghs_token","expires_at":"2099-01-01T00:00:00Z"}`)
+						return resp.Result(), nil
+					}
+					got = r.Header.Get(api.AuthzHeader)
+					resp.WriteHeader(http.StatusOK)
+					return resp.Result(), nil
+				}),
+			}
+
+			ctx := context.Background()
+			if tc.existing {
+				ctx = WithExistingAuthorization(ctx)
+			}
+
+			if tc.jwt {
+				ctx = ctxWithJWTKey(ctx)
+			}
+
+			r, _ := http.NewRequestWithContext(ctx, http.MethodGet, u.JoinPath("user").String(), nil)
+			if tc.header != "" {
+				r.Header.Set(api.AuthzHeader, tc.header)
+			}
+
+			resp, err := transport.RoundTrip(r)
+			if err != nil {
+				t.Fatalf("expected no error, got %s", err)
+			}
+			resp.Body.Close()
+
+			expect := tc.expect
+			if tc.jwt {
+				jwt, _ := transport.JWT(ctx)
+				expect = api.AuthzHeaderValue(jwt.Token)
+			}
+
+			if got != expect {
+				t.Errorf("expected Authorization=%q, got=%q", expect, got)
+			}
+
+			if v := r.Header.Get(api.AuthzHeader); v != tc.header {
+				t.Errorf("original request must not be modified, got %q", v)
+			}
+		})
+	}
+}
+
+func TestNewTransport_StaticInstallationToken(t *testing.T) {
+	token := InstallationToken{
+		Token:          "ghs_static",
+		AppID:          99,
+		AppName:        "gh-integration-tests",
+		InstallationID: 42,
+		Server:         "https://api.go-githubapp.test/",
+		Owner:          "gh-integration-tests",
+		OwnerID:        7,
+		TargetType:     "Organization",
+		Repositories:   []string{"foo"},
+		Exp:            time.Now().Add(time.Hour),
+	}
+
+	var revoked atomic.Bool
+	next := api.RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+		resp := httptest.NewRecorder()
+		switch {
+		case r.Method == http.MethodDelete && r.URL.Path == "/installation/token":
+			if v := r.Header.Get(api.AuthzHeader); v != "Bearer ghs_static" {
+				t.Errorf("expected revoke request to use static token, got %q", v)
+			}
+			revoked.Store(true)
+			resp.WriteHeader(http.StatusNoContent)
+		case strings.HasSuffix(r.URL.Path, "/access_tokens") || r.URL.Path == "/app":
+			t.Errorf("unexpected request to %s", r.URL.Path)
+			resp.WriteHeader(http.StatusInternalServerError)
+		default:
+			_, _ = resp.WriteString(r.Header.Get(api.AuthzHeader))
+		}
+		return resp.Result(), nil
+	})
+
+	// App id and signer are not required.
+	transport, err := NewTransport(context.Background(), 0, nil,
+		WithStaticInstallationToken(token),
+		WithRoundTripper(next),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	if transport.AppID() != 99 || transport.InstallationID() != 42 || transport.AppName() != "gh-integration-tests" {
+		t.Errorf("expected metadata to be populated from token, got app=%d installation=%d name=%s",
+			transport.AppID(), transport.InstallationID(), transport.AppName())
+	}
+
+	expectAccount := InstallationAccount{Login: "gh-integration-tests", ID: 7, Type: "Organization"}
+	if v := transport.InstallationAccount(); v != expectAccount {
+		t.Errorf("expected installation account=%#v, got=%#v", expectAccount, v)
+	}
+
+	authz := func(ctx context.Context) (string, error) {
+		r, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.go-githubapp.test/repos", nil)
+		resp, err := transport.RoundTrip(r)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+
+		data, err := io.ReadAll(resp.Body)
+		return string(data), err
+	}
+
+	for i := 0; i < 2; i++ {
+		v, err := authz(context.Background())
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		if v != "Bearer ghs_static" {
+			t.Errorf("expected static token to be used, got %q", v)
+		}
+	}
+
+	// Context overrides cannot be honored without JWT.
+	_, err = authz(ContextWithInstallationID(context.Background(), 43))
+	if err == nil {
+		t.Errorf("expected an error for installation id from context, got nil")
+	}
+
+	_, err = authz(WithRequestPermissions(context.Background(), map[string]string{"issues": "read"}))
+	if err == nil {
+		t.Errorf("expected an error for permissions from context, got nil")
+	}
+
+	_, err = transport.JWT(context.Background())
+	if err == nil {
+		t.Errorf("expected an error for JWT, got nil")
+	}
+
+	// Revoke works on the static token.
+	v, err := transport.InstallationToken(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	err = v.revoke(context.Background(), next, "")
+	if err != nil {
+		t.Errorf("expected no error, got %s", err)
+	}
+
+	if !revoked.Load() {
+		t.Errorf("expected token to be revoked")
+	}
+
+	// Once expired, requests fail with typed error.
+	transport.static.Exp = time.Now().Add(-time.Second)
+	transport.token.Store(*transport.static)
+
+	_, err = authz(context.Background())
+	if !errors.Is(err, ErrInstallationTokenExpired) {
+		t.Errorf("expected error=%s, got=%s", ErrInstallationTokenExpired, err)
+	}
+
+	_, err = transport.InstallationToken(context.Background())
+	if !errors.Is(err, ErrInstallationTokenExpired) {
+		t.Errorf("expected error=%s, got=%s", ErrInstallationTokenExpired, err)
+	}
+}
+
+func TestNewTransport_StaticInstallationTokenConflicts(t *testing.T) {
+	token := InstallationToken{Token: "ghs_static", Exp: time.Now().Add(time.Hour)}
+	tt := []struct {
+		name string
+		opts []Option
+	}{
+		{
+			name: "installation-id",
+			opts: []Option{WithInstallationID(42)},
+		},
+		{
+			name: "owner",
+			opts: []Option{WithOwner("gh-integration-tests")},
+		},
+		{
+			name: "repositories",
+			opts: []Option{WithRepositories("gh-integration-tests/foo")},
+		},
+		{
+			name: "permissions",
+			opts: []Option{WithPermissions("issues:read")},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := append([]Option{WithStaticInstallationToken(token)}, tc.opts...)
+			_, err := NewTransport(context.Background(), 0, nil, opts...)
+			if err == nil {
+				t.Errorf("expected an error, got nil")
+			}
+		})
+	}
+}
+
+func TestNewTransport_JWTExpiry(t *testing.T) {
+	server := githubapptest.NewServer()
+	t.Cleanup(server.Close)
+
+	transport, err := NewTransport(context.Background(), githubapptest.AppID, testkeys.RSA2048(),
+		WithEndpoint(server.URL),
+		WithJWTExpiry(8*time.Minute),
+		WithJWTClockSkew(time.Minute),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	bearer, err := transport.JWT(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	if v := bearer.Exp.Sub(bearer.IssuedAt); v != 9*time.Minute {
+		t.Errorf("expected exp-iat=%s, got=%s", 9*time.Minute, v)
+	}
+}
+
+func TestNewTransport_RepositoriesWithInstallationID(t *testing.T) {
+	server := githubapptest.NewServer(githubapptest.WithRepositories("foo", "bar"))
+	t.Cleanup(server.Close)
+
+	t.Run("bare-names", func(t *testing.T) {
+		transport, err := NewTransport(context.Background(), githubapptest.AppID, testkeys.RSA2048(),
+			WithEndpoint(server.URL),
+			WithInstallationID(githubapptest.InstallationID),
+			WithRepositories("foo", "bar", "foo"),
+			WithoutBotMetadata(),
+		)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		// Owner is populated from the installation.
+		if transport.owner != githubapptest.Owner {
+			t.Errorf("expected owner=%s, got=%s", githubapptest.Owner, transport.owner)
+		}
+
+		token, err := transport.InstallationToken(context.Background())
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		if !slices.Equal(token.Repositories, []string{"bar", "foo"}) {
+			t.Errorf("expected token to be scoped to [bar foo], got %v", token.Repositories)
+		}
+	})
+
+	t.Run("owner-matches", func(t *testing.T) {
+		_, err := NewTransport(context.Background(), githubapptest.AppID, testkeys.RSA2048(),
+			WithEndpoint(server.URL),
+			WithInstallationID(githubapptest.InstallationID),
+			WithRepositories("GH-Integration-Tests/foo", "bar"),
+			WithoutBotMetadata(),
+		)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+	})
+
+	t.Run("owner-case-preserved", func(t *testing.T) {
+		transport, err := NewTransport(context.Background(), githubapptest.AppID, testkeys.RSA2048(),
+			WithEndpoint(server.URL),
+			WithInstallationID(githubapptest.InstallationID),
+			WithOwner("GH-Integration-Tests"),
+			WithRepositories("foo", "bar"),
+			WithoutBotMetadata(),
+		)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		if transport.owner != "GH-Integration-Tests" {
+			t.Errorf("expected owner=GH-Integration-Tests, got=%s", transport.owner)
+		}
+	})
+
+	t.Run("owner-mismatch", func(t *testing.T) {
+		_, err := NewTransport(context.Background(), githubapptest.AppID, testkeys.RSA2048(),
+			WithEndpoint(server.URL),
+			WithInstallationID(githubapptest.InstallationID),
+			WithRepositories("another-owner/foo", "bar"),
+			WithoutBotMetadata(),
+		)
+		if err == nil {
+			t.Errorf("expected an error, got nil")
+		}
+	})
+}
+
+func TestNewTransport_ValidateOnly(t *testing.T) {
+	server := githubapptest.NewServer()
+	t.Cleanup(server.Close)
+
+	tokenPath := fmt.Sprintf("/app/installations/%d/access_tokens", githubapptest.InstallationID)
+	botPath := fmt.Sprintf("/users/%s[bot]", githubapptest.AppSlug)
+
+	t.Run("valid", func(t *testing.T) {
+		rec := &requestRecorder{}
+		transport, err := NewTransport(context.Background(), githubapptest.AppID, testkeys.RSA2048(),
+			WithEndpoint(server.URL),
+			WithInstallationID(githubapptest.InstallationID),
+			WithValidateOnly(),
+			WithRoundTripper(rec),
+		)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		if v := rec.count(tokenPath); v != 0 {
+			t.Errorf("expected no installation tokens to be minted, got %d", v)
+		}
+
+		if v := rec.count(botPath); v != 0 {
+			t.Errorf("expected bot metadata not to be fetched, got %d requests", v)
+		}
+
+		// Token is minted lazily on first use. Server only lists repositories
+		// for requests authenticated with an installation token.
+		r, _ := http.NewRequestWithContext(context.Background(), http.MethodGet,
+			server.URL+"/installation/repositories", nil)
+		resp, err := transport.RoundTrip(r)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("expected installation token to be used, got %s", resp.Status)
+		}
+
+		if v := rec.count(tokenPath); v != 1 {
+			t.Errorf("expected 1 installation token to be minted, got %d", v)
+		}
+	})
+
+	t.Run("missing-permissions", func(t *testing.T) {
+		rec := &requestRecorder{}
+		_, err := NewTransport(context.Background(), githubapptest.AppID, testkeys.RSA2048(),
+			WithEndpoint(server.URL),
+			WithInstallationID(githubapptest.InstallationID),
+			WithPermissions("administration:admin"),
+			WithValidateOnly(),
+			WithRoundTripper(rec),
+		)
+		if err == nil {
+			t.Errorf("expected an error, got nil")
+		}
+
+		if v := rec.count(tokenPath); v != 0 {
+			t.Errorf("expected no installation tokens to be minted, got %d", v)
+		}
+	})
+}
+
+func TestTransport_Refresh(t *testing.T) {
+	var mints atomic.Int64
+	var revoked atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/access_tokens"):
+			n := mints.Add(1)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = fmt.Fprintf(w, `{"token":"ghs_%d","expires_at":"2099-01-01T00:00:00Z"}`, n)
+		case r.URL.Path == "/repos":
+			// Tokens minted before revocation are rejected.
+			if revoked.Load() && r.Header.Get(api.AuthzHeader) == "Bearer ghs_1" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	u, _ := url.Parse(server.URL)
+	transport := &Transport{
+		appID:     99,
+		installID: 99,
+		baseURL:   u,
+		minter:    &jwtRS256{internal: testkeys.RSA2048()},
+		next:      http.DefaultTransport,
+	}
+
+	// POST requests without GetBody are not retried with a new token on 401.
+	status := func() int {
+		r, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, server.URL+"/repos", nil)
+		resp, err := transport.RoundTrip(r)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if v := status(); v != http.StatusOK {
+		t.Fatalf("expected status=200, got=%d", v)
+	}
+
+	// Simulate a cached JWT, which is still valid, but was revoked by rotating keys.
+	transport.jwt.Store(JWT{Token: "stale", Exp: time.Now().Add(time.Hour)})
+
+	// Revoke the token, requests must fail until refreshed.
+	revoked.Store(true)
+	if v := status(); v != http.StatusUnauthorized {
+		t.Fatalf("expected status=401, got=%d", v)
+	}
+
+	err := transport.Refresh(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	if v := mints.Load(); v != 2 {
+		t.Errorf("expected 2 installation tokens to be minted, got %d", v)
+	}
+
+	if v, _ := transport.JWT(context.Background()); v.Token == "stale" {
+		t.Errorf("expected JWT to be renewed")
+	}
+
+	for i := 0; i < 2; i++ {
+		if v := status(); v != http.StatusOK {
+			t.Errorf("expected status=200 after refresh, got=%d", v)
+		}
+	}
+
+	if v := mints.Load(); v != 2 {
+		t.Errorf("expected refreshed token to be re-used, got %d mints", v)
+	}
+}
+
+func TestTransport_RevokeToken(t *testing.T) {
+	var mints atomic.Int64
+	var revoked []string
+	u, _ := url.Parse("https://api.go-githubapp.test/")
+	cache := NewMemoryCache()
+	transport := &Transport{
+		appID:      99,
+		installID:  99,
+		ua:         api.UAHeaderValue,
+		baseURL:    u,
+		cache:      cache,
+		apiVersion: "2026-03-10",
+		minter:     &jwtRS256{internal: testkeys.RSA2048()},
+		next: api.RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+			resp := httptest.NewRecorder()
+			switch {
+			case strings.HasSuffix(r.URL.Path, "/access_tokens"):
+				n := mints.Add(1)
+				resp.WriteHeader(http.StatusCreated)
+				_, _ = fmt.Fprintf(resp, `{"token":"ghs_%d","expires_at":"2099-01-01T00:00:00Z"}`, n)
+			case r.Method == http.MethodDelete && r.URL.Path == "/installation/token":
+				if v := r.Header.Get(api.VersionHeader); v != "2026-03-10" {
+					t.Errorf("expected %s=2026-03-10, got=%s", api.VersionHeader, v)
+				}
+				revoked = append(revoked, r.Header.Get(api.AuthzHeader))
+				resp.WriteHeader(http.StatusNoContent)
+			default:
+				_, _ = resp.WriteString(r.Header.Get(api.AuthzHeader))
+			}
+			return resp.Result(), nil
+		}),
+	}
+
+	authz := func() string {
+		r, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, u.JoinPath("repos").String(), nil)
+		resp, err := transport.RoundTrip(r)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return string(data)
+	}
+
+	// No token is cached.
+	err := transport.RevokeToken(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	if len(revoked) != 0 {
+		t.Fatalf("expected no token to be revoked, got %v", revoked)
+	}
+
+	if v := authz(); v != "Bearer ghs_1" {
+		t.Fatalf("expected authorization=Bearer ghs_1, got=%s", v)
+	}
+
+	err = transport.RevokeToken(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	if !slices.Equal(revoked, []string{"Bearer ghs_1"}) {
+		t.Errorf("expected cached token to be revoked, got %v", revoked)
+	}
+
+	if _, ok := cache.items[transport.tokenCacheKey()]; ok {
+		t.Errorf("expected revoked token to be deleted from the cache")
+	}
+
+	if v := authz(); v != "Bearer ghs_2" {
+		t.Errorf("expected authorization=Bearer ghs_2, got=%s", v)
+	}
+
+	if v := mints.Load(); v != 2 {
+		t.Errorf("expected 2 installation tokens to be minted, got %d", v)
+	}
+}
+
+func TestTransport_BotCommitterName(t *testing.T) {
+	transport := &Transport{}
+	if v := transport.BotCommitterName(); v != "" {
+		t.Errorf("expected empty committer name without app name, got %q", v)
+	}
+
+	transport.appSlug = "gh-integration-tests-app"
+	if v := transport.BotCommitterName(); v != "gh-integration-tests-app[bot]" {
+		t.Errorf("expected committer name=gh-integration-tests-app[bot], got=%q", v)
+	}
+}
+
+func TestNewTransport_WithHTTPClient(t *testing.T) {
+	server := githubapptest.NewServer()
+	t.Cleanup(server.Close)
+
+	internal := &requestRecorder{}
+	client := &http.Client{
+		Timeout:   time.Minute,
+		Transport: internal,
+	}
+
+	external := &requestRecorder{}
+	transport, err := NewTransport(context.Background(), githubapptest.AppID, testkeys.RSA2048(),
+		WithEndpoint(server.URL),
+		WithInstallationID(githubapptest.InstallationID),
+		WithoutBotMetadata(),
+		WithHTTPClient(client),
+		WithRoundTripper(external),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	expect := []string{
+		"/app",
+		fmt.Sprintf("/app/installations/%d", githubapptest.InstallationID),
+		fmt.Sprintf("/app/installations/%d/access_tokens", githubapptest.InstallationID),
+	}
+	if v := internal.paths(); !slices.Equal(v, expect) {
+		t.Errorf("expected internal requests=%v, got=%v", expect, v)
+	}
+
+	if v := external.paths(); len(v) != 0 {
+		t.Errorf("expected no requests via round tripper, got %v", v)
+	}
+
+	// Requests via the transport use the round tripper and are authenticated.
+	r, _ := http.NewRequestWithContext(context.Background(), http.MethodGet,
+		server.URL+"/installation/repositories", nil)
+	resp, err := transport.RoundTrip(r)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected request to be authenticated, got %s", resp.Status)
+	}
+
+	if v := external.paths(); !slices.Equal(v, []string{"/installation/repositories"}) {
+		t.Errorf("expected requests via round tripper=[/installation/repositories], got=%v", v)
+	}
+
+	if v := internal.paths(); len(v) != len(expect) {
+		t.Errorf("expected no additional internal requests, got %v", v)
+	}
+}
+
+func TestNewTransport_RepositoryIDs(t *testing.T) {
+	m := apitestdata.Get(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app":
+			_, _ = w.Write(m["get-app"])
+		case fmt.Sprintf("/app/installations/%d", apitestdata.InstallationID):
+			_, _ = w.Write(m["get-installation-by-id"])
+		case fmt.Sprintf("/app/installations/%d/access_tokens", apitestdata.InstallationID):
+			req := map[string]any{}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Errorf("failed to decode token request: %s", err)
+			}
+
+			if _, ok := req["repositories"]; ok {
+				t.Errorf("expected repositories to be omitted, got %v", req)
+			}
+
+			if v := fmt.Sprint(req["repository_ids"]); v != "[1 2]" {
+				t.Errorf("expected repository_ids=[1 2], got %s", v)
+			}
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"token":"ghs_token","expires_at":"2099-01-01T00:00:00Z"}`))
+		default:
+			t.Errorf("Unknown/Invalid Request => %s", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	t.Run("valid", func(t *testing.T) {
+		_, err := NewTransport(context.Background(), apitestdata.AppID, testkeys.RSA2048(),
+			WithEndpoint(server.URL),
+			WithInstallationID(apitestdata.InstallationID),
+			WithRepositoryIDs(2, 1),
+			WithoutBotMetadata(),
+		)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+	})
+
+	t.Run("invalid-options", func(t *testing.T) {
+		tt := []struct {
+			name string
+			opts []Option
+		}{
+			{
+				name: "no-installation",
+				opts: []Option{WithRepositoryIDs(1)},
+			},
+			{
+				name: "with-repositories",
+				opts: []Option{
+					WithInstallationID(apitestdata.InstallationID),
+					WithRepositories("foo"),
+					WithRepositoryIDs(1),
+				},
+			},
+		}
+		for _, tc := range tt {
+			t.Run(tc.name, func(t *testing.T) {
+				_, err := NewTransport(context.Background(), apitestdata.AppID, testkeys.RSA2048(),
+					append(tc.opts, WithEndpoint(server.URL))...)
+				if err == nil {
+					t.Errorf("expected an error, got nil")
+				}
+			})
+		}
+	})
+}
+
+func TestNewTransport_SingleFile(t *testing.T) {
+	m := apitestdata.Get(t)
+	installation := map[string]any{}
+	if err := json.Unmarshal(m["get-installation-by-id"], &installation); err != nil {
+		t.Fatalf("failed to decode installation: %s", err)
+	}
+
+	installation["single_file_paths"] = []string{".github/config.yml"}
+	installation["permissions"].(map[string]any)["single_file"] = "read"
+	data, err := json.Marshal(installation)
+	if err != nil {
+		t.Fatalf("failed to encode installation: %s", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app":
+			_, _ = w.Write(m["get-app"])
+		case fmt.Sprintf("/app/installations/%d", apitestdata.InstallationID):
+			_, _ = w.Write(data)
+		case fmt.Sprintf("/app/installations/%d/access_tokens", apitestdata.InstallationID):
+			req := api.InstallationTokenRequest{}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Errorf("failed to decode token request: %s", err)
+			}
+
+			if v := req.Permissions["single_file"]; v != "read" {
+				t.Errorf("expected single_file=read, got %q", v)
+			}
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"token":"ghs_token","expires_at":"2099-01-01T00:00:00Z"}`))
+		default:
+			t.Errorf("Unknown/Invalid Request => %s", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	tt := []struct {
+		name string
+		path string
+		ok   bool
+	}{
+		{
+			name: "configured",
+			path: ".github/config.yml",
+			ok:   true,
+		},
+		{
+			name: "not-configured",
+			path: ".github/other.yml",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewTransport(context.Background(), apitestdata.AppID, testkeys.RSA2048(),
+				WithEndpoint(server.URL),
+				WithInstallationID(apitestdata.InstallationID),
+				WithSingleFile(tc.path),
+				WithoutBotMetadata(),
+			)
+			if tc.ok {
+				if err != nil {
+					t.Fatalf("expected no error, got %s", err)
+				}
+			} else if err == nil {
+				t.Errorf("expected an error, got nil")
+			}
+		})
+	}
+}
+
+func TestTransport_RoundTrip_RetryUnauthorized(t *testing.T) {
+	tt := []struct {
+		name     string
+		method   string
+		body     func() io.Reader
+		revoked  func(token string) bool
+		cancel   bool
+		status   int
+		requests int64
+		mints    int64
+	}{
+		{
+			name:     "revoked-token",
+			method:   http.MethodGet,
+			revoked:  func(token string) bool { return token == "Bearer ghs_1" },
+			status:   http.StatusOK,
+			requests: 2,
+			mints:    2,
+		},
+		{
+			name:     "revoked-token-replayable-body",
+			method:   http.MethodPost,
+			body:     func() io.Reader { return strings.NewReader("body") },
+			revoked:  func(token string) bool { return token == "Bearer ghs_1" },
+			status:   http.StatusOK,
+			requests: 2,
+			mints:    2,
+		},
+		{
+			name:     "always-unauthorized",
+			method:   http.MethodGet,
+			revoked:  func(string) bool { return true },
+			status:   http.StatusUnauthorized,
+			requests: 2,
+			mints:    2,
+		},
+		{
+			name:     "non-replayable-body",
+			method:   http.MethodPost,
+			body:     func() io.Reader { return io.NopCloser(strings.NewReader("body")) },
+			revoked:  func(token string) bool { return token == "Bearer ghs_1" },
+			status:   http.StatusUnauthorized,
+			requests: 1,
+			mints:    1,
+		},
+		{
+			name:     "non-idempotent-without-body",
+			method:   http.MethodPost,
+			revoked:  func(token string) bool { return token == "Bearer ghs_1" },
+			status:   http.StatusUnauthorized,
+			requests: 1,
+			mints:    1,
+		},
+		{
+			name:     "context-done",
+			method:   http.MethodGet,
+			revoked:  func(token string) bool { return token == "Bearer ghs_1" },
+			cancel:   true,
+			status:   http.StatusUnauthorized,
+			requests: 1,
+			mints:    1,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+
+			var mints, requests atomic.Int64
+			u, _ := url.Parse("https://api.go-githubapp.test/")
+			transport := &Transport{
+				appID:     99,
+				installID: 99,
+				ua:        api.UAHeaderValue,
+				baseURL:   u,
+				minter:    &jwtRS256{internal: testkeys.RSA2048()},
+				next: api.RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+					resp := httptest.NewRecorder()
+					if strings.HasSuffix(r.URL.Path, "/access_tokens") {
+						n := mints.Add(1)
+						resp.WriteHeader(http.StatusCreated)
+						_, _ = fmt.Fprintf(resp, `{"token":"ghs_%d","expires_at":"2099-01-01T00:00:00Z"}`, n)
+						return resp.Result(), nil
+					}
+
+					requests.Add(1)
+					if r.Body != nil {
+						data, _ := io.ReadAll(r.Body)
+						if tc.body != nil && string(data) != "body" {
+							t.Errorf("expected request body=body, got=%q", data)
+						}
+					}
+
+					if tc.cancel {
+						cancel()
+					}
+
+					if tc.revoked(r.Header.Get(api.AuthzHeader)) {
+						resp.WriteHeader(http.StatusUnauthorized)
+						_, _ = resp.WriteString(`{"message":"Bad credentials"}`)
+						return resp.Result(), nil
+					}
+					resp.WriteHeader(http.StatusOK)
+					return resp.Result(), nil
+				}),
+			}
+
+			var body io.Reader
+			if tc.body != nil {
+				body = tc.body()
+			}
+
+			r, _ := http.NewRequestWithContext(ctx, tc.method, u.JoinPath("repos").String(), body)
+			resp, err := transport.RoundTrip(r)
+			if err != nil {
+				t.Fatalf("expected no error, got %s", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tc.status {
+				t.Errorf("expected status=%d, got=%d", tc.status, resp.StatusCode)
+			}
+
+			if v := requests.Load(); v != tc.requests {
+				t.Errorf("expected requests=%d, got=%d", tc.requests, v)
+			}
+
+			if v := mints.Load(); v != tc.mints {
+				t.Errorf("expected mints=%d, got=%d", tc.mints, v)
+			}
+		})
+	}
+}
+
+func TestTransport_CloneWith(t *testing.T) {
+	server := githubapptest.NewServer()
+	t.Cleanup(server.Close)
+
+	rec := &requestRecorder{}
+	transport, err := NewTransport(context.Background(), githubapptest.AppID, testkeys.RSA2048(),
+		WithEndpoint(server.URL),
+		WithInstallationID(githubapptest.InstallationID),
+		WithPermissions("contents:read"),
+		WithoutBotMetadata(),
+		WithRoundTripper(rec),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	t.Run("permissions", func(t *testing.T) {
+		before := len(rec.paths())
+		clone, err := transport.CloneWith(WithPermissions("issues:read"))
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		if v := len(rec.paths()); v != before {
+			t.Errorf("expected no API calls for clone, got %d", v-before)
+		}
+
+		if v := clone.InstallationID(); v != githubapptest.InstallationID {
+			t.Errorf("expected installation id=%d, got=%d", githubapptest.InstallationID, v)
+		}
+
+		// Scopes of the original transport must not be modified.
+		if v := permissionsKey(transport.scopes); v != "contents:read" {
+			t.Errorf("expected original scopes to be unchanged, got %s", v)
+		}
+
+		token, err := clone.InstallationToken(context.Background())
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		if v := permissionsKey(token.Permissions); v != "issues:read" {
+			t.Errorf("expected clone to mint token with replaced scopes, got %s", v)
+		}
+	})
+
+	t.Run("without-permissions", func(t *testing.T) {
+		clone, err := transport.CloneWith(WithUserAgentComment("clone"))
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		if v := permissionsKey(clone.ScopedPermissions()); v != "contents:read" {
+			t.Errorf("expected clone to inherit scopes, got %s", v)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		tt := []struct {
+			name string
+			opts []Option
+		}{
+			{name: "permissions-not-available", opts: []Option{WithPermissions("issues:write")}},
+			{name: "endpoint", opts: []Option{WithEndpoint("https://api.go-githubapp.test/")}},
+			{name: "installation-id", opts: []Option{WithInstallationID(99)}},
+			{name: "repositories", opts: []Option{WithRepositories("foo")}},
+			{name: "single-file", opts: []Option{WithSingleFile(".github/config.yml")}},
+			{name: "static-token", opts: []Option{WithStaticInstallationToken(InstallationToken{Token: "ghs_static"})}},
+		}
+		for _, tc := range tt {
+			t.Run(tc.name, func(t *testing.T) {
+				before := len(rec.paths())
+				_, err := transport.CloneWith(tc.opts...)
+				if err == nil {
+					t.Errorf("expected an error, got nil")
+				}
+
+				if v := len(rec.paths()); v != before {
+					t.Errorf("expected no API calls for clone, got %d", v-before)
+				}
+			})
+		}
+	})
+}
+
+func TestTransport_Close(t *testing.T) {
+	var revoked []string
+	var mu sync.Mutex
+	u, _ := url.Parse("https://api.go-githubapp.test/")
+	transport := &Transport{
+		appID:     99,
+		installID: 99,
+		ua:        api.UAHeaderValue,
+		baseURL:   u,
+		minter:    &jwtRS256{internal: testkeys.RSA2048()},
+		next: api.RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+			resp := httptest.NewRecorder()
+			switch {
+			case strings.HasSuffix(r.URL.Path, "/access_tokens"):
+				resp.WriteHeader(http.StatusCreated)
+				_, _ = resp.WriteString(`{"token":"ghs_token","expires_at":"2099-01-01T00:00:00Z"}`)
+			case r.Method == http.MethodDelete && r.URL.Path == "/installation/token":
+				mu.Lock()
+				revoked = append(revoked, r.Header.Get(api.AuthzHeader))
+				mu.Unlock()
+				resp.WriteHeader(http.StatusNoContent)
+			default:
+				resp.WriteHeader(http.StatusOK)
+			}
+			return resp.Result(), nil
+		}),
+	}
+
+	do := func() error {
+		r, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, u.JoinPath("repos").String(), nil)
+		resp, err := transport.RoundTrip(r)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}
+
+	if err := do(); err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	// Concurrent and repeated Close must only revoke the token once.
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = transport.Close(context.Background())
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range append(errs, transport.Close(context.Background())) {
+		if err != nil {
+			t.Errorf("expected no error, got %s", err)
+		}
+	}
+
+	if !slices.Equal(revoked, []string{"Bearer ghs_token"}) {
+		t.Errorf("expected token to be revoked once, got %v", revoked)
+	}
+
+	if err := do(); !errors.Is(err, ErrTransportClosed) {
+		t.Errorf("expected request after close to fail with ErrTransportClosed, got %v", err)
+	}
+
+	if _, err := transport.InstallationToken(context.Background()); !errors.Is(err, ErrTransportClosed) {
+		t.Errorf("expected ErrTransportClosed, got %v", err)
+	}
+
+	if _, err := transport.JWT(context.Background()); !errors.Is(err, ErrTransportClosed) {
+		t.Errorf("expected ErrTransportClosed, got %v", err)
+	}
+}
+
+func TestTransport_AppEvents(t *testing.T) {
+	m := apitestdata.Get(t)
+	app := map[string]any{}
+	if err := json.Unmarshal(m["get-app"], &app); err != nil {
+		t.Fatalf("failed to decode app: %s", err)
+	}
+
+	events := []string{"issues", "pull_request"}
+	app["events"] = events
+	data, err := json.Marshal(app)
+	if err != nil {
+		t.Fatalf("failed to encode app: %s", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app":
+			_, _ = w.Write(data)
+		default:
+			t.Errorf("Unknown/Invalid Request => %s", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	transport, err := NewTransport(context.Background(), apitestdata.AppID, testkeys.RSA2048(),
+		WithEndpoint(server.URL),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	if v := transport.AppEvents(); !slices.Equal(v, events) {
+		t.Errorf("expected app events=%v, got=%v", events, v)
+	}
+
+	// Accessor must return a clone.
+	transport.AppEvents()[0] = "push"
+	if v := transport.AppEvents(); !slices.Equal(v, events) {
+		t.Errorf("expected app events to be immutable, got=%v", v)
+	}
+}
+
+// countingMinter counts JWTs minted by the wrapped minter.
+type countingMinter struct {
+	jwtMinter
+	count atomic.Int64
+}
+
+func (m *countingMinter) MintJWT(ctx context.Context, iss uint64, now time.Time) (JWT, error) {
+	m.count.Add(1)
+	time.Sleep(10 * time.Millisecond)
+	return m.jwtMinter.MintJWT(ctx, iss, now)
+}
+
+func TestTransport_ConcurrentMint(t *testing.T) {
+	var tokens atomic.Int64
+	minter := &countingMinter{jwtMinter: &jwtRS256{internal: testkeys.RSA2048()}}
+	u, _ := url.Parse("https://api.go-githubapp.test/")
+	transport := &Transport{
+		appID:     99,
+		installID: 99,
+		ua:        api.UAHeaderValue,
+		baseURL:   u,
+		minter:    minter,
+		next: api.RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+			resp := httptest.NewRecorder()
+			if strings.HasSuffix(r.URL.Path, "/access_tokens") {
+				tokens.Add(1)
+				time.Sleep(50 * time.Millisecond)
+				resp.WriteHeader(http.StatusCreated)
+				_, _ = resp.WriteString(`{"token":"ghs_token","expires_at":"2099-01-01T00:00:00Z"}`)
+				return resp.Result(), nil
+			}
+
+			if v := r.Header.Get(api.AuthzHeader); v != "Bearer ghs_token" {
+				t.Errorf("expected installation token, got %q", v)
+			}
+			resp.WriteHeader(http.StatusOK)
+			return resp.Result(), nil
+		}),
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, u.JoinPath("repos").String(), nil)
+			resp, err := transport.RoundTrip(r)
+			if err != nil {
+				t.Errorf("unexpected error: %s", err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	if v := tokens.Load(); v != 1 {
+		t.Errorf("expected installation token to be minted once, got %d", v)
+	}
+
+	if v := minter.count.Load(); v != 1 {
+		t.Errorf("expected JWT to be minted once, got %d", v)
+	}
+
+	// Concurrent JWT callers share the JWT.
+	transport.jwt = atomic.Value{}
+	wg = sync.WaitGroup{}
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := transport.JWT(context.Background()); err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if v := minter.count.Load(); v != 2 {
+		t.Errorf("expected JWT to be minted once more, got %d", v)
+	}
+}
+
+func TestTransport_TokenExpiry(t *testing.T) {
+	var mints atomic.Int64
+	u, _ := url.Parse("https://api.go-githubapp.test/")
+	transport := &Transport{
+		appID:     99,
+		installID: 99,
+		ua:        api.UAHeaderValue,
+		baseURL:   u,
+		minter:    &jwtRS256{internal: testkeys.RSA2048()},
+		next: api.RoundTripFunc(func(r *http.Request) (*http.Response, error) {
+			resp := httptest.NewRecorder()
+			if strings.HasSuffix(r.URL.Path, "/access_tokens") {
+				n := mints.Add(1)
+				resp.WriteHeader(http.StatusCreated)
+				_, _ = fmt.Fprintf(resp, `{"token":"ghs_%d","expires_at":"209%d-01-01T00:00:00Z"}`, n, n)
+				return resp.Result(), nil
+			}
+			resp.WriteHeader(http.StatusOK)
+			return resp.Result(), nil
+		}),
+	}
+
+	if _, ok := transport.TokenExpiry(); ok {
+		t.Errorf("expected no expiry before installation token is minted")
+	}
+
+	r, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, u.JoinPath("repos").String(), nil)
+	resp, err := transport.RoundTrip(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	resp.Body.Close()
+
+	exp, ok := transport.TokenExpiry()
+	if !ok || exp.Year() != 2091 {
+		t.Errorf("expected expiry in 2091, got %s, %t", exp, ok)
+	}
+
+	err = transport.Refresh(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	exp, ok = transport.TokenExpiry()
+	if !ok || exp.Year() != 2092 {
+		t.Errorf("expected expiry to be updated after refresh, got %s, %t", exp, ok)
+	}
+
+	transport.token.Store(InstallationToken{Token: "ghs_expired", Exp: time.Now().Add(-time.Minute)})
+	if _, ok := transport.TokenExpiry(); ok {
+		t.Errorf("expected no expiry for expired installation token")
+	}
+}
@@ -5,12 +5,16 @@ package githubapp
 
 import (
 	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec // only used when explicitly enabled.
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"hash"
 	"io"
 	"log/slog"
+	"mime"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 
@@ -27,7 +31,7 @@ const (
 	ErrWebHookMethod = Error("githubapp(webhook): method not supported")
 
 	// ErrWebHookContentType is returned by [VerifyWebHookRequest] when a request
-	// content type is not 'application/json'.
+	// content type is not supported.
 	ErrWebHookContentType = Error("githubapp(webhook): unsupported content type")
 
 	// ErrWebHookRequest is returned by [VerifyWebHookRequest] when request is invalid
@@ -38,6 +42,59 @@ const (
 	ErrWebhookSignature = Error("githubapp(webhook): HMAC-SHA256 signature is invalid")
 )
 
+// WebHookOption configures [VerifyWebHookRequest].
+type WebHookOption interface {
+	apply(*webHookConfig)
+}
+
+// webHookConfig is configuration for verifying webhooks.
+type webHookConfig struct {
+	form bool // accept application/x-www-form-urlencoded
+	sha1 bool // fallback to X-Hub-Signature (HMAC-SHA1)
+}
+
+var (
+	_ WebHookOption = (*webHookFuncOption)(nil)
+)
+
+// webHookFuncOption implements [WebHookOption].
+type webHookFuncOption struct {
+	f func(*webHookConfig)
+}
+
+func (opt *webHookFuncOption) apply(c *webHookConfig) {
+	opt.f(c)
+}
+
+// WithWebHookFormEncoding configures [VerifyWebHookRequest] to accept webhooks
+// with content type 'application/x-www-form-urlencoded'. Payload of such webhooks
+// is extracted from the 'payload' form field after verifying the signature,
+// thus [WebHook.Payload] is always JSON.
+func WithWebHookFormEncoding() WebHookOption {
+	return &webHookFuncOption{
+		f: func(c *webHookConfig) {
+			c.form = true
+		},
+	}
+}
+
+// WithWebHookAllowSHA1 configures [VerifyWebHookRequest] to verify HMAC-SHA1
+// signature from the 'X-Hub-Signature' header, when 'X-Hub-Signature-256' header
+// is absent. This is only useful for old GitHub Enterprise Server versions and
+// proxies which do not forward 'X-Hub-Signature-256' header. HMAC-SHA256 signature
+// is always preferred when both headers are present.
+//
+// SHA1 is considered weak, and GitHub only sends it for backwards compatibility.
+// Enabling this allows an attacker who can strip 'X-Hub-Signature-256' header to
+// downgrade verification to HMAC-SHA1. Only use it when absolutely necessary.
+func WithWebHookAllowSHA1() WebHookOption {
+	return &webHookFuncOption{
+		f: func(c *webHookConfig) {
+			c.sha1 = true
+		},
+	}
+}
+
 // WebHook is returned by [VerifyWebHookRequest] upon successful verification of
 // the webhook request. It contains all the webhook payloads with additional info
 // from headers to detect GitHub app installation.
@@ -55,7 +112,9 @@ type WebHook struct {
 	DeliveryID string
 
 	// Signature is HMAC hex digest of the request body with the prefix "sha256=".
-	// This is populated from X-Hub-Signature-256 header.
+	// This is populated from X-Hub-Signature-256 header. If [WithWebHookAllowSHA1]
+	// is specified and X-Hub-Signature-256 header is absent, this is populated from
+	// X-Hub-Signature header and has the prefix "sha1=".
 	Signature string
 
 	// GitHub app installation ID. This can be used by WithInstallationID
@@ -84,12 +143,14 @@ func (w *WebHook) LogValue() slog.Value {
 //
 // Typically, HMAC secret would be []byte, but as it may be updated via web interface,
 // which can only accept strings. Returned value is only valid if error is nil.
+// This does not detect replayed requests, use [WebHookVerifier] to reject them.
 //
 //   - [ErrWebHookRequest] is returned when request is invalid and is missing or malformed
 //     headers like 'X-GitHub-Event', 'X-Hub-Signature-256' and more.
 //   - [ErrWebHookMethod] is returned when webhook request is not a PUT request.
-//   - [ErrWebHookContentType] is returned when content type header is not set to 'application/json'.
-//     Though GitHub supports 'application/x-www-form-urlencoded', it is NOT supported by this library.
+//   - [ErrWebHookContentType] is returned when content type header is not 'application/json'
+//     (with any charset). 'application/x-www-form-urlencoded' is only supported when
+//     [WithWebHookFormEncoding] is specified.
 //   - [ErrWebhookSignature] is returned when signature does not match.
 //
 // An example HTTP handler which returns appropriate http status code is shown below.
@@ -124,7 +185,14 @@ func (w *WebHook) LogValue() slog.Value {
 //		// Return HTTP status 2xx.
 //	    w.WriteHeader(http.StatusAccepted)
 //	})
-func VerifyWebHookRequest(secret string, req *http.Request) (WebHook, error) {
+func VerifyWebHookRequest(secret string, req *http.Request, opts ...WebHookOption) (WebHook, error) {
+	var cfg webHookConfig
+	for _, opt := range opts {
+		if opt != nil {
+			opt.apply(&cfg)
+		}
+	}
+
 	if req == nil {
 		return WebHook{}, fmt.Errorf("%w: request is nil", ErrWebHookRequest)
 	}
@@ -145,7 +213,6 @@ func VerifyWebHookRequest(secret string, req *http.Request) (WebHook, error) {
 		api.InstallationTargetTypeHeader,
 		api.InstallationTargetIDHeader,
 		api.ContentTypeHeader,
-		api.SignatureSHA256Header,
 	}
 	missingHeaders := make([]string, 0, len(requiredHeaders))
 	for _, item := range requiredHeaders {
@@ -154,16 +221,42 @@ func VerifyWebHookRequest(secret string, req *http.Request) (WebHook, error) {
 		}
 	}
 
+	// Prefer X-Hub-Signature-256 header, and only fallback to X-Hub-Signature
+	// header if it is absent and SHA1 is explicitly allowed.
+	signatureHeader, signaturePrefix, hashFunc := api.SignatureSHA256Header, "sha256=", sha256.New
+	if req.Header.Get(api.SignatureSHA256Header) == "" {
+		if cfg.sha1 && req.Header.Get(api.SignatureSHA1Header) != "" {
+			signatureHeader, signaturePrefix, hashFunc = api.SignatureSHA1Header, "sha1=", func() hash.Hash {
+				return sha1.New() //nolint:gosec // explicitly enabled.
+			}
+		} else {
+			missingHeaders = append(missingHeaders, api.SignatureSHA256Header)
+		}
+	}
+
 	if len(missingHeaders) > 0 {
 		return WebHook{}, fmt.Errorf("%w: missing header(s): %v", ErrWebHookRequest, missingHeaders)
 	}
 
-	// Only support content type application/json.
-	if req.Header.Get(api.ContentTypeHeader) != api.ContentTypeJSON {
+	// Only support content type application/json with any charset, and
+	// application/x-www-form-urlencoded if enabled.
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get(api.ContentTypeHeader))
+	if err != nil {
 		return WebHook{}, fmt.Errorf("%w: %q", ErrWebHookContentType,
 			req.Header.Get(api.ContentTypeHeader))
 	}
 
+	switch mediaType {
+	case api.ContentTypeJSON:
+	case api.ContentTypeForm:
+		if !cfg.form {
+			return WebHook{}, fmt.Errorf("%w: %q (form encoding is not enabled)",
+				ErrWebHookContentType, mediaType)
+		}
+	default:
+		return WebHook{}, fmt.Errorf("%w: %q", ErrWebHookContentType, mediaType)
+	}
+
 	// Ensure X-GitHub-Hook-Installation-Target-ID header is an integer.
 	installID, err := strconv.ParseUint(req.Header.Get(api.InstallationTargetIDHeader), 10, 64)
 	if err != nil {
@@ -171,15 +264,15 @@ func VerifyWebHookRequest(secret string, req *http.Request) (WebHook, error) {
 			fmt.Errorf("%w: invalid %s header", ErrWebHookRequest, api.InstallationTargetIDHeader)
 	}
 
-	// Ensure X-Hub-Signature-256 header exists and has a valid format.
-	signature := req.Header.Get(api.SignatureSHA256Header)
-	if !strings.HasPrefix(signature, "sha256=") {
-		return WebHook{}, fmt.Errorf("%w: missing prefix sha256= from %s header",
-			ErrWebHookRequest, api.SignatureSHA256Header)
+	// Ensure signature header has a valid format.
+	signature := req.Header.Get(signatureHeader)
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return WebHook{}, fmt.Errorf("%w: missing prefix %s from %s header",
+			ErrWebHookRequest, signaturePrefix, signatureHeader)
 	}
 
 	// Decode hex encoded signature.
-	untrusted, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	untrusted, err := hex.DecodeString(strings.TrimPrefix(signature, signaturePrefix))
 	if err != nil {
 		return WebHook{}, fmt.Errorf("%w: signature not hex encoded", ErrWebHookRequest)
 	}
@@ -189,14 +282,23 @@ func VerifyWebHookRequest(secret string, req *http.Request) (WebHook, error) {
 		return WebHook{}, fmt.Errorf("%w: failed to read request body", ErrWebHookRequest)
 	}
 
-	// Compute HMAC-SHA256.
-	hasher := hmac.New(sha256.New, []byte(secret))
+	// Compute HMAC.
+	hasher := hmac.New(hashFunc, []byte(secret))
 	hasher.Write(data)
 
 	trusted := hasher.Sum(nil)
 
 	// Check HMAC signature.
 	if hmac.Equal(trusted, untrusted) {
+		// Signature is computed over the raw body, extract the payload after verifying it.
+		if mediaType == api.ContentTypeForm {
+			form, err := url.ParseQuery(string(data))
+			if err != nil || !form.Has("payload") {
+				return WebHook{}, fmt.Errorf("%w: missing payload form field", ErrWebHookRequest)
+			}
+			data = []byte(form.Get("payload"))
+		}
+
 		w := WebHook{
 			ID:               req.Header.Get(api.HookIDHeader),
 			DeliveryID:       req.Header.Get(api.DeliveryHeader),
@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+)
+
+var (
+	_ http.Handler = (*WebHookHandler)(nil)
+)
+
+// WebHookHandlerFunc handles a verified webhook.
+type WebHookHandlerFunc func(ctx context.Context, hook WebHook) error
+
+// WebHookHandler is a [http.Handler] which verifies webhooks using
+// [VerifyWebHookRequest] and dispatches them to handlers registered for the
+// event type ('X-GitHub-Event' header). Use [NewWebHookHandler] to create one.
+//
+// Status codes written by the handler are,
+//
+//   - 202 (Accepted) if the handler for the event returns no error, or if there
+//     is no handler registered for the event.
+//   - 401 (Unauthorized) if signature is invalid.
+//   - 400 (Bad Request) if request is invalid or is missing required headers.
+//   - 415 (Unsupported Media Type) if content type is not supported.
+//   - 405 (Method Not Allowed) if request method is not POST.
+//   - 500 (Internal Server Error) if the handler for the event returns an error.
+//
+// As GitHub expects a response within 10 seconds, handlers SHOULD NOT perform
+// long-running operations, and SHOULD instead queue the webhook for processing.
+type WebHookHandler struct {
+	secret   string
+	handlers map[string]WebHookHandlerFunc
+	opts     []WebHookOption
+}
+
+// NewWebHookHandler returns a new [WebHookHandler] which verifies webhooks with
+// the secret and dispatches them to handlers, which is a map of event type like
+// "push" or "issues" to its handler. Options are passed to [VerifyWebHookRequest].
+//
+//	handler := githubapp.NewWebHookHandler(secret,
+//	    map[string]githubapp.WebHookHandlerFunc{
+//	        "issues": func(ctx context.Context, hook githubapp.WebHook) error {
+//	            // Do something with webhook, for example, put it in SQS or PubSub.
+//	            return doSomething(ctx, hook)
+//	        },
+//	    },
+//	)
+//	mux.Handle("/webhook", handler)
+func NewWebHookHandler(secret string, handlers map[string]WebHookHandlerFunc, opts ...WebHookOption) *WebHookHandler {
+	return &WebHookHandler{
+		secret:   secret,
+		handlers: maps.Clone(handlers),
+		opts:     opts,
+	}
+}
+
+// ServeHTTP implements [http.Handler].
+func (h *WebHookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	hook, err := VerifyWebHookRequest(h.secret, r, h.opts...)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrWebhookSignature):
+			w.WriteHeader(http.StatusUnauthorized)
+		case errors.Is(err, ErrWebHookRequest):
+			w.WriteHeader(http.StatusBadRequest)
+		case errors.Is(err, ErrWebHookContentType):
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+		case errors.Is(err, ErrWebHookMethod):
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+
+	handler, ok := h.handlers[hook.Event]
+	if !ok || handler == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	// Errors from handlers are not written to the response,
+	// as they might include sensitive information.
+	if err = handler(r.Context(), hook); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
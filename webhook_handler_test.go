@@ -0,0 +1,122 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tprasadtp/go-githubapp/internal/api"
+)
+
+func TestWebHookHandler(t *testing.T) {
+	const secret = "It's a Secret to Everybody"
+	const delivery = "72d3162e-cc78-11e3-81ab-4c9367dc0958"
+
+	tt := []struct {
+		name    string
+		request func() *http.Request
+		status  int
+		called  bool
+	}{
+		{
+			name: "dispatch",
+			request: func() *http.Request {
+				return newTestWebHookRequest(secret, delivery)
+			},
+			status: http.StatusAccepted,
+			called: true,
+		},
+		{
+			name: "handler-error",
+			request: func() *http.Request {
+				r := newTestWebHookRequest(secret, delivery)
+				r.Header.Set(api.EventHeader, "push")
+				return r
+			},
+			status: http.StatusInternalServerError,
+		},
+		{
+			name: "unregistered-event",
+			request: func() *http.Request {
+				r := newTestWebHookRequest(secret, delivery)
+				r.Header.Set(api.EventHeader, "pull_request")
+				return r
+			},
+			status: http.StatusAccepted,
+		},
+		{
+			name: "invalid-signature",
+			request: func() *http.Request {
+				return newTestWebHookRequest("invalid-secret", delivery)
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "missing-headers",
+			request: func() *http.Request {
+				r := newTestWebHookRequest(secret, delivery)
+				r.Header.Del(api.DeliveryHeader)
+				return r
+			},
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "unsupported-content-type",
+			request: func() *http.Request {
+				r := newTestWebHookRequest(secret, delivery)
+				r.Header.Set(api.ContentTypeHeader, "text/plain")
+				return r
+			},
+			status: http.StatusUnsupportedMediaType,
+		},
+		{
+			name: "invalid-method",
+			request: func() *http.Request {
+				r := newTestWebHookRequest(secret, delivery)
+				r.Method = http.MethodGet
+				return r
+			},
+			status: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var called bool
+			handler := NewWebHookHandler(secret, map[string]WebHookHandlerFunc{
+				"issues": func(ctx context.Context, hook WebHook) error {
+					called = true
+					if ctx == nil {
+						t.Errorf("context must not be nil")
+					}
+					if hook.DeliveryID != delivery || hook.Event != "issues" {
+						t.Errorf("unexpected webhook: %#v", hook)
+					}
+					return nil
+				},
+				"push": func(context.Context, WebHook) error {
+					return errors.New("handler failed")
+				},
+			})
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, tc.request())
+			if w.Code != tc.status {
+				t.Errorf("expected status=%d, got=%d", tc.status, w.Code)
+			}
+
+			if called != tc.called {
+				t.Errorf("expected handler called=%t, got=%t", tc.called, called)
+			}
+
+			if strings.Contains(w.Body.String(), "handler failed") {
+				t.Errorf("handler errors must not be written to response")
+			}
+		})
+	}
+}
@@ -6,12 +6,17 @@ package githubapp
 import (
 	"bufio"
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec // test SHA1 fallback.
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"io"
 	"log/slog"
 	"maps"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -283,6 +288,233 @@ func TestVerifyWebHookSignature(t *testing.T) {
 	}
 }
 
+func TestVerifyWebHookRequest_ContentType(t *testing.T) {
+	const secret = "It's a Secret to Everybody"
+	const payload = `{"action":"opened"}`
+	sign := func(body string) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(body))
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	form := url.Values{"payload": []string{payload}}.Encode()
+
+	type testCase struct {
+		name        string
+		contentType string
+		body        string
+		opts        []WebHookOption
+		payload     string
+		err         error
+	}
+	tt := []testCase{
+		{
+			name:        "json",
+			contentType: "application/json",
+			body:        payload,
+			payload:     payload,
+		},
+		{
+			name:        "json-charset",
+			contentType: "application/json; charset=utf-8",
+			body:        payload,
+			payload:     payload,
+		},
+		{
+			name:        "json-charset-uppercase",
+			contentType: "Application/JSON; charset=UTF-8",
+			body:        payload,
+			payload:     payload,
+		},
+		{
+			name:        "json-with-form-enabled",
+			contentType: "application/json",
+			body:        payload,
+			opts:        []WebHookOption{WithWebHookFormEncoding()},
+			payload:     payload,
+		},
+		{
+			name:        "json-nil-option",
+			contentType: "application/json",
+			body:        payload,
+			opts:        []WebHookOption{nil},
+			payload:     payload,
+		},
+		{
+			name:        "form-disabled",
+			contentType: "application/x-www-form-urlencoded",
+			body:        form,
+			err:         ErrWebHookContentType,
+		},
+		{
+			name:        "form-enabled",
+			contentType: "application/x-www-form-urlencoded",
+			body:        form,
+			opts:        []WebHookOption{WithWebHookFormEncoding()},
+			payload:     payload,
+		},
+		{
+			name:        "form-enabled-charset",
+			contentType: "application/x-www-form-urlencoded; charset=utf-8",
+			body:        form,
+			opts:        []WebHookOption{WithWebHookFormEncoding()},
+			payload:     payload,
+		},
+		{
+			name:        "form-enabled-missing-payload",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "foo=bar",
+			opts:        []WebHookOption{WithWebHookFormEncoding()},
+			err:         ErrWebHookRequest,
+		},
+		{
+			name:        "text-plain",
+			contentType: "text/plain",
+			body:        payload,
+			err:         ErrWebHookContentType,
+		},
+		{
+			name:        "json-suffix",
+			contentType: "application/vnd.github+json",
+			body:        payload,
+			err:         ErrWebHookContentType,
+		},
+		{
+			name:        "malformed",
+			contentType: "application/json; charset",
+			body:        payload,
+			err:         ErrWebHookContentType,
+		},
+		{
+			name: "missing",
+			body: payload,
+			err:  ErrWebHookRequest,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+			r.Header.Set(api.DeliveryHeader, "72d3162e-cc78-11e3-81ab-4c9367dc0958")
+			r.Header.Set(api.SignatureSHA256Header, sign(tc.body))
+			r.Header.Set(api.UAHeader, "GitHub-Hookshot/044aadd")
+			r.Header.Set(api.EventHeader, "issues")
+			r.Header.Set(api.HookIDHeader, "292430182")
+			r.Header.Set(api.InstallationTargetIDHeader, "79929171")
+			r.Header.Set(api.InstallationTargetTypeHeader, "repository")
+			if tc.contentType != "" {
+				r.Header.Set(api.ContentTypeHeader, tc.contentType)
+			}
+
+			hook, err := VerifyWebHookRequest(secret, r, tc.opts...)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error=%v, got=%v", tc.err, err)
+			}
+
+			if tc.err == nil && string(hook.Payload) != tc.payload {
+				t.Errorf("expected payload=%s, got=%s", tc.payload, hook.Payload)
+			}
+		})
+	}
+}
+
+func TestVerifyWebHookRequest_SHA1(t *testing.T) {
+	const secret = "It's a Secret to Everybody"
+	const payload = `{"action":"opened"}`
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	sha256Signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	mac = hmac.New(sha1.New, []byte(secret))
+	mac.Write([]byte(payload))
+	sha1Signature := "sha1=" + hex.EncodeToString(mac.Sum(nil))
+
+	tt := []struct {
+		name      string
+		sha256    string
+		sha1      string
+		opts      []WebHookOption
+		signature string
+		err       error
+	}{
+		{
+			name:      "sha256",
+			sha256:    sha256Signature,
+			signature: sha256Signature,
+		},
+		{
+			name:      "sha256-with-sha1-enabled",
+			sha256:    sha256Signature,
+			sha1:      sha1Signature,
+			opts:      []WebHookOption{WithWebHookAllowSHA1()},
+			signature: sha256Signature,
+		},
+		{
+			name:   "sha256-preferred-over-sha1",
+			sha256: "sha256=" + strings.Repeat("0", 64),
+			sha1:   sha1Signature,
+			opts:   []WebHookOption{WithWebHookAllowSHA1()},
+			err:    ErrWebhookSignature,
+		},
+		{
+			name: "sha1-disabled",
+			sha1: sha1Signature,
+			err:  ErrWebHookRequest,
+		},
+		{
+			name:      "sha1-enabled",
+			sha1:      sha1Signature,
+			opts:      []WebHookOption{WithWebHookAllowSHA1()},
+			signature: sha1Signature,
+		},
+		{
+			name: "sha1-enabled-invalid",
+			sha1: "sha1=" + strings.Repeat("0", 40),
+			opts: []WebHookOption{WithWebHookAllowSHA1()},
+			err:  ErrWebhookSignature,
+		},
+		{
+			name: "sha1-enabled-invalid-prefix",
+			sha1: sha256Signature,
+			opts: []WebHookOption{WithWebHookAllowSHA1()},
+			err:  ErrWebHookRequest,
+		},
+		{
+			name: "sha1-enabled-missing",
+			opts: []WebHookOption{WithWebHookAllowSHA1()},
+			err:  ErrWebHookRequest,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(payload))
+			r.Header.Set(api.DeliveryHeader, "72d3162e-cc78-11e3-81ab-4c9367dc0958")
+			r.Header.Set(api.UAHeader, "GitHub-Hookshot/044aadd")
+			r.Header.Set(api.ContentTypeHeader, api.ContentTypeJSON)
+			r.Header.Set(api.EventHeader, "issues")
+			r.Header.Set(api.HookIDHeader, "292430182")
+			r.Header.Set(api.InstallationTargetIDHeader, "79929171")
+			r.Header.Set(api.InstallationTargetTypeHeader, "repository")
+			if tc.sha256 != "" {
+				r.Header.Set(api.SignatureSHA256Header, tc.sha256)
+			}
+			if tc.sha1 != "" {
+				r.Header.Set(api.SignatureSHA1Header, tc.sha1)
+			}
+
+			hook, err := VerifyWebHookRequest(secret, r, tc.opts...)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error=%v, got=%v", tc.err, err)
+			}
+
+			if tc.err == nil && hook.Signature != tc.signature {
+				t.Errorf("expected signature=%s, got=%s", tc.signature, hook.Signature)
+			}
+		})
+	}
+}
+
 func TestVerifyWebHookSignature_WithReplayers(t *testing.T) {
 	dir := filepath.Join("internal", "testdata", "webhooks")
 	items, le := os.ReadDir(dir)
@@ -0,0 +1,101 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrWebHookReplay is returned by [WebHookVerifier] when a webhook delivery
+// was already seen, which indicates that the request was replayed.
+const ErrWebHookReplay = Error("githubapp(webhook): delivery was already seen")
+
+// defaultWebHookMaxAge is default duration for which [WebHookVerifier]
+// remembers the delivery IDs.
+const defaultWebHookMaxAge = time.Hour
+
+// WebHookVerifier verifies webhooks like [VerifyWebHookRequest], but also rejects
+// deliveries which were already seen, to prevent replaying captured requests.
+// GitHub webhooks do not include a timestamp, thus deliveries are identified
+// by their unique 'X-GitHub-Delivery' header.
+//
+// Webhooks re-delivered via GitHub UI or API have a new delivery ID, and are
+// not considered replays. Zero value is ready to use, and uses an in-memory set
+// of delivery IDs, which is not shared between processes. Use Seen to plug in a
+// shared store, like Redis. WebHookVerifier MUST NOT be copied after first use.
+type WebHookVerifier struct {
+	// Seen reports whether delivery with the given ID was already seen, and
+	// records it as seen. It MUST be safe for concurrent use and atomic.
+	// If nil, an in-memory set of delivery IDs is used.
+	Seen func(deliveryID string) (bool, error)
+
+	// MaxAge is duration for which delivery IDs are remembered by the in-memory
+	// set. This is not used if Seen is specified. If zero, defaults to 1 hour.
+	MaxAge time.Duration
+
+	mu    sync.Mutex
+	seen  map[string]time.Time
+	prune time.Time
+}
+
+// Verify verifies webhook request like [VerifyWebHookRequest], and returns
+// [ErrWebHookReplay] if the delivery was already seen. Deliveries are only
+// recorded after verifying their signature, thus unauthenticated requests cannot
+// be used to reject legitimate deliveries.
+func (v *WebHookVerifier) Verify(secret string, req *http.Request, opts ...WebHookOption) (WebHook, error) {
+	hook, err := VerifyWebHookRequest(secret, req, opts...)
+	if err != nil {
+		return WebHook{}, err
+	}
+
+	seen := v.Seen
+	if seen == nil {
+		seen = v.seenInMemory
+	}
+
+	replay, err := seen(hook.DeliveryID)
+	if err != nil {
+		return WebHook{}, fmt.Errorf("githubapp(webhook): failed to check delivery %s: %w", hook.DeliveryID, err)
+	}
+
+	if replay {
+		return WebHook{}, fmt.Errorf("%w: %s", ErrWebHookReplay, hook.DeliveryID)
+	}
+	return hook, nil
+}
+
+// seenInMemory checks and records delivery ID in the in-memory set.
+func (v *WebHookVerifier) seenInMemory(deliveryID string) (bool, error) {
+	maxAge := v.MaxAge
+	if maxAge <= 0 {
+		maxAge = defaultWebHookMaxAge
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := time.Now()
+	if v.seen == nil {
+		v.seen = make(map[string]time.Time)
+	}
+
+	// Periodically evict expired delivery IDs to bound memory usage.
+	if now.After(v.prune) {
+		for id, ts := range v.seen {
+			if now.Sub(ts) > maxAge {
+				delete(v.seen, id)
+			}
+		}
+		v.prune = now.Add(maxAge / 2)
+	}
+
+	if ts, ok := v.seen[deliveryID]; ok && now.Sub(ts) <= maxAge {
+		return true, nil
+	}
+	v.seen[deliveryID] = now
+	return false, nil
+}
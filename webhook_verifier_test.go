@@ -0,0 +1,133 @@
+// SPDX-FileCopyrightText: Copyright 2023 Prasad Tengse
+// SPDX-License-Identifier: MIT
+
+package githubapp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tprasadtp/go-githubapp/internal/api"
+)
+
+// newTestWebHookRequest returns a signed webhook request with delivery id.
+func newTestWebHookRequest(secret, deliveryID string) *http.Request {
+	const payload = `{"action":"opened"}`
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(payload))
+	r.Header.Set(api.DeliveryHeader, deliveryID)
+	r.Header.Set(api.SignatureSHA256Header, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	r.Header.Set(api.UAHeader, "GitHub-Hookshot/044aadd")
+	r.Header.Set(api.ContentTypeHeader, api.ContentTypeJSON)
+	r.Header.Set(api.EventHeader, "issues")
+	r.Header.Set(api.HookIDHeader, "292430182")
+	r.Header.Set(api.InstallationTargetIDHeader, "79929171")
+	r.Header.Set(api.InstallationTargetTypeHeader, "repository")
+	return r
+}
+
+func TestWebHookVerifier(t *testing.T) {
+	const secret = "It's a Secret to Everybody"
+	const delivery = "72d3162e-cc78-11e3-81ab-4c9367dc0958"
+
+	t.Run("in-memory", func(t *testing.T) {
+		verifier := &WebHookVerifier{}
+		hook, err := verifier.Verify(secret, newTestWebHookRequest(secret, delivery))
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		if hook.DeliveryID != delivery {
+			t.Errorf("expected delivery=%s, got=%s", delivery, hook.DeliveryID)
+		}
+
+		_, err = verifier.Verify(secret, newTestWebHookRequest(secret, delivery))
+		if !errors.Is(err, ErrWebHookReplay) {
+			t.Errorf("expected %s, got %v", ErrWebHookReplay, err)
+		}
+
+		_, err = verifier.Verify(secret, newTestWebHookRequest(secret, "b1c5a3b2-cc78-11e3-81ab-4c9367dc0958"))
+		if err != nil {
+			t.Errorf("expected no error for a different delivery, got %s", err)
+		}
+	})
+
+	t.Run("invalid-signature-not-recorded", func(t *testing.T) {
+		verifier := &WebHookVerifier{}
+		_, err := verifier.Verify(secret, newTestWebHookRequest("invalid-secret", delivery))
+		if !errors.Is(err, ErrWebhookSignature) {
+			t.Fatalf("expected %s, got %v", ErrWebhookSignature, err)
+		}
+
+		_, err = verifier.Verify(secret, newTestWebHookRequest(secret, delivery))
+		if err != nil {
+			t.Errorf("expected no error, got %s", err)
+		}
+	})
+
+	t.Run("max-age", func(t *testing.T) {
+		verifier := &WebHookVerifier{MaxAge: 50 * time.Millisecond}
+		_, err := verifier.Verify(secret, newTestWebHookRequest(secret, delivery))
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+		_, err = verifier.Verify(secret, newTestWebHookRequest(secret, delivery))
+		if err != nil {
+			t.Errorf("expected expired delivery to be forgotten, got %s", err)
+		}
+
+		if len(verifier.seen) != 1 {
+			t.Errorf("expected expired deliveries to be evicted, got %d", len(verifier.seen))
+		}
+	})
+
+	t.Run("custom-seen", func(t *testing.T) {
+		var mu sync.Mutex
+		store := map[string]bool{}
+		verifier := &WebHookVerifier{
+			Seen: func(id string) (bool, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				seen := store[id]
+				store[id] = true
+				return seen, nil
+			},
+		}
+
+		for i, expect := range []error{nil, ErrWebHookReplay} {
+			_, err := verifier.Verify(secret, newTestWebHookRequest(secret, delivery))
+			if !errors.Is(err, expect) {
+				t.Errorf("attempt %d: expected %v, got %v", i, expect, err)
+			}
+		}
+
+		if !store[delivery] {
+			t.Errorf("expected delivery to be recorded in custom store")
+		}
+	})
+
+	t.Run("custom-seen-error", func(t *testing.T) {
+		verifier := &WebHookVerifier{
+			Seen: func(string) (bool, error) {
+				return false, errors.New("store unavailable")
+			},
+		}
+
+		_, err := verifier.Verify(secret, newTestWebHookRequest(secret, delivery))
+		if err == nil || errors.Is(err, ErrWebHookReplay) {
+			t.Errorf("expected store error, got %v", err)
+		}
+	})
+}